@@ -0,0 +1,597 @@
+package main
+
+// Command line subcommands. Running with no arguments starts the GUI.
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/API"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Classifier"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Drafts"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Export"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Lineup"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Lint"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/PracConfig"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Radar"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Screenshots"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/WebUI"
+)
+
+// commands maps a subcommand name to the function that runs it. Each returns the exit code.
+var commands = map[string]func(args []string, settings Settings) int{
+	"lint":     runLint,
+	"backfill": runBackfill,
+	"radar":    runRadar,
+	"export":   runExport,
+	"serve":    runServe,
+	"import":   runImport,
+	"lineup":   runLineup,
+	"prac":     runPrac,
+}
+
+// runCommand runs the subcommand named in args, reading the settings only then.
+// ok is false when args don't name one, such as a file path a launcher passes,
+// so the GUI should start.
+func runCommand(args []string, settings func() Settings) (code int, ok bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage()
+		return 0, true
+	}
+	cmd, found := commands[args[0]]
+	if !found {
+		return 0, false
+	}
+	return cmd(args[1:], settings()), true
+}
+
+func printUsage() {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "Usage: CS_StratBook [command] [flags]")
+	fmt.Fprintln(os.Stderr, "With no command the GUI starts. Commands: "+strings.Join(names, ", "))
+	fmt.Fprintln(os.Stderr, "Run CS_StratBook <command> -h for the flags of a command.")
+}
+
+// runLint checks the annotation files and exits with 1 if any errors were found
+func runLint(args []string, settings Settings) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	format := fs.String("format", "text", "report format: text, json or sarif")
+	disable := fs.String("disable", "", "comma separated rules to turn off: "+strings.Join(Lint.RuleIDs(), ", "))
+	output := fs.String("o", "", "write the report to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: CS_StratBook lint [flags] [annotation folder]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	root := settings.AnnotationPath
+	if fs.NArg() > 0 {
+		root = fs.Arg(0)
+	}
+
+	cfg := Lint.Config{Disabled: make(map[string]bool)}
+	for _, id := range strings.Split(*disable, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			cfg.Disabled[id] = true
+		}
+	}
+
+	report, err := Lint.Run(root, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := Lint.Write(out, report, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if report.HasErrors() {
+		return 1
+	}
+	return 0
+}
+
+// runBackfill fills the side, site and callout of the nades in tags.json from the map outlines
+func runBackfill(args []string, settings Settings) int {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	overwrite := fs.Bool("overwrite", false, "replace sides, sites and callouts that are already set")
+	dryRun := fs.Bool("dry-run", false, "list the nades that would change without saving")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: CS_StratBook backfill [flags] [tags.json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	tagsPath := settings.TagsPath
+	if fs.NArg() > 0 {
+		tagsPath = fs.Arg(0)
+	}
+
+	nades, err := Tags.LoadTags(tagsPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	updated, changed, warnings := Classifier.Backfill(nades, *overwrite)
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, w)
+	}
+	var placeholders []string
+	for i, nade := range updated {
+		if !reflect.DeepEqual(nade, nades[i]) {
+			fmt.Printf("%s: side %q, site %q, callout %q, thrown from %q\n", nade.NadeName, nade.Side, nade.Site, nade.Callout, nade.ThrowZone)
+			if m, ok := Maps.Lookup(nade.MapName); ok && m.Placeholder && !slices.Contains(placeholders, m.DisplayName) {
+				placeholders = append(placeholders, m.DisplayName)
+			}
+		}
+	}
+	for _, name := range placeholders {
+		fmt.Fprintf(os.Stderr, "%s only has placeholder outlines, check what was filled in\n", name)
+	}
+	if *dryRun {
+		fmt.Printf("%d of %d nade(s) would be updated\n", len(changed), len(nades))
+		return 0
+	}
+	fmt.Printf("%d of %d nade(s) updated\n", len(changed), len(nades))
+	if len(changed) == 0 {
+		return 0
+	}
+	if err := Tags.SaveTags(tagsPath, updated); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// runRadar draws the nades of one map from tags.json onto its radar as a PNG or SVG
+func runRadar(args []string, settings Settings) int {
+	fs := flag.NewFlagSet("radar", flag.ContinueOnError)
+	mapName := fs.String("map", "", "map to draw, e.g. de_inferno (required)")
+	imagePath := fs.String("image", "", "radar image to draw on (default: <map>_radar.png in the radar folder)")
+	output := fs.String("o", "radar.png", "file to write; .svg writes an SVG, anything else a PNG")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: CS_StratBook radar -map <map> [flags] [tags.json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *mapName == "" {
+		fs.Usage()
+		return 2
+	}
+
+	tagsPath := settings.TagsPath
+	if fs.NArg() > 0 {
+		tagsPath = fs.Arg(0)
+	}
+	nades, err := Tags.LoadTags(tagsPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	ov, err := Radar.OverviewFor(*mapName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var lineups []Radar.Lineup
+	for _, nade := range nades {
+		if !strings.EqualFold(nade.MapName, *mapName) {
+			continue
+		}
+		ls, err := Radar.LoadLineups(nade.NadeName, nade.FilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipped %s: %v\n", nade.NadeName, err)
+			continue
+		}
+		lineups = append(lineups, ls...)
+	}
+
+	if *imagePath == "" {
+		*imagePath = Radar.FindImage(settings.RadarPath, *mapName)
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer out.Close()
+
+	if strings.EqualFold(filepath.Ext(*output), ".svg") {
+		// The SVG links the image, so the link has to work from where the SVG is
+		href := *imagePath
+		if href != "" {
+			if rel, err := relativeTo(*output, href); err == nil {
+				href = filepath.ToSlash(rel)
+			}
+		}
+		err = Radar.WriteSVG(out, href, ov, lineups)
+	} else {
+		var background image.Image
+		if *imagePath != "" {
+			if background, err = Radar.LoadImage(*imagePath); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+		}
+		err = Radar.WritePNG(out, background, ov, lineups)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Printf("%d lineup(s) drawn to %s\n", len(lineups), *output)
+	return 0
+}
+
+// relativeTo returns the path of target relative to the folder of file
+func relativeTo(file, target string) (string, error) {
+	fileAbs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	targetAbs, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(filepath.Dir(fileAbs), targetAbs)
+}
+
+// runExport writes a stratbook per map from tags.json, optionally limited to a draft
+func runExport(args []string, settings Settings) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", Export.HTML, "book format: "+strings.Join(Export.Formats, ", "))
+	output := fs.String("o", settings.OutputPath, "folder to write the books to")
+	draft := fs.String("draft", "", "only export the nades in this draft")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: CS_StratBook export [flags] [tags.json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	tagsPath := settings.TagsPath
+	if fs.NArg() > 0 {
+		tagsPath = fs.Arg(0)
+	}
+	nades, err := Tags.LoadTags(tagsPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var files []string
+	if *draft != "" {
+		store, err := Drafts.Load(settings.DraftsPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		d, ok := store.Get(*draft)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "no draft named %q\n", *draft)
+			return 2
+		}
+		if len(d.Files) == 0 {
+			fmt.Fprintf(os.Stderr, "draft %q is empty\n", *draft)
+			return 2
+		}
+		files = d.Files
+	}
+
+	paths, err := Export.Write(*output, Export.Books(nades, files), *format)
+	for _, p := range paths {
+		fmt.Println(p)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// runServe serves the web view of tags.json, and the JSON API unless turned off, until stopped
+func runServe(args []string, settings Settings) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on, use :8080 to share it on the network")
+	withAPI := fs.Bool("api", false, "also serve the read-only JSON API under /api/")
+	apiWrite := fs.Bool("api-write", false, "let the JSON API change tags.json and build packs, implies -api")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: CS_StratBook serve [flags] [tags.json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	tagsPath := settings.TagsPath
+	if fs.NArg() > 0 {
+		tagsPath = fs.Arg(0)
+	}
+	if _, err := os.Stat(tagsPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", WebUI.New(WebUI.Config{TagsPath: tagsPath}))
+	if *withAPI || *apiWrite {
+		mux.Handle("/api/", API.New(API.Config{TagsPath: tagsPath, AnnotationPath: settings.AnnotationPath, Write: *apiWrite}))
+	}
+
+	fmt.Printf("Serving %s on http://%s\n", tagsPath, *addr)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		log.Printf("Error serving web UI: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// runImport lists recent screenshots, or copies them into the folders of the
+// nades they are assigned to and adds them to tags.json
+func runImport(args []string, settings Settings) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dir := fs.String("dir", settings.ScreenshotsPath, "screenshot folder, found from the Steam folder when empty")
+	since := fs.Duration("since", 24*time.Hour, "only list screenshots taken this recently")
+	tagsPath := fs.String("tags", settings.TagsPath, "tags.json to add the images to")
+	dryRun := fs.Bool("dry-run", false, "list what would be imported without copying")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: CS_StratBook import [flags] [screenshot=nade[:label] ...]")
+		fmt.Fprintln(fs.Output(), "With no assignments the recent screenshots are listed, numbered newest first.")
+		fmt.Fprintln(fs.Output(), "screenshot is a number from the list or a file. Labels: "+strings.Join(Tags.ImageLabels, ", "))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	folders := screenshotFolders(*dir, settings.AnnotationPath)
+	if len(folders) == 0 {
+		fmt.Fprintln(os.Stderr, "no screenshot folder found, set one with -dir")
+		return 2
+	}
+	shots, err := Screenshots.List(folders, time.Now().Add(-*since))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		for i, s := range shots {
+			fmt.Printf("%3d  %s  %s\n", i+1, s.Time.Format("2006-01-02 15:04:05"), s.Path)
+		}
+		return 0
+	}
+
+	var assignments []Screenshots.Assignment
+	for _, arg := range fs.Args() {
+		shot, nade, ok := strings.Cut(arg, "=")
+		if !ok || nade == "" {
+			fmt.Fprintf(os.Stderr, "%q is not screenshot=nade[:label]\n", arg)
+			return 2
+		}
+		a := Screenshots.Assignment{Screenshot: shot, NadeName: nade}
+		if i := strings.LastIndex(nade, ":"); i >= 0 {
+			a.NadeName, a.Label = nade[:i], nade[i+1:]
+		}
+		if n, err := strconv.Atoi(shot); err == nil {
+			if n < 1 || n > len(shots) {
+				fmt.Fprintf(os.Stderr, "no screenshot %d, %d listed\n", n, len(shots))
+				return 2
+			}
+			a.Screenshot = shots[n-1].Path
+		}
+		assignments = append(assignments, a)
+	}
+
+	nades, err := Tags.LoadTags(*tagsPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *dryRun {
+		for _, a := range assignments {
+			fmt.Printf("%s -> %s %s\n", a.Screenshot, a.NadeName, a.Label)
+		}
+		return 0
+	}
+	updated, attached, err := Screenshots.Attach(nades, assignments)
+	// Whatever was copied before an error is still saved
+	if len(attached) > 0 {
+		if saveErr := Tags.SaveTags(*tagsPath, updated); saveErr != nil {
+			fmt.Fprintln(os.Stderr, saveErr)
+			return 2
+		}
+	}
+	for _, a := range attached {
+		fmt.Printf("%s -> %s\n", a.Screenshot, a.Path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// runLineup writes a new lineup annotation file from a setpos/setang string
+func runLineup(args []string, settings Settings) int {
+	fs := flag.NewFlagSet("lineup", flag.ContinueOnError)
+	mapName := fs.String("map", "", "map the lineup is on, like de_inferno")
+	nadeType := fs.String("type", "", "grenade type: "+strings.Join(Lineup.GrenadeTypes, ", "))
+	setpos := fs.String("setpos", "", `where to stand, as printed by getpos: "setpos x y z;setang pitch yaw roll"`)
+	land := fs.String("land", "", `where the grenade lands: "x y z" or a setpos string`)
+	title := fs.String("title", "", "title shown in game, the name when empty")
+	standText := fs.String("stand-text", "", "how to find the standing spot")
+	aimText := fs.String("aim-text", "", "where to aim")
+	jump := fs.Bool("jump", false, "jumpthrow")
+	run := fs.Bool("run", false, "thrown while running")
+	walk := fs.Bool("walk", false, "thrown while walking")
+	crouch := fs.Bool("crouch", false, "thrown while crouching")
+	click := fs.String("click", "", "mouse button: left, right or both")
+	output := fs.String("o", settings.AnnotationPath, "annotation folder, the file goes in local/<name>/<name>.txt")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: CS_StratBook lineup -map m -type t -setpos s -land l [flags] name")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || *setpos == "" || *land == "" {
+		fs.Usage()
+		return 2
+	}
+
+	stand, err := Lineup.ParseSetpos(*setpos)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	landing, err := Lineup.ParsePosition(*land)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	path, err := Lineup.Write(*output, Lineup.Lineup{
+		Name:        fs.Arg(0),
+		MapName:     *mapName,
+		GrenadeType: *nadeType,
+		Stand:       stand,
+		Landing:     landing,
+		Throw:       Lineup.Throw{Jump: *jump, Run: *run, Walk: *walk, Crouch: *crouch, Click: *click},
+		Title:       *title,
+		StandText:   *standText,
+		AimText:     *aimText,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Println(path)
+	return 0
+}
+
+// runPrac writes a practice config, optionally loading a pack and binding keys to its lineups
+func runPrac(args []string, settings Settings) int {
+	defaults := PracConfig.DefaultOptions()
+	fs := flag.NewFlagSet("prac", flag.ContinueOnError)
+	output := fs.String("o", cfgFolder(settings.CfgPath, settings.AnnotationPath), "CS2 cfg folder to write to")
+	name := fs.String("name", "", "config file name, prac or prac_<pack> when empty")
+	mapName := fs.String("map", "", "map to load, the pack's map when empty")
+	trajectory := fs.Int("trajectory", defaults.TrajectoryTime, "seconds grenade trails stay on screen")
+	ammo := fs.Bool("infinite-ammo", defaults.InfiniteAmmo, "never run out of grenades")
+	bots := fs.Bool("kick-bots", defaults.KickBots, "remove the bots")
+	binds := fs.Bool("binds", false, "bind keys that teleport to the pack's lineups")
+	keys := fs.String("keys", strings.Join(defaults.Keys, ","), "comma separated keys for -binds, in pack order")
+	tmpl := fs.String("template", "", "text/template file to use instead of the built in one")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: CS_StratBook prac [flags] [pack.txt]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *output == "" {
+		fmt.Fprintln(os.Stderr, "CS2 cfg folder not found, set one with -o")
+		return 2
+	}
+
+	opts := PracConfig.Options{TrajectoryTime: *trajectory, InfiniteAmmo: *ammo, KickBots: *bots, Map: *mapName}
+	for _, k := range strings.Split(*keys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			opts.Keys = append(opts.Keys, k)
+		}
+	}
+	if fs.NArg() > 0 {
+		packPath := fs.Arg(0)
+		f, err := Annotation.Load(packPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		opts.Pack = strings.TrimSuffix(filepath.Base(packPath), filepath.Ext(packPath))
+		if opts.Map == "" {
+			opts.Map = f.MapName()
+		}
+		if *binds {
+			if opts.Spots, err = PracConfig.Spots([]string{packPath}); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+		}
+	}
+	if *name == "" {
+		*name = "prac"
+		if opts.Pack != "" {
+			*name = "prac_" + opts.Pack
+		}
+	}
+
+	text := ""
+	if *tmpl != "" {
+		data, err := os.ReadFile(*tmpl)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		text = string(data)
+	}
+	var path string
+	var warnings []string
+	var err error
+	if text != "" {
+		path, warnings, err = PracConfig.WriteTemplate(*output, *name, text, opts)
+	} else {
+		path, warnings, err = PracConfig.Write(*output, *name, opts)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, w)
+	}
+	fmt.Println(path)
+	return 0
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Collisions"
+)
+
+// showCollisions opens a window that runs a text collision check with a
+// configurable distance and lists the colliding labels
+func (g *gui) showCollisions(title string, check func(distance float64) (Collisions.Report, error)) {
+	w := g.App.NewWindow(title)
+
+	distanceEntry := widget.NewEntry()
+	distanceEntry.SetText(strconv.FormatFloat(Collisions.DefaultDistance, 'f', -1, 64))
+	summary := widget.NewLabel("")
+
+	var lines []string
+	results := widget.NewList(
+		func() int { return len(lines) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) { o.(*widget.Label).SetText(lines[i]) },
+	)
+
+	run := func() {
+		distance, err := strconv.ParseFloat(distanceEntry.Text, 64)
+		if err != nil || distance <= 0 {
+			summary.SetText("Distance must be a positive number")
+			return
+		}
+		report, err := check(distance)
+		if err != nil {
+			log.Printf("Error checking collisions: %v", err)
+			summary.SetText("Error: " + err.Error())
+			return
+		}
+
+		lines = lines[:0]
+		for _, c := range report.Collisions {
+			lines = append(lines, c.String())
+		}
+		for _, s := range report.Skipped {
+			lines = append(lines, "Skipped (could not read): "+s)
+		}
+		summary.SetText(fmt.Sprintf("%d collision(s) within %g units", len(report.Collisions), distance))
+		results.Refresh()
+	}
+
+	top := container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("Distance:"), widget.NewButton("Check", run), distanceEntry),
+		summary,
+	)
+	w.SetContent(container.NewBorder(top, nil, nil, nil, results))
+	w.Resize(fyne.NewSize(900, 500))
+	run()
+	w.Show()
+}
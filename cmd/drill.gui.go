@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Carousel"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Drill"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/MetadataExplorer"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// Drill prompts: show where the nade lands, or just its name
+const (
+	drillLanding = "Show landing"
+	drillName    = "Show name"
+)
+
+// makeDrillTab builds the study tab. It quizzes a player on the nades picked by
+// the filters and keeps their spaced repetition stats in the drill file.
+func (g *gui) makeDrillTab(getMetadata func() []MetadataExplorer.Metadata) fyne.CanvasObject {
+	store, err := Drill.Load(g.Drill_path)
+	if err != nil {
+		log.Printf("Error loading drill stats: %v", err)
+	}
+
+	var filters MetadataExplorer.FilterOptions
+	var pool []MetadataExplorer.Metadata
+	var current *MetadataExplorer.Metadata
+
+	player := widget.NewSelectEntry(store.PlayerNames())
+	player.SetPlaceHolder("Player name")
+
+	sites := container.New(layout.NewGridLayout(4))
+	// Maps are picked by display name, so workshop copies of a map stay apart
+	mapKeys := make(map[string]Maps.Key)
+	mapSelect := widget.NewSelect(nil, func(display string) {
+		key := mapKeys[display]
+		filters.MapPick, filters.WorkshopID = key.Name, key.WorkshopID
+		filters.Sites = make(map[string]bool)
+		sites.Objects = nil
+		for _, name := range Maps.Sites(key.Name) {
+			name := name
+			sites.Add(widget.NewCheck(name, func(ticked bool) { filters.Sites[name] = ticked }))
+		}
+		sites.Refresh()
+	})
+	mapSelect.PlaceHolder = "Map"
+	refreshMaps := func() {
+		seen := make(map[string]bool)
+		var names []string
+		for _, m := range getMetadata() {
+			key := Maps.Key{Name: m.MapName, WorkshopID: m.WorkshopID}
+			if display := key.DisplayName(); !seen[display] {
+				seen[display] = true
+				mapKeys[display] = key
+				names = append(names, display)
+			}
+		}
+		sort.Strings(names)
+		mapSelect.Options = names
+		mapSelect.Refresh()
+	}
+	refreshMaps()
+	tCheck := widget.NewCheck("T", func(t bool) { filters.T = t })
+	ctCheck := widget.NewCheck("CT", func(ct bool) { filters.CT = ct })
+	mode := widget.NewRadioGroup([]string{drillLanding, drillName}, nil)
+	mode.Horizontal = true
+	mode.SetSelected(drillLanding)
+
+	stats := widget.NewLabel("")
+	prompt := widget.NewLabel("Pick a player and a map, then press Start.")
+	prompt.Wrapping = fyne.TextWrapWord
+	answer := widget.NewLabel("")
+	answer.Wrapping = fyne.TextWrapWord
+	images := Carousel.New(fyne.NewSize(320, 180))
+
+	var gradeButtons []*widget.Button
+	revealBtn := widget.NewButton("Reveal", nil)
+	revealBtn.Disable()
+	setGrading := func(on bool) {
+		for _, b := range gradeButtons {
+			if on {
+				b.Enable()
+			} else {
+				b.Disable()
+			}
+		}
+	}
+
+	names := func() []string {
+		var n []string
+		for _, m := range pool {
+			n = append(n, m.NadeName)
+		}
+		return n
+	}
+	playerName := func() string { return strings.TrimSpace(player.Text) }
+
+	// next asks about the nade that is most due, or says when nothing is left
+	next := func() {
+		current = nil
+		answer.SetText("")
+		setGrading(false)
+		cards := store.Cards(playerName())
+		stats.SetText(Drill.Summary(cards, names(), time.Now()).String())
+		name, ok := Drill.Next(cards, names(), time.Now())
+		if !ok {
+			prompt.SetText("Nothing is due for these nades. Come back later or pick other filters.")
+			images.SetItems(nil)
+			revealBtn.Disable()
+			return
+		}
+		for i := range pool {
+			if pool[i].NadeName == name {
+				current = &pool[i]
+			}
+		}
+
+		if mode.Selected == drillName {
+			prompt.SetText(fmt.Sprintf("%s (%s). Where do you stand and aim?", current.NadeName, current.NadeType))
+			images.SetItems(nil)
+		} else {
+			where := strings.TrimSpace(current.Callout + " " + current.SiteLocation)
+			if where == "" {
+				where = "the spot shown"
+			}
+			prompt.SetText(fmt.Sprintf("A %s %s landing at %s. Where do you stand and aim?", current.Side, current.NadeType, where))
+			if img, ok := Drill.LandingImage(current.Images); ok {
+				images.SetItems(MetadataExplorer.CarouselItems([]Tags.Image{img}))
+			} else {
+				images.SetItems(nil)
+			}
+		}
+		revealBtn.Enable()
+	}
+
+	revealBtn.OnTapped = func() {
+		if current == nil {
+			return
+		}
+		text := current.NadeName
+		if current.Description != "" {
+			text += "\n" + current.Description
+		}
+		stand, aim, err := Drill.Instructions(current.FilePath)
+		if err != nil {
+			log.Printf("Error reading instructions: %v", err)
+		}
+		if stand != "" {
+			text += "\nStand: " + stand
+		}
+		if aim != "" {
+			text += "\nAim: " + aim
+		}
+		answer.SetText(text)
+		images.SetItems(MetadataExplorer.CarouselItems(current.Images))
+		revealBtn.Disable()
+		setGrading(true)
+	}
+
+	for i, label := range Drill.GradeNames {
+		grade := Drill.Grade(i)
+		b := widget.NewButton(label, func() {
+			if current == nil {
+				return
+			}
+			store.Grade(playerName(), current.NadeName, grade, time.Now())
+			if err := store.Save(); err != nil {
+				log.Printf("Error saving drill stats: %v", err)
+			}
+			next()
+		})
+		b.Disable()
+		gradeButtons = append(gradeButtons, b)
+	}
+
+	startBtn := widget.NewButton("Start", func() {
+		if playerName() == "" {
+			dialog.ShowError(errors.New("enter a player name to keep their stats"), g.win)
+			return
+		}
+		if filters.MapPick == "" {
+			dialog.ShowError(errors.New("pick a map to drill"), g.win)
+			return
+		}
+		player.SetOptions(append(store.PlayerNames(), playerName()))
+		refreshMaps()
+		pool = MetadataExplorer.FilterMetadata(getMetadata(), filters)
+		next()
+	})
+
+	var gradeObjects []fyne.CanvasObject
+	for _, b := range gradeButtons {
+		gradeObjects = append(gradeObjects, b)
+	}
+	top := container.NewVBox(
+		container.NewGridWithColumns(2, player, mapSelect),
+		container.NewHBox(tCheck, ctCheck, mode),
+		sites,
+		container.NewBorder(nil, nil, nil, startBtn, stats),
+	)
+	bottom := container.NewVBox(answer, revealBtn, container.NewGridWithColumns(len(gradeObjects), gradeObjects...))
+	return container.NewBorder(container.NewVBox(top, prompt), bottom, nil, nil, images)
+}
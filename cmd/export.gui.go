@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Export"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+var exportFormatNames = map[string]string{
+	"HTML":     Export.HTML,
+	"Markdown": Export.Markdown,
+	"PDF":      Export.PDF,
+}
+
+// showExport asks for a format and folder and writes a stratbook per map.
+// selected is the File Generator selection; the book can be limited to it.
+func (g *gui) showExport(selected []string) {
+	formatSelect := widget.NewSelect([]string{"HTML", "Markdown", "PDF"}, nil)
+	formatSelect.SetSelected("HTML")
+	onlySelected := widget.NewCheck(fmt.Sprintf("Only the %d selected nade(s)", len(selected)), nil)
+	onlySelected.SetChecked(len(selected) > 0)
+	if len(selected) == 0 {
+		onlySelected.Disable()
+	}
+
+	dialog.ShowForm("Export Stratbook", "Choose Folder...", "Cancel",
+		[]*widget.FormItem{
+			widget.NewFormItem("Format", formatSelect),
+			widget.NewFormItem("", onlySelected),
+		},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			var files []string
+			if onlySelected.Checked {
+				files = selected
+			}
+			g.chooseExportFolder(exportFormatNames[formatSelect.Selected], files)
+		}, g.win)
+}
+
+// chooseExportFolder writes the books into the folder picked in a folder dialog
+func (g *gui) chooseExportFolder(format string, files []string) {
+	open := dialog.NewFolderOpen(func(lu fyne.ListableURI, err error) {
+		if err != nil {
+			dialog.ShowError(err, g.win)
+			return
+		}
+		if lu == nil {
+			return // canceled
+		}
+		dir := lu.Path()
+
+		nades, err := Tags.LoadTags(g.Tags_path)
+		if err != nil {
+			log.Printf("Error loading tags for export: %v", err)
+			dialog.ShowError(err, g.win)
+			return
+		}
+		books := Export.Books(nades, files)
+		if len(books) == 0 {
+			dialog.ShowInformation("Export Stratbook", "There are no nades to export.", g.win)
+			return
+		}
+		paths, err := Export.Write(dir, books, format)
+		if err != nil {
+			log.Printf("Error exporting stratbook: %v", err)
+			if len(paths) == 0 {
+				dialog.ShowError(err, g.win)
+				return
+			}
+		}
+		text := "Wrote:\n  " + strings.Join(paths, "\n  ")
+		if err != nil {
+			text += "\n\nFailed:\n  " + strings.ReplaceAll(err.Error(), "\n", "\n  ")
+		}
+		dialog.ShowConfirm("Stratbook Exported", text+"\n\nOpen the folder?", func(ok bool) {
+			if ok {
+				g.openFolder(dir)
+			}
+		}, g.win)
+	}, g.win)
+
+	if lister, err := storage.ListerForURI(storage.NewFileURI(g.Output_path)); err == nil {
+		open.SetLocation(lister)
+	}
+	open.Show()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Lineup"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+)
+
+// showNewLineup opens a window that writes a lineup annotation file from a
+// setpos/setang string and a landing position
+func (g *gui) showNewLineup() {
+	w := g.App.NewWindow("New Lineup")
+
+	name := widget.NewEntry()
+	mapSelect := widget.NewSelectEntry(Maps.Names())
+	typeSelect := widget.NewSelect(Lineup.GrenadeTypes, nil)
+	setpos := widget.NewEntry()
+	setpos.SetPlaceHolder("setpos x y z;setang pitch yaw roll")
+	land := widget.NewEntry()
+	land.SetPlaceHolder("x y z, or setpos from getpos where it lands")
+	title := widget.NewEntry()
+	title.SetPlaceHolder("Same as the name")
+	standText := widget.NewEntry()
+	aimText := widget.NewEntry()
+	jump := widget.NewCheck("Jump", nil)
+	run := widget.NewCheck("Run", nil)
+	walk := widget.NewCheck("Walk", nil)
+	crouch := widget.NewCheck("Crouch", nil)
+	click := widget.NewRadioGroup([]string{Lineup.ClickLeft, Lineup.ClickRight, Lineup.ClickBoth}, nil)
+	click.Horizontal = true
+	click.SetSelected(Lineup.ClickLeft)
+
+	form := widget.NewForm(
+		widget.NewFormItem("Name", name),
+		widget.NewFormItem("Map", mapSelect),
+		widget.NewFormItem("Grenade", typeSelect),
+		widget.NewFormItem("Stand (getpos)", setpos),
+		widget.NewFormItem("Landing", land),
+		widget.NewFormItem("Title", title),
+		widget.NewFormItem("Stand text", standText),
+		widget.NewFormItem("Aim text", aimText),
+		widget.NewFormItem("Throw", container.NewHBox(jump, run, walk, crouch)),
+		widget.NewFormItem("Click", click),
+	)
+	form.SubmitText = "Create"
+	form.OnSubmit = func() {
+		stand, err := Lineup.ParseSetpos(setpos.Text)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		landing, err := Lineup.ParsePosition(land.Text)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		path, err := Lineup.Write(g.Annotation_path, Lineup.Lineup{
+			Name:        strings.TrimSpace(name.Text),
+			MapName:     strings.TrimSpace(mapSelect.Text),
+			GrenadeType: typeSelect.Selected,
+			Stand:       stand,
+			Landing:     landing,
+			Throw:       Lineup.Throw{Jump: jump.Checked, Run: run.Checked, Walk: walk.Checked, Crouch: crouch.Checked, Click: click.Selected},
+			Title:       strings.TrimSpace(title.Text),
+			StandText:   standText.Text,
+			AimText:     aimText.Text,
+		})
+		if err != nil {
+			log.Printf("Error creating lineup: %v", err)
+			dialog.ShowError(err, w)
+			return
+		}
+		log.Printf("Lineup written to %s", path)
+		dialog.ShowInformation("Lineup Created", path+"\n\nLoad it in game with annotations_load, or tag it with Generate New Tags.", w)
+	}
+
+	w.SetContent(form)
+	w.Resize(fyne.NewSize(600, 450))
+	w.Show()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Lint"
+)
+
+// showLint opens a window that lints the annotation folder with the ticked rules
+func (g *gui) showLint() {
+	w := g.App.NewWindow("Lint Annotations")
+
+	enabled := make(map[string]*widget.Check)
+	var ruleChecks []fyne.CanvasObject
+	for _, r := range Lint.Rules {
+		check := widget.NewCheck(r.ID, nil)
+		check.SetChecked(true)
+		enabled[r.ID] = check
+		ruleChecks = append(ruleChecks, check)
+	}
+	summary := widget.NewLabel("")
+
+	var lines []string
+	results := widget.NewList(
+		func() int { return len(lines) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) { o.(*widget.Label).SetText(lines[i]) },
+	)
+
+	run := func() {
+		cfg := Lint.Config{Disabled: make(map[string]bool)}
+		for id, check := range enabled {
+			cfg.Disabled[id] = !check.Checked
+		}
+		report, err := Lint.Run(g.Annotation_path, cfg)
+		if err != nil {
+			log.Printf("Error linting %s: %v", g.Annotation_path, err)
+			summary.SetText("Error: " + err.Error())
+			return
+		}
+
+		lines = lines[:0]
+		for _, f := range report.Findings {
+			lines = append(lines, f.String())
+		}
+		errs, warns := report.Count()
+		summary.SetText(fmt.Sprintf("%d file(s) checked, %d error(s), %d warning(s)", report.Files, errs, warns))
+		results.Refresh()
+	}
+
+	top := container.NewVBox(
+		container.NewGridWithColumns(4, ruleChecks...),
+		widget.NewButton("Run", run),
+		summary,
+	)
+	w.SetContent(container.NewBorder(top, nil, nil, nil, results))
+	w.Resize(fyne.NewSize(900, 500))
+	run()
+	w.Show()
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
-	"encoding/json"
 	"log"
 	"os"
-	"regexp"
+	"path/filepath"
 
 	"fyne.io/fyne/v2/app"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Carousel"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Classifier"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/ScanCache"
 	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
 )
 
@@ -15,13 +17,27 @@ import (
 //"log"
 
 func main() {
+	// Subcommands like "lint" run without the GUI. They only read the settings,
+	// so no log, settings or tags file is left in the working directory.
+	readSettings := func() Settings {
+		s, _ := ReadSettings()
+		loadMaps(s)
+		return s
+	}
+	if code, ok := runCommand(os.Args[1:], readSettings); ok {
+		os.Exit(code)
+	}
+
 	// Load from file (or defaults if not found)
 	settings := LoadSettings()
+	loadMaps(settings)
 
 	a := app.New()
 	//	loadTheme(a)
 
-	g := newGUI(a, settings.TagsPath, settings.AnnotationPath)
+	g := newGUI(a, settings)
+	// Previews show thumbnails, loaded in the background
+	Carousel.DefaultLoader = g.thumbs
 	w := g.makeWindow(a)
 
 	g.setupActions()
@@ -37,66 +53,76 @@ func (g *gui) setupActions() {
 //}
 
 func (g *gui) generate_tags() {
+	g.generateTagsFor(nil)
+}
+
+// generateTagsFor tags the nades in the named annotation folders, or every folder when folders is nil
+func (g *gui) generateTagsFor(folders []string) {
 	log.Println("Generating new tags...")
 
-	// Step 1: Get all text/png files from the annotation folder
-	files, err := Tags.GetFilePaths(g.Annotation_path)
+	// Step 1: Get all text/png files from the annotation folder. The scan cache
+	// only reads the files that changed since the last scan.
+	cache, err := ScanCache.Load(g.ScanCache_path)
+	if err != nil {
+		log.Printf("Error loading scan cache: %v\n", err)
+	}
+	scan, err := cache.Scan(g.Annotation_path)
 	if err != nil {
 		log.Printf("Error getting file paths from %s: %v\n", g.Annotation_path, err)
 		return
 	}
+	if err := cache.Save(); err != nil {
+		log.Printf("Error saving scan cache: %v\n", err)
+	}
+	files := scan.Files
 
-	// Step 2: Build initial metadata slice (mapName and nadeType extracted here)
-	var metadataList []Tags.AnnotationMetadata
-	for baseName, fileInfo := range files {
-		fileText, err := os.ReadFile(fileInfo.TxtPath)
-		if err != nil {
-			log.Printf("Error reading file %s: %v", fileInfo.TxtPath, err)
-			continue
+	// Step 1.5: Follow tagged nades whose files moved, and report the ones that were edited
+	existing, err := Tags.LoadTags(g.Tags_path)
+	if err != nil {
+		log.Printf("Error loading %s: %v\n", g.Tags_path, err)
+	}
+	tagged := ScanCache.Tagged(scan, existing)
+	if len(tagged.Moved) > 0 {
+		existing = ScanCache.ApplyMoves(existing, tagged.Moved, files)
+		if err := Tags.SaveTags(g.Tags_path, existing); err != nil {
+			log.Printf("Error updating moved nades: %v\n", err)
 		}
+	}
+	g.showTaggedChanges(tagged, scan.Warnings)
 
-		// Extract map name
-		mapName := ""
-		if m := regexp.MustCompile(`de_\w+`).FindString(string(fileText)); m != "" {
-			mapName = m
+	if folders != nil {
+		only := make(map[string]bool)
+		for _, f := range folders {
+			only[f] = true
 		}
-
-		// Extract nade type
-		nadeType := ""
-		if match := regexp.MustCompile(`GrenadeType = "([^"]+)"`).FindStringSubmatch(string(fileText)); len(match) > 1 {
-			nadeType = match[1]
+		for baseName, f := range files {
+			if !only[f.ParentPath] {
+				delete(files, baseName)
+			}
 		}
+	}
 
-		metadata := Tags.AnnotationMetadata{
-			FileName:    baseName + ".txt",
-			FilePath:    fileInfo.TxtPath,
-			ImagePath:   fileInfo.PngPath,
-			NadeName:    fileInfo.ParentPath,
-			MapName:     mapName,
-			NadeType:    nadeType,
-			Description: "", // user fills in GUI
-			Side:        "", // user selects GUI
-			Site:        "", // user selects GUI
-		}
-		metadataList = append(metadataList, metadata)
+	// Step 2: Build initial metadata slice (mapName and nadeType read from the file keys)
+	metadataList, err := Tags.GenerateMetadataWith(files, cache.ReadAnnotationInfo)
+	if err != nil {
+		log.Printf("Error generating metadata from %s: %v\n", g.Annotation_path, err)
 	}
 
-	// Step 2.5: Filter out duplicates based on NadeName so user is not prompted for them.
+	// Step 2.1: Filter out nades already in tags.json so user is not prompted for them:
+	// the same name, the same file, or a copy of a tagged file
 	existingNames := make(map[string]bool)
-	// Load Existing tags.json
-	if data, err := os.ReadFile(g.Tags_path); err == nil {
-		var merged struct {
-			Nades []Tags.AnnotationMetadata `json:"nades"`
-		}
-		if err := json.Unmarshal(data, &merged); err == nil {
-			for _, nade := range merged.Nades {
-				existingNames[nade.NadeName] = true
-			}
+	existingPaths := make(map[string]bool)
+	existingHashes := make(map[string]bool)
+	for _, nade := range existing {
+		existingNames[nade.NadeName] = true
+		existingPaths[filepath.Clean(nade.FilePath)] = true
+		if h := cache.Hash(nade.FilePath); h != "" {
+			existingHashes[h] = true
 		}
 	}
 	var filteredList []Tags.AnnotationMetadata
 	for _, m := range metadataList {
-		if _, exists := existingNames[m.NadeName]; exists {
+		if existingNames[m.NadeName] || existingPaths[filepath.Clean(m.FilePath)] || existingHashes[cache.Hash(m.FilePath)] {
 			log.Printf("[generate_tags] Skipping duplicate: %s\n", m.NadeName)
 			continue
 		}
@@ -105,6 +131,16 @@ func (g *gui) generate_tags() {
 	metadataList = filteredList
 	log.Println("[generate_tags] MetadataList:", metadataList)
 
+	// Step 2.5: Prefill side, site and callout from where each nade is thrown and lands
+	for i := range metadataList {
+		s, err := Classifier.Classify(metadataList[i].FilePath)
+		if err != nil {
+			log.Printf("[generate_tags] Could not classify %s: %v\n", metadataList[i].NadeName, err)
+			continue
+		}
+		Classifier.Apply(&metadataList[i], s, false)
+	}
+
 	// Step 3: Prompt user to edit metadata for all nades in a single window
 	updatedList, err := Tags.PromptUserForAllNades(g.App, metadataList)
 	if err != nil {
@@ -1,31 +1,102 @@
 package main
 
 import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Carousel"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Collisions"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Drafts"
 	"github.com/yahzoos/CS-StratBook/cmd/pkg/FileGenerator"
 	"github.com/yahzoos/CS-StratBook/cmd/pkg/MetadataExplorer"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/ScanCache"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Styles"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Thumbnails"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Watcher"
+	_ "golang.org/x/image/webp" // .webp screenshots
 )
 
 type gui struct {
-	App             fyne.App
-	win             fyne.Window
-	Tags_path       string
-	Annotation_path string
+	App              fyne.App
+	win              fyne.Window
+	Tags_path        string
+	Annotation_path  string
+	Drafts_path      string
+	Output_path      string
+	Styles_path      string
+	Radar_path       string
+	ScanCache_path   string
+	Thumbnails_path  string
+	Screenshots_path string
+	Cfg_path         string
+	Drill_path       string
+	Maps_path        string
+
+	thumbs  *Thumbnails.Cache
+	watcher *Watcher.Watcher
+
+	offeredMu sync.Mutex
+	offered   map[string]bool // untagged folders already offered for tagging
 }
 
-func newGUI(a fyne.App, tagsPath, annotationPath string) *gui {
+func newGUI(a fyne.App, settings Settings) *gui {
 	return &gui{
-		App:             a,
-		Tags_path:       tagsPath,
-		Annotation_path: annotationPath,
+		App:              a,
+		Tags_path:        settings.TagsPath,
+		Annotation_path:  settings.AnnotationPath,
+		Drafts_path:      settings.DraftsPath,
+		Output_path:      settings.OutputPath,
+		Styles_path:      settings.StylesPath,
+		Radar_path:       settings.RadarPath,
+		ScanCache_path:   settings.ScanCachePath,
+		Thumbnails_path:  settings.ThumbnailsPath,
+		Screenshots_path: settings.ScreenshotsPath,
+		Cfg_path:         settings.CfgPath,
+		Drill_path:       settings.DrillPath,
+		Maps_path:        settings.MapsPath,
+		thumbs:           Thumbnails.New(settings.ThumbnailsPath, Thumbnails.DefaultSize),
 	}
 }
 
+// saveSettings writes the current paths back to settings.json
+func (g *gui) saveSettings() {
+	SaveSettings(Settings{
+		TagsPath:        g.Tags_path,
+		AnnotationPath:  g.Annotation_path,
+		DraftsPath:      g.Drafts_path,
+		OutputPath:      g.Output_path,
+		StylesPath:      g.Styles_path,
+		RadarPath:       g.Radar_path,
+		ScanCachePath:   g.ScanCache_path,
+		ThumbnailsPath:  g.Thumbnails_path,
+		ScreenshotsPath: g.Screenshots_path,
+		CfgPath:         g.Cfg_path,
+		DrillPath:       g.Drill_path,
+		MapsPath:        g.Maps_path,
+	})
+}
+
+// warmThumbnails makes thumbnails of every nade's screenshots in the background
+func (g *gui) warmThumbnails(metadata []MetadataExplorer.Metadata) {
+	var paths []string
+	for _, m := range metadata {
+		for _, img := range m.Images {
+			paths = append(paths, img.Path)
+		}
+	}
+	g.thumbs.Warm(paths)
+}
+
 func (g *gui) makeUI() fyne.CanvasObject {
 	tagsEntry := widget.NewEntry()
 	tagsEntry.SetText(g.Tags_path)
@@ -33,22 +104,75 @@ func (g *gui) makeUI() fyne.CanvasObject {
 	annotationEntry := widget.NewEntry()
 	annotationEntry.SetText(g.Annotation_path)
 
+	outputFolderEntry := widget.NewEntry()
+	outputFolderEntry.SetText(g.Output_path)
+
+	radarFolderEntry := widget.NewEntry()
+	radarFolderEntry.SetText(g.Radar_path)
+
 	var metadataTab *container.TabItem
 	var reloadFunc func()
 	var nadeList *FileGenerator.NadeList
+
+	// The tags.json watcher swaps the metadata from its own goroutine, so
+	// allMetadata and updateExplorer are only used under metadataMu
+	var metadataMu sync.Mutex
 	var allMetadata []MetadataExplorer.Metadata
+	var updateExplorer func([]MetadataExplorer.Metadata)
+	currentMetadata := func() []MetadataExplorer.Metadata {
+		metadataMu.Lock()
+		defer metadataMu.Unlock()
+		return allMetadata
+	}
+
+	// ---- Drafts ----
+	// The selection lives in a named draft so it survives reloads and restarts
+	drafts, err := Drafts.Load(g.Drafts_path)
+	if err != nil {
+		log.Printf("Error loading drafts: %v", err)
+	}
+	if drafts.Active == "" {
+		drafts.Active = Drafts.DefaultDraftName
+	}
+	nadeList = &FileGenerator.NadeList{}
+	if d, ok := drafts.Get(drafts.Active); ok {
+		nadeList.Files = append([]string(nil), d.Files...)
+	} else {
+		drafts.Put(drafts.Active, nadeList.Files)
+	}
 
 	reloadFunc = func() {
-		result := MetadataExplorer.MetadataExplorer(g.Tags_path, reloadFunc)
+		result := MetadataExplorer.MetadataExplorer(g.Tags_path, g.Radar_path, reloadFunc, nadeList)
 		metadataTab.Content = result.UI
 		nadeList = result.NadeList
+		metadataMu.Lock()
 		allMetadata = result.Metadata
+		updateExplorer = result.Update
+		metadataMu.Unlock()
 	}
 
-	result := MetadataExplorer.MetadataExplorer(g.Tags_path, reloadFunc)
+	result := MetadataExplorer.MetadataExplorer(g.Tags_path, g.Radar_path, reloadFunc, nadeList)
 	metadataTab = container.NewTabItem("Metadata Explorer", result.UI)
 	nadeList = result.NadeList
 	allMetadata = result.Metadata
+	updateExplorer = result.Update
+	g.warmThumbnails(allMetadata)
+
+	// Edits to tags.json made outside the explorer are picked up without a refresh
+	onTagsChanged := func() {
+		metadata, err := MetadataExplorer.LoadMetadata(g.Tags_path)
+		if err != nil {
+			log.Printf("Error reloading %s: %v", g.Tags_path, err)
+			return
+		}
+		metadataMu.Lock()
+		allMetadata = metadata
+		update := updateExplorer
+		metadataMu.Unlock()
+		update(metadata)
+		g.warmThumbnails(metadata)
+	}
+	g.watch(onTagsChanged)
 
 	// ---- File Generator Tab ----
 	nadeListWidget := widget.NewList(
@@ -57,27 +181,153 @@ func (g *gui) makeUI() fyne.CanvasObject {
 		func(i int, o fyne.CanvasObject) { o.(*widget.Label).SetText(nadeList.Files[i]) },
 	)
 
-	var nadeImage = canvas.NewImageFromFile("")
-	nadeImage.FillMode = canvas.ImageFillContain
+	nadeImages := Carousel.New(fyne.NewSize(200, 150))
 
 	nadeListWidget.OnSelected = func(id widget.ListItemID) {
 		if id >= 0 && id < len(nadeList.Files) {
 			selectedFile := nadeList.Files[id]
-			for _, m := range allMetadata {
+			for _, m := range currentMetadata() {
 				if m.FilePath == selectedFile {
-					nadeImage.File = m.ImagePath
-					nadeImage.Refresh()
+					nadeImages.SetItems(MetadataExplorer.CarouselItems(m.Images))
 					break
 				}
 			}
 		}
 	}
 
+	draftSelect := widget.NewSelect(drafts.Names(), func(name string) {
+		if name == drafts.Active {
+			return
+		}
+		log.Println("Switching to draft", name)
+		drafts.Active = name
+		d, _ := drafts.Get(name)
+		nadeList.SetFiles(d.Files)
+	})
+	draftSelect.SetSelected(drafts.Active)
+
+	// Persist every change to the active draft
+	nadeList.OnChanged = func() {
+		drafts.Put(drafts.Active, nadeList.Files)
+		if err := drafts.Save(); err != nil {
+			log.Printf("Error saving drafts: %v", err)
+		}
+		nadeListWidget.UnselectAll()
+		nadeListWidget.Refresh()
+		draftSelect.Options = drafts.Names()
+		draftSelect.SetSelected(drafts.Active)
+	}
+
+	saveAsBtn := widget.NewButton("Save As...", func() {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetPlaceHolder("e.g. inferno B exec v2")
+		dialog.ShowForm("Save Draft As", "Save", "Cancel",
+			[]*widget.FormItem{widget.NewFormItem("Name", nameEntry)},
+			func(ok bool) {
+				name := strings.TrimSpace(nameEntry.Text)
+				if !ok || name == "" {
+					return
+				}
+				if _, exists := drafts.Get(name); exists {
+					dialog.ShowError(fmt.Errorf("a draft named %q already exists", name), g.win)
+					return
+				}
+				// The new draft starts as a copy of the current selection
+				drafts.Active = name
+				nadeList.SetFiles(nadeList.Files)
+			}, g.win)
+	})
+
+	deleteDraftBtn := widget.NewButton("Delete", func() {
+		dialog.ShowConfirm("Delete Draft", fmt.Sprintf("Delete draft %q?", drafts.Active), func(ok bool) {
+			if !ok {
+				return
+			}
+			drafts.Delete(drafts.Active)
+			names := drafts.Names()
+			if len(names) == 0 {
+				drafts.Active = Drafts.DefaultDraftName
+				nadeList.SetFiles(nil)
+				return
+			}
+			drafts.Active = names[0]
+			d, _ := drafts.Get(names[0])
+			nadeList.SetFiles(d.Files)
+		}, g.win)
+	})
+
+	diffBtn := widget.NewButton("Diff...", func() {
+		otherSelect := widget.NewSelect(drafts.Names(), nil)
+		dialog.ShowForm("Compare Drafts", "Compare", "Cancel",
+			[]*widget.FormItem{
+				widget.NewFormItem("Current", widget.NewLabel(drafts.Active)),
+				widget.NewFormItem("Compare with", otherSelect),
+			},
+			func(ok bool) {
+				if !ok || otherSelect.Selected == "" {
+					return
+				}
+				a, _ := drafts.Get(drafts.Active)
+				b, _ := drafts.Get(otherSelect.Selected)
+				g.showDraftDiff(a, b)
+			}, g.win)
+	})
+
+	draftBar := container.NewBorder(nil, nil, widget.NewLabel("Draft:"),
+		container.NewHBox(saveAsBtn, deleteDraftBtn, diffBtn),
+		draftSelect,
+	)
+
 	outputEntry := widget.NewEntry()
-	outputEntry.SetPlaceHolder("Enter output file...")
+	outputEntry.SetPlaceHolder("Enter output file name...")
+
+	mergeCheck := widget.NewCheck("Merge lineups thrown from the same spot", nil)
+	teleportCheck := widget.NewCheck("Write teleport binds ("+FileGenerator.TeleportNextKey+" / "+FileGenerator.TeleportPrevKey+")", nil)
+
+	// Style profiles restyle every node of the pack; "As recorded" leaves them alone
+	const noStyle = "As recorded"
+	profiles, err := Styles.LoadProfiles(g.Styles_path)
+	if err != nil {
+		log.Printf("Error loading style profiles: %v", err)
+	}
+	styleSelect := widget.NewSelect(append([]string{noStyle}, Styles.Names(profiles)...), nil)
+	styleSelect.SetSelected(noStyle)
 
 	generateBtn := widget.NewButton("Generate File", func() {
-		FileGenerator.FileGeneratorFromList(outputEntry.Text, nadeList)
+		name, err := FileGenerator.NormalizeOutputName(outputEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, g.win)
+			return
+		}
+		outputEntry.SetText(name)
+
+		var nadeNames []string
+		for _, m := range currentMetadata() {
+			nadeNames = append(nadeNames, m.NadeName)
+		}
+		g.chooseOutputFile(name, nadeNames, func(path string) {
+			var opts FileGenerator.Options
+			if mergeCheck.Checked {
+				opts.MergeTolerance = FileGenerator.DefaultMergeTolerance
+			}
+			if p, ok := profiles[styleSelect.Selected]; ok {
+				opts.Style = &p
+			}
+			if teleportCheck.Checked {
+				// Next to the pack when the cfg folder isn't known, to be copied by hand
+				opts.TeleportDir = cfgFolder(g.Cfg_path, g.Annotation_path)
+				if opts.TeleportDir == "" {
+					opts.TeleportDir = filepath.Dir(path)
+				}
+			}
+			result, err := FileGenerator.FileGeneratorFromList(path, nadeList, opts)
+			if err != nil {
+				log.Printf("Error generating %s: %v", path, err)
+				dialog.ShowError(err, g.win)
+				return
+			}
+			g.showGenerateResult(result)
+		})
 	})
 	generateBtn.Disable()
 
@@ -89,14 +339,31 @@ func (g *gui) makeUI() fyne.CanvasObject {
 		}
 	}
 
-	leftSide := container.NewBorder(nil,
-		container.NewVBox(outputEntry, generateBtn),
+	collisionsBtn := widget.NewButton("Check Text Collisions", func() {
+		files := append([]string(nil), nadeList.Files...)
+		g.showCollisions("Text Collisions: "+drafts.Active, func(distance float64) (Collisions.Report, error) {
+			return Collisions.AnalyzeFiles(files, distance), nil
+		})
+	})
+
+	exportBtn := widget.NewButton("Export Stratbook...", func() {
+		g.showExport(append([]string(nil), nadeList.Files...))
+	})
+
+	pracBtn := widget.NewButton("Practice Config...", func() {
+		g.showPracConfig(append([]string(nil), nadeList.Files...), outputEntry.Text)
+	})
+
+	leftSide := container.NewBorder(draftBar,
+		container.NewVBox(collisionsBtn, exportBtn, pracBtn, mergeCheck, teleportCheck,
+			container.NewBorder(nil, nil, widget.NewLabel("Style:"), nil, styleSelect),
+			outputEntry, generateBtn),
 		nil, nil,
 		nadeListWidget,
 	)
 
 	fileGenTab := container.NewTabItem("File Generator",
-		container.NewHSplit(leftSide, nadeImage),
+		container.NewHSplit(leftSide, nadeImages),
 	)
 
 	// ---- Tabs ----
@@ -109,11 +376,9 @@ func (g *gui) makeUI() fyne.CanvasObject {
 						tagsEntry,
 						widget.NewButton("Save Tags Path", func() {
 							g.Tags_path = tagsEntry.Text
-							SaveSettings(Settings{
-								TagsPath:       g.Tags_path,
-								AnnotationPath: g.Annotation_path,
-							})
+							g.saveSettings()
 							checkFile(g.Tags_path)
+							g.watch(onTagsChanged)
 						}),
 					),
 					container.NewGridWithColumns(3,
@@ -121,17 +386,41 @@ func (g *gui) makeUI() fyne.CanvasObject {
 						annotationEntry,
 						widget.NewButton("Save Annotation Path", func() {
 							g.Annotation_path = annotationEntry.Text
-							SaveSettings(Settings{
-								TagsPath:       g.Tags_path,
-								AnnotationPath: g.Annotation_path,
-							})
+							g.saveSettings()
+							g.watch(onTagsChanged)
+						}),
+					),
+					container.NewGridWithColumns(3,
+						widget.NewLabel("Output Folder:"),
+						outputFolderEntry,
+						widget.NewButton("Save Output Path", func() {
+							g.Output_path = outputFolderEntry.Text
+							g.saveSettings()
+						}),
+					),
+					container.NewGridWithColumns(3,
+						widget.NewLabel("Radar Folder:"),
+						radarFolderEntry,
+						widget.NewButton("Save Radar Path", func() {
+							g.Radar_path = radarFolderEntry.Text
+							g.saveSettings()
 						}),
 					),
 					widget.NewButton("Generate New Tags", g.generate_tags),
+					widget.NewButton("New Lineup from getpos", g.showNewLineup),
+					widget.NewButton("Import Screenshots", g.showScreenshotImport),
+					widget.NewButton("Sync Annotation Text", g.showTextSync),
+					widget.NewButton("Lint Annotations", g.showLint),
+					widget.NewButton("Check Text Collisions", func() {
+						g.showCollisions("Text Collisions: "+g.Annotation_path, func(distance float64) (Collisions.Report, error) {
+							return Collisions.AnalyzeLibrary(g.Annotation_path, distance)
+						})
+					}),
 				),
 			),
 			metadataTab,
 			fileGenTab,
+			container.NewTabItem("Drill", g.makeDrillTab(currentMetadata)),
 		),
 	)
 }
@@ -143,3 +432,149 @@ func (g *gui) makeWindow(a fyne.App) fyne.Window {
 	w.SetContent(g.makeUI())
 	return w
 }
+
+// showDraftDiff lists the nades that only appear in one of the two drafts
+func (g *gui) showDraftDiff(a, b Drafts.Draft) {
+	diff := Drafts.Diff(a, b)
+
+	section := func(title string, files []string) string {
+		text := fmt.Sprintf("%s (%d)\n", title, len(files))
+		for _, f := range files {
+			text += "  " + f + "\n"
+		}
+		return text
+	}
+	text := section("Only in "+a.Name, diff.OnlyA) + "\n" +
+		section("Only in "+b.Name, diff.OnlyB) + "\n" +
+		section("In both", diff.Both)
+
+	label := widget.NewLabel(text)
+	scroll := container.NewVScroll(label)
+	scroll.SetMinSize(fyne.NewSize(500, 400))
+	dialog.ShowCustom(a.Name+" vs "+b.Name, "Close", scroll, g.win)
+}
+
+// chooseOutputFile shows a save dialog rooted at the output folder, checks the
+// chosen name and warns if it is already used before calling generate
+func (g *gui) chooseOutputFile(name string, nadeNames []string, generate func(path string)) {
+	save := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, g.win)
+			return
+		}
+		if uc == nil {
+			return // canceled
+		}
+		// The dialog creates the file; FileGenerator writes it, so just close it here
+		chosenPath := uc.URI().Path()
+		uc.Close()
+		removeIfEmpty := func() {
+			if info, err := os.Stat(chosenPath); err == nil && info.Size() == 0 {
+				os.Remove(chosenPath)
+			}
+		}
+
+		chosenName, err := FileGenerator.NormalizeOutputName(filepath.Base(chosenPath))
+		if err != nil {
+			removeIfEmpty()
+			dialog.ShowError(err, g.win)
+			return
+		}
+		outputPath := filepath.Join(filepath.Dir(chosenPath), chosenName)
+		if outputPath != chosenPath {
+			removeIfEmpty()
+		}
+
+		collisions := FileGenerator.FindCollisions(filepath.Dir(outputPath), chosenName, nadeNames)
+		if len(collisions) == 0 {
+			generate(outputPath)
+			return
+		}
+		dialog.ShowConfirm("Name Already Used",
+			"The name "+chosenName+" is already used:\n  "+strings.Join(collisions, "\n  ")+"\n\nGenerate anyway?",
+			func(ok bool) {
+				if ok {
+					generate(outputPath)
+				} else {
+					removeIfEmpty()
+				}
+			}, g.win)
+	}, g.win)
+
+	save.SetFileName(name)
+	save.SetFilter(storage.NewExtensionFileFilter([]string{".txt"}))
+	if lister, err := storage.ListerForURI(storage.NewFileURI(g.Output_path)); err == nil {
+		save.SetLocation(lister)
+	} else {
+		log.Printf("Output folder %s can't be used as dialog location: %v", g.Output_path, err)
+	}
+	save.Show()
+}
+
+// showGenerateResult reports a generated pack and offers to open its folder
+func (g *gui) showGenerateResult(result FileGenerator.Result) {
+	text := fmt.Sprintf("Created %s with %d nodes.", result.OutputPath, result.NodeCount)
+	if result.TeleportPath != "" {
+		name := strings.TrimSuffix(filepath.Base(result.TeleportPath), ".cfg")
+		text += fmt.Sprintf("\n\nTeleport binds written to %s. Run exec %s in game, then %s and %s step through the lineups.",
+			result.TeleportPath, name, FileGenerator.TeleportNextKey, FileGenerator.TeleportPrevKey)
+	}
+	if len(result.Skipped) > 0 {
+		text += fmt.Sprintf("\n\nSkipped %d file(s):", len(result.Skipped))
+		for _, f := range result.Skipped {
+			text += "\n  " + f
+		}
+	}
+	if len(result.Merged) > 0 {
+		text += "\n\nShared standing spots:"
+		for _, m := range result.Merged {
+			text += "\n  " + m
+		}
+	}
+	if len(result.Warnings) > 0 {
+		text += "\n\nWarnings:"
+		for _, w := range result.Warnings {
+			text += "\n  " + w
+		}
+	}
+
+	dialog.ShowCustomConfirm("File Generated", "Open Folder", "Close", widget.NewLabel(text), func(open bool) {
+		if open {
+			g.openFolder(filepath.Dir(result.OutputPath))
+		}
+	}, g.win)
+}
+
+// showTaggedChanges lists the tagged nades whose annotation files were edited or
+// moved since the last scan, and any files the scan couldn't pair
+func (g *gui) showTaggedChanges(tc ScanCache.TaggedChanges, warnings []string) {
+	if len(tc.Changed) == 0 && len(tc.Moved) == 0 && len(warnings) == 0 {
+		return
+	}
+	lines := append([]string(nil), warnings...)
+	for _, name := range tc.Changed {
+		lines = append(lines, name+": annotation file changed")
+	}
+	for _, m := range tc.Moved {
+		lines = append(lines, fmt.Sprintf("%s: moved to %s, tags.json updated", m.NadeName, m.To))
+	}
+	log.Printf("Scan results to check: %v", lines)
+	dialog.ShowInformation("Check These Annotations", strings.Join(lines, "\n"), g.win)
+}
+
+// openFolder opens a folder in the system file manager
+func (g *gui) openFolder(folder string) {
+	dir, err := filepath.Abs(folder)
+	if err != nil {
+		log.Printf("Error finding folder %s: %v", folder, err)
+		return
+	}
+	u, err := url.Parse(storage.NewFileURI(dir).String())
+	if err != nil {
+		log.Printf("Error building folder URL for %s: %v", dir, err)
+		return
+	}
+	if err := g.App.OpenURL(u); err != nil {
+		dialog.ShowError(err, g.win)
+	}
+}
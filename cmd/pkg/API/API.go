@@ -0,0 +1,406 @@
+package API
+
+// JSON API over tags.json for other tools: bots, spreadsheets, scrim trackers.
+// The routes are described in openapi.json, served at /api/openapi.json.
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Classifier"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/FileGenerator"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/MetadataExplorer"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Config says where the library is and whether the API may change it
+type Config struct {
+	TagsPath       string
+	AnnotationPath string
+	// Write adds the PATCH and POST routes. Without it the API only reads.
+	Write bool
+}
+
+// Server serves the API under /api/
+type Server struct {
+	cfg Config
+	mux *http.ServeMux
+	mu  sync.Mutex // held while tags.json is read and written back
+}
+
+// New builds the API. Its routes all start with /api/, so it can share a mux with the web UI.
+func New(cfg Config) *Server {
+	s := &Server{cfg: cfg, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /api/openapi.json", s.handleSpec)
+	s.mux.HandleFunc("GET /api/maps", s.handleMaps)
+	s.mux.HandleFunc("GET /api/nades", s.handleList)
+	s.mux.HandleFunc("GET /api/nades/{name}", s.handleGet)
+	if cfg.Write {
+		s.mux.HandleFunc("PATCH /api/nades/{name}", sameSite(s.handleUpdate))
+		s.mux.HandleFunc("POST /api/tags/generate", sameSite(s.handleGenerate))
+		s.mux.HandleFunc("POST /api/packs", sameSite(s.handlePack))
+	}
+	return s
+}
+
+// sameSite turns away requests that a page on another site could make the
+// browser send: ones with a foreign Origin, and ones that aren't JSON, which
+// a plain HTML form can't post.
+func sameSite(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				writeError(w, http.StatusForbidden, fmt.Errorf("requests from %s are not allowed", origin))
+				return
+			}
+		}
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, errors.New("the request needs Content-Type: application/json"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// errorBody is sent with every error status
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Printf("[API] Error writing response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorBody{err.Error()})
+}
+
+func (s *Server) handleSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
+
+// MapInfo is a map with nades in tags.json
+type MapInfo struct {
+	Name        string   `json:"name"`
+	WorkshopID  string   `json:"workshop_id,omitempty"`
+	DisplayName string   `json:"display_name"`
+	Nades       int      `json:"nades"`
+	Sites       []string `json:"sites"`
+	Callouts    []string `json:"callouts"`
+}
+
+func (s *Server) handleMaps(w http.ResponseWriter, r *http.Request) {
+	metadata, err := MetadataExplorer.LoadMetadata(s.cfg.TagsPath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	byKey := make(map[Maps.Key]*MapInfo)
+	for _, m := range metadata {
+		key := Maps.Key{Name: m.MapName, WorkshopID: m.WorkshopID}
+		info, ok := byKey[key]
+		if !ok {
+			info = &MapInfo{
+				Name:        m.MapName,
+				WorkshopID:  m.WorkshopID,
+				DisplayName: key.DisplayName(),
+				Sites:       Maps.Sites(m.MapName),
+				Callouts:    Maps.Callouts(m.MapName),
+			}
+			byKey[key] = info
+		}
+		info.Nades++
+	}
+	maps := []MapInfo{}
+	for _, info := range byKey {
+		maps = append(maps, *info)
+	}
+	sort.Slice(maps, func(i, j int) bool {
+		if maps[i].Name != maps[j].Name {
+			return maps[i].Name < maps[j].Name
+		}
+		return maps[i].WorkshopID < maps[j].WorkshopID
+	})
+	writeJSON(w, http.StatusOK, maps)
+}
+
+// handleList returns the nades matching the query filters. Without map it
+// looks at every map.
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	metadata, err := MetadataExplorer.LoadMetadata(s.cfg.TagsPath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	filters := MetadataExplorer.FiltersFromQuery(r.URL.Query())
+	nades := []MetadataExplorer.Metadata{}
+	if filters.MapPick != "" {
+		nades = append(nades, MetadataExplorer.FilterMetadata(metadata, filters)...)
+	} else {
+		for _, m := range metadata {
+			f := filters
+			f.MapPick, f.WorkshopID = m.MapName, m.WorkshopID
+			nades = append(nades, MetadataExplorer.FilterMetadata([]MetadataExplorer.Metadata{m}, f)...)
+		}
+	}
+	writeJSON(w, http.StatusOK, nades)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	nades, err := Tags.LoadTags(s.cfg.TagsPath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	i := indexOf(nades, r.PathValue("name"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no nade named %q", r.PathValue("name")))
+		return
+	}
+	writeJSON(w, http.StatusOK, nades[i])
+}
+
+func indexOf(nades []Tags.AnnotationMetadata, name string) int {
+	for i, n := range nades {
+		if n.NadeName == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Update holds the fields a PATCH can change. Fields left out keep their value.
+type Update struct {
+	Description *string `json:"description"`
+	Side        *string `json:"side"`
+	Site        *string `json:"site"`
+	Callout     *string `json:"callout"`
+	ThrowZone   *string `json:"throw_zone"`
+}
+
+// Apply sets the fields given in the update
+func (u Update) Apply(nade *Tags.AnnotationMetadata) {
+	for _, f := range []struct {
+		value *string
+		field *string
+	}{
+		{u.Description, &nade.Description},
+		{u.Side, &nade.Side},
+		{u.Site, &nade.Site},
+		{u.Callout, &nade.Callout},
+		{u.ThrowZone, &nade.ThrowZone},
+	} {
+		if f.value != nil {
+			*f.field = *f.value
+		}
+	}
+}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	var u Update
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&u); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid update: %v", err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nades, err := Tags.LoadTags(s.cfg.TagsPath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	i := indexOf(nades, r.PathValue("name"))
+	if i < 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no nade named %q", r.PathValue("name")))
+		return
+	}
+
+	nade := nades[i]
+	u.Apply(&nade)
+	if err := Tags.ValidateAnnotationMetadata(nade); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	nades[i] = nade
+	if err := Tags.SaveTags(s.cfg.TagsPath, nades); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log.Printf("[API] Updated %s", nade.NadeName)
+	writeJSON(w, http.StatusOK, nade)
+}
+
+// Skipped is a nade that tag generation left out, and why
+type Skipped struct {
+	NadeName string `json:"nade_name"`
+	Reason   string `json:"reason"`
+}
+
+// GenerateResult lists what tag generation added to tags.json
+type GenerateResult struct {
+	Added   []Tags.AnnotationMetadata `json:"added"`
+	Skipped []Skipped                 `json:"skipped"`
+}
+
+// Generate tags every nade in the annotation folder that isn't in tags.json
+// yet, without prompting. The description, side, site and callout come from
+// the nade's sidecar <NadeName>.json next to its .txt when there is one, and
+// gaps are filled from the map outlines. Nades that don't validate are skipped.
+func Generate(annotationPath string, existing []Tags.AnnotationMetadata) GenerateResult {
+	result := GenerateResult{Added: []Tags.AnnotationMetadata{}, Skipped: []Skipped{}}
+	files, err := Tags.GetFilePaths(annotationPath)
+	if err != nil {
+		result.Skipped = append(result.Skipped, Skipped{Reason: err.Error()})
+		return result
+	}
+	generated, _ := Tags.GenerateMetadata(files)
+	sort.Slice(generated, func(i, j int) bool { return generated[i].NadeName < generated[j].NadeName })
+
+	for _, nade := range generated {
+		if indexOf(existing, nade.NadeName) >= 0 || indexOf(result.Added, nade.NadeName) >= 0 {
+			continue
+		}
+		if err := readSidecar(&nade); err != nil {
+			result.Skipped = append(result.Skipped, Skipped{nade.NadeName, err.Error()})
+			continue
+		}
+		if s, err := Classifier.Classify(nade.FilePath); err == nil {
+			Classifier.Apply(&nade, s, false)
+		}
+		if err := Tags.ValidateAnnotationMetadata(nade); err != nil {
+			result.Skipped = append(result.Skipped, Skipped{nade.NadeName, err.Error()})
+			continue
+		}
+		result.Added = append(result.Added, nade)
+	}
+	return result
+}
+
+// readSidecar copies the hand entered fields from the nade's sidecar file, if it has one
+func readSidecar(nade *Tags.AnnotationMetadata) error {
+	path := filepath.Join(filepath.Dir(nade.FilePath), nade.NadeName+".json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var sidecar Tags.AnnotationMetadata
+	if err := json.Unmarshal(data, &sidecar); err != nil {
+		return fmt.Errorf("could not read %s: %v", path, err)
+	}
+	nade.Description = sidecar.Description
+	nade.Side = sidecar.Side
+	nade.Site = sidecar.Site
+	nade.Callout = sidecar.Callout
+	nade.ThrowZone = sidecar.ThrowZone
+	return nil
+}
+
+// handleGenerate runs Generate and saves the new nades, unless dry_run=true
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nades, err := Tags.LoadTags(s.cfg.TagsPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	result := Generate(s.cfg.AnnotationPath, nades)
+	if r.URL.Query().Get("dry_run") != "true" && len(result.Added) > 0 {
+		if err := Tags.SaveTags(s.cfg.TagsPath, append(nades, result.Added...)); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		log.Printf("[API] Added %d nade(s) to %s", len(result.Added), s.cfg.TagsPath)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// PackRequest names the nades to put in a pack
+type PackRequest struct {
+	Name  string   `json:"name"`
+	Nades []string `json:"nades"`
+	// Merge joins lineups of one grenade type thrown from the same spot into one standing node
+	Merge bool `json:"merge"`
+}
+
+// handlePack sends back the generated annotation file as an attachment
+func (s *Server) handlePack(w http.ResponseWriter, r *http.Request) {
+	var req PackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid pack request: %v", err))
+		return
+	}
+	fileName, err := FileGenerator.NormalizeOutputName(req.Name)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Nades) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("nades must list at least one nade"))
+		return
+	}
+
+	nades, err := Tags.LoadTags(s.cfg.TagsPath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var files []string
+	for _, name := range req.Nades {
+		i := indexOf(nades, name)
+		if i < 0 {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no nade named %q", name))
+			return
+		}
+		files = append(files, nades[i].FilePath)
+	}
+
+	var opts FileGenerator.Options
+	if req.Merge {
+		opts.MergeTolerance = FileGenerator.DefaultMergeTolerance
+	}
+	data, result, err := FileGenerator.Pack(files, opts)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if len(result.Skipped) > 0 {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("could not read %v", result.Skipped))
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.Write(data)
+}
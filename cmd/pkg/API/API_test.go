@@ -0,0 +1,297 @@
+package API
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// smokeFile is an inferno smoke named name. Tag generation reads the map and
+// the grenade type, and packs are checked for the name.
+func smokeFile(name string) string {
+	return fmt.Sprintf(`{
+	MapName = "de_inferno"
+	MapAnnotationNode0 =
+	{
+		SubType = "main"
+		Title =
+		{
+			Text = "%s"
+		}
+		GrenadeType = "smoke"
+	}
+}`, name)
+}
+
+// setup writes an annotation folder with three nades, two of them in tags.json
+func setup(t *testing.T) (*httptest.Server, Config) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := Config{TagsPath: filepath.Join(dir, "tags.json"), AnnotationPath: filepath.Join(dir, "local"), Write: true}
+
+	var nades []Tags.AnnotationMetadata
+	for _, n := range []struct{ name, side, site string }{
+		{"BananaSmoke", "T", "B"},
+		{"ArchSmoke", "CT", "A"},
+		{"NewSmoke", "", ""},
+	} {
+		folder := filepath.Join(cfg.AnnotationPath, n.name)
+		os.MkdirAll(folder, 0755)
+		txt := filepath.Join(folder, n.name+".txt")
+		if err := os.WriteFile(txt, []byte(smokeFile(n.name)), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", txt, err)
+		}
+		if n.side != "" {
+			nades = append(nades, Tags.AnnotationMetadata{
+				FileName: n.name + ".txt", FilePath: txt, NadeName: n.name, Description: "Smokes " + n.site,
+				MapName: "de_inferno", Side: n.side, NadeType: "smoke", Site: n.site,
+			})
+		}
+	}
+	if err := Tags.SaveTags(cfg.TagsPath, nades); err != nil {
+		t.Fatalf("failed to write tags: %v", err)
+	}
+
+	srv := httptest.NewServer(New(cfg))
+	t.Cleanup(srv.Close)
+	return srv, cfg
+}
+
+func do(t *testing.T, method, url, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if method != "GET" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func names(t *testing.T, data []byte) []string {
+	t.Helper()
+	var nades []Tags.AnnotationMetadata
+	if err := json.Unmarshal(data, &nades); err != nil {
+		t.Fatalf("invalid nade list: %v\n%s", err, data)
+	}
+	var list []string
+	for _, n := range nades {
+		list = append(list, n.NadeName)
+	}
+	return list
+}
+
+func TestListAndGet(t *testing.T) {
+	srv, cfg := setup(t)
+
+	for query, want := range map[string]string{
+		"":                           "BananaSmoke,ArchSmoke",
+		"?map=de_inferno&side=CT":    "ArchSmoke",
+		"?site=B":                    "BananaSmoke",
+		"?map=de_nuke":               "",
+		"?map=de_inferno&type=flash": "",
+		"?side=T&side=CT&type=smoke": "BananaSmoke,ArchSmoke",
+	} {
+		resp, data := do(t, "GET", srv.URL+"/api/nades"+query, "")
+		if got := strings.Join(names(t, data), ","); resp.StatusCode != http.StatusOK || got != want {
+			t.Errorf("%q: got %d %q, want %q", query, resp.StatusCode, got, want)
+		}
+	}
+
+	resp, data := do(t, "GET", srv.URL+"/api/nades/ArchSmoke", "")
+	var nade Tags.AnnotationMetadata
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(data, &nade) != nil || nade.Site != "A" {
+		t.Errorf("unexpected nade %d: %s", resp.StatusCode, data)
+	}
+	if resp, _ := do(t, "GET", srv.URL+"/api/nades/Nope", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+
+	resp, data = do(t, "GET", srv.URL+"/api/maps", "")
+	var maps []MapInfo
+	if err := json.Unmarshal(data, &maps); err != nil || len(maps) != 1 || maps[0].DisplayName != "Inferno" || maps[0].Nades != 2 {
+		t.Errorf("unexpected maps %d: %s", resp.StatusCode, data)
+	}
+
+	// A workshop copy of Inferno is a map of its own
+	nades, _ := Tags.LoadTags(cfg.TagsPath)
+	nades = append(nades, Tags.AnnotationMetadata{NadeName: "WorkshopSmoke", MapName: "de_inferno", WorkshopID: "3070284539", Side: "T", NadeType: "smoke"})
+	Tags.SaveTags(cfg.TagsPath, nades)
+	_, data = do(t, "GET", srv.URL+"/api/maps", "")
+	maps = nil
+	if err := json.Unmarshal(data, &maps); err != nil || len(maps) != 2 || maps[0].Nades != 2 || maps[1].WorkshopID != "3070284539" || maps[1].Nades != 1 {
+		t.Errorf("unexpected maps with a workshop copy: %s", data)
+	}
+	for query, want := range map[string]string{
+		"?map=de_inferno&side=T":              "BananaSmoke",
+		"?map=de_inferno&workshop=3070284539": "WorkshopSmoke",
+		"?side=T":                             "BananaSmoke,WorkshopSmoke",
+	} {
+		_, data := do(t, "GET", srv.URL+"/api/nades"+query, "")
+		if got := strings.Join(names(t, data), ","); got != want {
+			t.Errorf("%q: got %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	srv, cfg := setup(t)
+
+	resp, data := do(t, "PATCH", srv.URL+"/api/nades/ArchSmoke", `{"description": "Smokes Arch from CT", "side": "T"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", resp.StatusCode, data)
+	}
+	nades, _ := Tags.LoadTags(cfg.TagsPath)
+	if nades[1].Description != "Smokes Arch from CT" || nades[1].Side != "T" || nades[1].Site != "A" {
+		t.Errorf("update not saved: %+v", nades[1])
+	}
+
+	for body, status := range map[string]int{
+		`{"side": "Both"}`:  http.StatusUnprocessableEntity,
+		`{"site": "C"}`:     http.StatusUnprocessableEntity,
+		`{"map_name": "x"}`: http.StatusBadRequest,
+		`not json`:          http.StatusBadRequest,
+	} {
+		if resp, data := do(t, "PATCH", srv.URL+"/api/nades/ArchSmoke", body); resp.StatusCode != status {
+			t.Errorf("%s: expected %d, got %d: %s", body, status, resp.StatusCode, data)
+		}
+	}
+	if after, _ := Tags.LoadTags(cfg.TagsPath); !reflect.DeepEqual(after[1], nades[1]) {
+		t.Errorf("rejected updates should not be saved: %+v", after[1])
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	srv, cfg := setup(t)
+
+	resp, data := do(t, "POST", srv.URL+"/api/tags/generate?dry_run=true", "")
+	var result GenerateResult
+	if err := json.Unmarshal(data, &result); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected result %d: %s", resp.StatusCode, data)
+	}
+	// NewSmoke has no description until it has a sidecar
+	if len(result.Added) != 0 || len(result.Skipped) != 1 || result.Skipped[0].NadeName != "NewSmoke" {
+		t.Errorf("unexpected dry run: %+v", result)
+	}
+
+	sidecar, _ := json.Marshal(Tags.AnnotationMetadata{NadeName: "NewSmoke", Description: "Smokes Coffins", Side: "T", Site: "B"})
+	os.WriteFile(filepath.Join(cfg.AnnotationPath, "NewSmoke", "NewSmoke.json"), sidecar, 0644)
+
+	resp, data = do(t, "POST", srv.URL+"/api/tags/generate", "")
+	result = GenerateResult{}
+	json.Unmarshal(data, &result)
+	if len(result.Added) != 1 || result.Added[0].Description != "Smokes Coffins" || len(result.Skipped) != 0 {
+		t.Errorf("unexpected result: %s", data)
+	}
+	if nades, _ := Tags.LoadTags(cfg.TagsPath); len(nades) != 3 || nades[2].NadeName != "NewSmoke" || nades[2].Site != "B" {
+		t.Errorf("new nade not saved: %+v", nades)
+	}
+
+	// Running again adds nothing
+	_, data = do(t, "POST", srv.URL+"/api/tags/generate", "")
+	result = GenerateResult{}
+	json.Unmarshal(data, &result)
+	if len(result.Added) != 0 {
+		t.Errorf("expected nothing new, got %+v", result.Added)
+	}
+}
+
+func TestPack(t *testing.T) {
+	srv, _ := setup(t)
+
+	resp, data := do(t, "POST", srv.URL+"/api/packs", `{"name": "Execute", "nades": ["BananaSmoke", "ArchSmoke"]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", resp.StatusCode, data)
+	}
+	if !strings.Contains(resp.Header.Get("Content-Disposition"), "Execute.txt") ||
+		!bytes.Contains(data, []byte("BananaSmoke")) || !bytes.Contains(data, []byte("MapAnnotationNode1")) {
+		t.Errorf("unexpected pack %v:\n%s", resp.Header, data)
+	}
+
+	for body, status := range map[string]int{
+		`{"name": "Execute", "nades": []}`:         http.StatusBadRequest,
+		`{"name": "../x", "nades": ["ArchSmoke"]}`: http.StatusBadRequest,
+		`{"name": "Execute", "nades": ["Nope"]}`:   http.StatusNotFound,
+	} {
+		if resp, data := do(t, "POST", srv.URL+"/api/packs", body); resp.StatusCode != status {
+			t.Errorf("%s: expected %d, got %d: %s", body, status, resp.StatusCode, data)
+		}
+	}
+}
+
+func TestWriteRoutes(t *testing.T) {
+	srv, cfg := setup(t)
+	update := `{"description": "changed"}`
+
+	req, _ := http.NewRequest("PATCH", srv.URL+"/api/nades/ArchSmoke", strings.NewReader(update))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Origin", "https://evil.example")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected cross-origin request to be forbidden, got %v, %v", resp, err)
+	}
+
+	// An HTML form posts text/plain or form encoded bodies
+	req, _ = http.NewRequest("PATCH", srv.URL+"/api/nades/ArchSmoke", strings.NewReader(update))
+	req.Header.Set("Content-Type", "text/plain")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("expected non-JSON request to be refused, got %v, %v", resp, err)
+	}
+
+	req, _ = http.NewRequest("PATCH", srv.URL+"/api/nades/ArchSmoke", strings.NewReader(update))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Origin", srv.URL)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("expected same-origin request to succeed, got %v, %v", resp, err)
+	}
+
+	// Without Write only the GET routes exist
+	cfg.Write = false
+	readOnly := httptest.NewServer(New(cfg))
+	defer readOnly.Close()
+	if resp, _ := do(t, "PATCH", readOnly.URL+"/api/nades/ArchSmoke", update); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 from read-only API, got %d", resp.StatusCode)
+	}
+	if resp, _ := do(t, "POST", readOnly.URL+"/api/tags/generate", ""); resp.StatusCode == http.StatusOK {
+		t.Errorf("expected tag generation to be unavailable on read-only API")
+	}
+	if resp, _ := do(t, "GET", readOnly.URL+"/api/nades/ArchSmoke", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected reads to work on read-only API, got %d", resp.StatusCode)
+	}
+}
+
+// Every route the server handles is in the OpenAPI description
+func TestSpec(t *testing.T) {
+	srv, _ := setup(t)
+
+	resp, data := do(t, "GET", srv.URL+"/api/openapi.json", "")
+	var doc struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil || resp.StatusCode != http.StatusOK || doc.OpenAPI == "" {
+		t.Fatalf("invalid spec %d: %v", resp.StatusCode, err)
+	}
+	for _, route := range []string{
+		"get /api/openapi.json", "get /api/maps", "get /api/nades", "get /api/nades/{name}",
+		"patch /api/nades/{name}", "post /api/tags/generate", "post /api/packs",
+	} {
+		method, path, _ := strings.Cut(route, " ")
+		if _, ok := doc.Paths[path][method]; !ok {
+			t.Errorf("%s is not described", route)
+		}
+	}
+}
@@ -0,0 +1,585 @@
+package Annotation
+
+// Reads and writes the KV3 text files CS2 saves with annotations_save.
+// Only the parts of KV3 that annotation files use are supported:
+// objects, arrays, strings, numbers and booleans.
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Kind is the type of a KV3 value
+type Kind int
+
+const (
+	String Kind = iota
+	Number
+	Bool
+	Array
+	Object
+)
+
+// Value is a single KV3 value. Numbers keep their original text so files
+// are written back exactly as the game wrote them.
+type Value struct {
+	Kind   Kind
+	Text   string // String and Number values
+	Bool   bool
+	Items  []*Value
+	Fields []*Field
+}
+
+// Field is a key = value pair inside an object
+type Field struct {
+	Key   string
+	Value *Value
+}
+
+// File is a parsed annotation file
+type File struct {
+	Header string // the <!-- kv3 ... --> line
+	Root   *Value
+}
+
+// Vec3 is a Position, Angles or TextPositionOffset array
+type Vec3 [3]float64
+
+// Node is one MapAnnotationNode in a file
+type Node struct {
+	Key   string
+	Value *Value
+}
+
+// Load parses the annotation file at path
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	return f, nil
+}
+
+// Save writes the file to path
+func (f *File) Save(path string) error {
+	return os.WriteFile(path, f.Bytes(), 0644)
+}
+
+// ---- Parsing ----
+
+type parser struct {
+	data []byte
+	pos  int
+	line int
+}
+
+// Parse reads KV3 text
+func Parse(data []byte) (*File, error) {
+	p := &parser{data: data, line: 1}
+	f := &File{}
+
+	p.skipSpace()
+	if strings.HasPrefix(p.rest(), "<!--") {
+		end := strings.Index(p.rest(), "-->")
+		if end < 0 {
+			return nil, p.errorf("unterminated header comment")
+		}
+		f.Header = p.rest()[:end+3]
+		p.advance(end + 3)
+	}
+
+	root, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if root.Kind != Object {
+		return nil, p.errorf("file must contain an object")
+	}
+	f.Root = root
+
+	p.skipSpace()
+	if p.pos < len(p.data) {
+		return nil, p.errorf("unexpected text after closing brace")
+	}
+	return f, nil
+}
+
+func (p *parser) rest() string {
+	return string(p.data[p.pos:])
+}
+
+func (p *parser) advance(n int) {
+	for i := 0; i < n && p.pos < len(p.data); i++ {
+		if p.data[p.pos] == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			p.advance(1)
+			continue
+		}
+		// Line comments
+		if c == '/' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '/' {
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				p.advance(1)
+			}
+			continue
+		}
+		return
+	}
+}
+
+func (p *parser) parseValue() (*Value, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of file")
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.parseObject()
+	case c == '[':
+		return p.parseArray()
+	case c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &Value{Kind: String, Text: s}, nil
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.data) && strings.IndexByte("+-.0123456789eE", p.data[p.pos]) >= 0 {
+			p.advance(1)
+		}
+		text := string(p.data[start:p.pos])
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return nil, p.errorf("invalid number %q", text)
+		}
+		return &Value{Kind: Number, Text: text}, nil
+	default:
+		word := p.parseIdent()
+		switch word {
+		case "true":
+			return &Value{Kind: Bool, Bool: true}, nil
+		case "false":
+			return &Value{Kind: Bool, Bool: false}, nil
+		}
+		return nil, p.errorf("unexpected value %q", word)
+	}
+}
+
+func (p *parser) parseIdent() string {
+	start := p.pos
+	for p.pos < len(p.data) {
+		r := rune(p.data[p.pos])
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.') {
+			break
+		}
+		p.advance(1)
+	}
+	return string(p.data[start:p.pos])
+}
+
+func (p *parser) parseString() (string, error) {
+	// Skip opening quote
+	p.advance(1)
+	var sb strings.Builder
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch c {
+		case '"':
+			p.advance(1)
+			return sb.String(), nil
+		case '\\':
+			if p.pos+1 >= len(p.data) {
+				return "", p.errorf("unterminated string")
+			}
+			next := p.data[p.pos+1]
+			switch next {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(next)
+			}
+			p.advance(2)
+		default:
+			sb.WriteByte(c)
+			p.advance(1)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) parseObject() (*Value, error) {
+	// Skip opening brace
+	p.advance(1)
+	obj := &Value{Kind: Object}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unterminated object")
+		}
+		if p.data[p.pos] == '}' {
+			p.advance(1)
+			return obj, nil
+		}
+
+		key := p.parseIdent()
+		if key == "" {
+			return nil, p.errorf("expected key, found %q", string(p.data[p.pos]))
+		}
+		p.skipSpace()
+		if p.pos >= len(p.data) || p.data[p.pos] != '=' {
+			return nil, p.errorf("expected '=' after %s", key)
+		}
+		p.advance(1)
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		obj.Fields = append(obj.Fields, &Field{Key: key, Value: value})
+	}
+}
+
+func (p *parser) parseArray() (*Value, error) {
+	// Skip opening bracket
+	p.advance(1)
+	arr := &Value{Kind: Array}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.data[p.pos] == ']' {
+			p.advance(1)
+			return arr, nil
+		}
+		if p.data[p.pos] == ',' {
+			p.advance(1)
+			continue
+		}
+
+		item, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		arr.Items = append(arr.Items, item)
+	}
+}
+
+// ---- Writing ----
+
+// Bytes returns the file in the same layout the game writes
+func (f *File) Bytes() []byte {
+	var sb strings.Builder
+	if f.Header != "" {
+		sb.WriteString(f.Header)
+		sb.WriteString("\n")
+	}
+	writeValue(&sb, f.Root, 0)
+	return []byte(sb.String())
+}
+
+func writeValue(sb *strings.Builder, v *Value, depth int) {
+	switch v.Kind {
+	case String:
+		sb.WriteString(Quote(v.Text))
+	case Number:
+		sb.WriteString(v.Text)
+	case Bool:
+		sb.WriteString(strconv.FormatBool(v.Bool))
+	case Array:
+		sb.WriteString("[ ")
+		for i, item := range v.Items {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeValue(sb, item, depth)
+		}
+		sb.WriteString(" ]")
+	case Object:
+		indent := strings.Repeat("\t", depth)
+		sb.WriteString("{\n")
+		for _, field := range v.Fields {
+			sb.WriteString(indent + "\t" + field.Key + " = ")
+			if field.Value.Kind == Object {
+				// Objects start on their own line
+				sb.WriteString("\n" + indent + "\t")
+			}
+			writeValue(sb, field.Value, depth+1)
+			sb.WriteString("\n")
+		}
+		sb.WriteString(indent + "}")
+	}
+}
+
+// Quote returns s as a KV3 string literal
+func Quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// ---- Values ----
+
+// NewString returns a string value
+func NewString(s string) *Value {
+	return &Value{Kind: String, Text: s}
+}
+
+// NewNumber returns a number value, always written with a decimal point like the game does
+func NewNumber(n float64) *Value {
+	text := strconv.FormatFloat(n, 'f', -1, 64)
+	if !strings.Contains(text, ".") {
+		text += ".0"
+	}
+	return &Value{Kind: Number, Text: text}
+}
+
+// NewInt returns a whole number value such as FontSize
+func NewInt(n int) *Value {
+	return &Value{Kind: Number, Text: strconv.Itoa(n)}
+}
+
+// NewBool returns a boolean value
+func NewBool(b bool) *Value {
+	return &Value{Kind: Bool, Bool: b}
+}
+
+// NewVec3 returns a three number array
+func NewVec3(v Vec3) *Value {
+	return &Value{Kind: Array, Items: []*Value{NewNumber(v[0]), NewNumber(v[1]), NewNumber(v[2])}}
+}
+
+// NewObject returns an empty object
+func NewObject() *Value {
+	return &Value{Kind: Object}
+}
+
+// Get returns the value of key in an object, or nil
+func (v *Value) Get(key string) *Value {
+	if v == nil || v.Kind != Object {
+		return nil
+	}
+	for _, f := range v.Fields {
+		if f.Key == key {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+// Set replaces the value of key, adding it at the end if missing
+func (v *Value) Set(key string, value *Value) {
+	for _, f := range v.Fields {
+		if f.Key == key {
+			f.Value = value
+			return
+		}
+	}
+	v.Fields = append(v.Fields, &Field{Key: key, Value: value})
+}
+
+// Delete removes key from an object
+func (v *Value) Delete(key string) {
+	for i, f := range v.Fields {
+		if f.Key == key {
+			v.Fields = append(v.Fields[:i], v.Fields[i+1:]...)
+			return
+		}
+	}
+}
+
+// String returns the text of a string value, or "" for anything else
+func (v *Value) String() string {
+	if v == nil || v.Kind != String {
+		return ""
+	}
+	return v.Text
+}
+
+// Float returns the number of a number value, or 0
+func (v *Value) Float() float64 {
+	if v == nil || v.Kind != Number {
+		return 0
+	}
+	n, _ := strconv.ParseFloat(v.Text, 64)
+	return n
+}
+
+// Vec3 returns a three number array, or false if v isn't one
+func (v *Value) Vec3() (Vec3, bool) {
+	var out Vec3
+	if v == nil || v.Kind != Array || len(v.Items) != 3 {
+		return out, false
+	}
+	for i, item := range v.Items {
+		if item.Kind != Number {
+			return out, false
+		}
+		out[i] = item.Float()
+	}
+	return out, true
+}
+
+// Copy returns a deep copy of v
+func (v *Value) Copy() *Value {
+	if v == nil {
+		return nil
+	}
+	c := &Value{Kind: v.Kind, Text: v.Text, Bool: v.Bool}
+	for _, item := range v.Items {
+		c.Items = append(c.Items, item.Copy())
+	}
+	for _, f := range v.Fields {
+		c.Fields = append(c.Fields, &Field{Key: f.Key, Value: f.Value.Copy()})
+	}
+	return c
+}
+
+// ---- Annotation helpers ----
+
+var nodeKeyRegex = regexp.MustCompile(`^MapAnnotationNode\d+$`)
+
+// MapName returns the top level MapName
+func (f *File) MapName() string {
+	return f.Root.Get("MapName").String()
+}
+
+// WorkshopID returns the top level WorkshopSubmissionID
+func (f *File) WorkshopID() string {
+	return f.Root.Get("WorkshopSubmissionID").String()
+}
+
+// Nodes returns the MapAnnotationNode entries in file order
+func (f *File) Nodes() []Node {
+	var nodes []Node
+	for _, field := range f.Root.Fields {
+		if nodeKeyRegex.MatchString(field.Key) && field.Value.Kind == Object {
+			nodes = append(nodes, Node{Key: field.Key, Value: field.Value})
+		}
+	}
+	return nodes
+}
+
+// SetNodes replaces all MapAnnotationNode entries, numbering them from 0
+func (f *File) SetNodes(nodes []Node) {
+	var fields []*Field
+	for _, field := range f.Root.Fields {
+		if !nodeKeyRegex.MatchString(field.Key) {
+			fields = append(fields, field)
+		}
+	}
+	for i, n := range nodes {
+		fields = append(fields, &Field{Key: "MapAnnotationNode" + strconv.Itoa(i), Value: n.Value})
+	}
+	f.Root.Fields = fields
+}
+
+// Str returns a string field of the node
+func (n Node) Str(key string) string {
+	return n.Value.Get(key).String()
+}
+
+// Id returns the node Id
+func (n Node) Id() string { return n.Str("Id") }
+
+// SubType returns main, aim_target or destination
+func (n Node) SubType() string { return n.Str("SubType") }
+
+// MasterNodeId returns the Id of the main node this node belongs to
+func (n Node) MasterNodeId() string { return n.Str("MasterNodeId") }
+
+// GrenadeType returns the grenade type of a main node
+func (n Node) GrenadeType() string { return n.Str("GrenadeType") }
+
+// LineupId returns the Id of the main node of the lineup this node is part of
+func (n Node) LineupId() string {
+	if n.SubType() == "main" || n.MasterNodeId() == "" {
+		return n.Id()
+	}
+	return n.MasterNodeId()
+}
+
+// Vec returns a vector field of the node
+func (n Node) Vec(key string) Vec3 {
+	v, _ := n.Value.Get(key).Vec3()
+	return v
+}
+
+// Position returns the node Position
+func (n Node) Position() Vec3 { return n.Vec("Position") }
+
+// Angles returns the node Angles
+func (n Node) Angles() Vec3 { return n.Vec("Angles") }
+
+// TextPositionOffset returns where the text is drawn relative to Position
+func (n Node) TextPositionOffset() Vec3 { return n.Vec("TextPositionOffset") }
+
+// TitleText returns the Title.Text of the node
+func (n Node) TitleText() string {
+	return n.Value.Get("Title").Get("Text").String()
+}
+
+// DescText returns the Desc.Text of the node
+func (n Node) DescText() string {
+	return n.Value.Get("Desc").Get("Text").String()
+}
+
+// SetTitleText replaces Title.Text
+func (n Node) SetTitleText(text string) error {
+	return n.setText("Title", text)
+}
+
+// SetDescText replaces Desc.Text
+func (n Node) SetDescText(text string) error {
+	return n.setText("Desc", text)
+}
+
+func (n Node) setText(block, text string) error {
+	b := n.Value.Get(block)
+	if b == nil || b.Kind != Object {
+		return errors.New(n.Key + " has no " + block + " block")
+	}
+	b.Set("Text", NewString(text))
+	return nil
+}
+
+// Add returns the sum of two vectors
+func (v Vec3) Add(o Vec3) Vec3 {
+	return Vec3{v[0] + o[0], v[1] + o[1], v[2] + o[2]}
+}
+
+// Sub returns v - o
+func (v Vec3) Sub(o Vec3) Vec3 {
+	return Vec3{v[0] - o[0], v[1] - o[1], v[2] - o[2]}
+}
+
+// Length returns the length of the vector
+func (v Vec3) Length() float64 {
+	return math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+}
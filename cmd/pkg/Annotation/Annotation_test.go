@@ -0,0 +1,136 @@
+package Annotation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A trimmed down file in the layout annotations_save writes
+const sampleFile = `<!-- kv3 encoding:text:version{e21c7f3c-8a33-41c5-9977-a76d3a32aa0d} format:generic:version{7412167c-06e9-4698-aff2-e63eb59037e7} -->
+{
+	MapName = "de_inferno"
+	WorkshopSubmissionID = ""
+	ScreenText = 
+	{
+	}
+	MapAnnotationNode0 = 
+	{
+		Enabled = true
+		Type = "grenade"
+		Id = "a9976c25-b38d-41b7-bf92-51f3abb33b6d"
+		SubType = "main"
+		Position = [ 791.982727, 2228.994873, 136.031265 ]
+		TextPositionOffset = [ 0.0, 0.0, 60.0 ]
+		Title = 
+		{
+			Text = "BackLogsFire"
+			FontSize = 125
+		}
+		Desc = 
+		{
+			Text = "stand \"wherever\""
+			FontSize = 75
+		}
+		GrenadeType = "incendiary"
+	}
+	MapAnnotationNode1 = 
+	{
+		Enabled = true
+		Id = "fb6569ed-76e2-4adf-8836-a7ecabe74e21"
+		SubType = "aim_target"
+		Position = [ 726.054016, 2154.579834, 210.792252 ]
+		Title = 
+		{
+			Text = "BackLogsFire"
+		}
+		MasterNodeId = "a9976c25-b38d-41b7-bf92-51f3abb33b6d"
+	}
+}`
+
+func TestParseRoundTrip(t *testing.T) {
+	f, err := Parse([]byte(sampleFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := string(f.Bytes()); got != sampleFile {
+		t.Errorf("round trip changed the file:\nGot:\n%s\n\nExpected:\n%s", got, sampleFile)
+	}
+
+	if f.MapName() != "de_inferno" || f.WorkshopID() != "" {
+		t.Errorf("unexpected map: %s %s", f.MapName(), f.WorkshopID())
+	}
+
+	nodes := f.Nodes()
+	if len(nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(nodes))
+	}
+	main, aim := nodes[0], nodes[1]
+	if main.SubType() != "main" || main.GrenadeType() != "incendiary" || main.TitleText() != "BackLogsFire" {
+		t.Errorf("unexpected main node: %s %s %s", main.SubType(), main.GrenadeType(), main.TitleText())
+	}
+	if main.DescText() != `stand "wherever"` {
+		t.Errorf("unexpected description: %s", main.DescText())
+	}
+	if main.Position() != (Vec3{791.982727, 2228.994873, 136.031265}) {
+		t.Errorf("unexpected position: %v", main.Position())
+	}
+	if aim.LineupId() != main.Id() || main.LineupId() != main.Id() {
+		t.Errorf("aim_target should belong to the main node lineup")
+	}
+}
+
+func TestEditAndSave(t *testing.T) {
+	f, err := Parse([]byte(sampleFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	nodes := f.Nodes()
+	if err := nodes[0].SetTitleText("Back Logs Molly"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := nodes[1].SetDescText("x"); err == nil {
+		t.Errorf("expected error setting text of a missing Desc block")
+	}
+	nodes[0].Value.Set("Color", NewVec3(Vec3{255, 0, 0}))
+
+	// Dropping the first node renumbers the rest from 0
+	f.SetNodes(nodes[1:])
+
+	path := filepath.Join(t.TempDir(), "out.txt")
+	if err := f.Save(path); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+	saved, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading: %v", err)
+	}
+	savedNodes := saved.Nodes()
+	if len(savedNodes) != 1 || savedNodes[0].Key != "MapAnnotationNode0" || savedNodes[0].SubType() != "aim_target" {
+		t.Errorf("unexpected nodes after save: %+v", savedNodes)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "Back Logs Molly") {
+		t.Errorf("removed node should not be written")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	bad := []string{
+		"",
+		"<!-- no end",
+		"{ MapName = \"de_inferno\"",
+		"{ MapName \"de_inferno\" }",
+		"{ Position = [ 1.0, abc ] }",
+		"{ Text = \"unterminated }",
+		"{ } extra",
+	}
+	for _, b := range bad {
+		if _, err := Parse([]byte(b)); err == nil {
+			t.Errorf("expected error parsing %q", b)
+		}
+	}
+}
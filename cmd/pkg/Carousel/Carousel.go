@@ -0,0 +1,192 @@
+package Carousel
+
+// Shows the screenshots of a nade one at a time with their labels, and
+// buttons to step through them.
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Item is one image and what it shows
+type Item struct {
+	Path  string
+	Label string
+}
+
+// Loader loads images in the background, such as a thumbnail cache.
+// done may be called from another goroutine.
+type Loader interface {
+	Load(path string, done func(image.Image, error))
+}
+
+// DefaultLoader is used by new carousels. When nil images are read from
+// their file as they are shown.
+var DefaultLoader Loader
+
+// Carousel is a widget showing one Item at a time
+type Carousel struct {
+	widget.BaseWidget
+
+	// OnChanged is called with the index of the image now shown
+	OnChanged func(i int)
+	// Loader loads the images, see DefaultLoader
+	Loader Loader
+
+	items   []Item
+	index   int
+	image   *canvas.Image
+	caption *widget.Label
+	prev    *widget.Button
+	next    *widget.Button
+
+	mu      sync.Mutex
+	current string // path of the image shown, so loads that finish late are dropped
+}
+
+// New creates an empty carousel whose image is at least minSize
+func New(minSize fyne.Size) *Carousel {
+	c := &Carousel{image: canvas.NewImageFromFile(""), caption: widget.NewLabel(""), Loader: DefaultLoader}
+	c.image.FillMode = canvas.ImageFillContain
+	c.image.SetMinSize(minSize)
+	c.caption.Alignment = fyne.TextAlignCenter
+	c.prev = widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() { c.ShowIndex(c.index - 1) })
+	c.next = widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() { c.ShowIndex(c.index + 1) })
+	c.ExtendBaseWidget(c)
+	c.update()
+	return c
+}
+
+// SetItems replaces the images and shows the first one
+func (c *Carousel) SetItems(items []Item) {
+	c.items = append([]Item(nil), items...)
+	c.index = 0
+	c.update()
+	if c.OnChanged != nil && len(c.items) > 0 {
+		c.OnChanged(0)
+	}
+}
+
+// Items returns the images, with any labels changed by SetLabel
+func (c *Carousel) Items() []Item {
+	return append([]Item(nil), c.items...)
+}
+
+// Index is the index of the image shown, or -1 when there are none
+func (c *Carousel) Index() int {
+	if len(c.items) == 0 {
+		return -1
+	}
+	return c.index
+}
+
+// ShowIndex shows the image at index i. Out of range indexes are ignored.
+func (c *Carousel) ShowIndex(i int) {
+	if i < 0 || i >= len(c.items) || i == c.index {
+		return
+	}
+	c.index = i
+	c.update()
+	if c.OnChanged != nil {
+		c.OnChanged(i)
+	}
+}
+
+// SetLabel changes the label of the image shown
+func (c *Carousel) SetLabel(label string) {
+	if len(c.items) == 0 {
+		return
+	}
+	c.items[c.index].Label = label
+	c.update()
+}
+
+// Caption is the text under the image, such as "Landing (3 / 3)"
+func (c *Carousel) Caption() string {
+	return c.caption.Text
+}
+
+func (c *Carousel) update() {
+	if len(c.items) == 0 {
+		c.mu.Lock()
+		c.current = ""
+		c.image.File = ""
+		c.image.Image = nil
+		c.mu.Unlock()
+		c.caption.SetText("No images")
+		c.prev.Disable()
+		c.next.Disable()
+		c.image.Refresh()
+		return
+	}
+
+	item := c.items[c.index]
+	c.load(item.Path)
+	caption := fmt.Sprintf("%d / %d", c.index+1, len(c.items))
+	if item.Label != "" {
+		caption = fmt.Sprintf("%s (%s)", item.Label, caption)
+	}
+	c.caption.SetText(caption)
+
+	if c.index > 0 {
+		c.prev.Enable()
+	} else {
+		c.prev.Disable()
+	}
+	if c.index < len(c.items)-1 {
+		c.next.Enable()
+	} else {
+		c.next.Disable()
+	}
+}
+
+// load shows the image at path, through the Loader when there is one
+func (c *Carousel) load(path string) {
+	c.mu.Lock()
+	if c.current == path {
+		c.mu.Unlock()
+		return
+	}
+	c.current = path
+	if c.Loader == nil {
+		c.image.Image = nil
+		c.image.File = path
+		c.mu.Unlock()
+		c.image.Refresh()
+		return
+	}
+	// Blank until the image arrives, rather than showing the last nade's
+	c.image.File = ""
+	c.image.Image = nil
+	c.mu.Unlock()
+	c.image.Refresh()
+
+	c.Loader.Load(path, func(img image.Image, err error) {
+		if err != nil {
+			log.Printf("[Carousel] Error loading %s: %v", path, err)
+			return
+		}
+		c.mu.Lock()
+		if c.current != path {
+			c.mu.Unlock()
+			return
+		}
+		c.image.Image = img
+		c.mu.Unlock()
+		c.image.Refresh()
+	})
+}
+
+// CreateRenderer puts the buttons and caption under the image
+func (c *Carousel) CreateRenderer() fyne.WidgetRenderer {
+	bar := container.NewBorder(nil, nil, c.prev, c.next, c.caption)
+	return widget.NewSimpleRenderer(container.NewBorder(nil, bar, nil, nil, c.image))
+}
@@ -0,0 +1,81 @@
+package Carousel
+
+import (
+	"image"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+)
+
+func TestCarousel(t *testing.T) {
+	test.NewApp()
+	c := New(fyne.NewSize(100, 100))
+	if c.Index() != -1 || c.Caption() != "No images" || !c.prev.Disabled() || !c.next.Disabled() {
+		t.Errorf("unexpected empty carousel: %d %q", c.Index(), c.Caption())
+	}
+
+	var shown []int
+	c.OnChanged = func(i int) { shown = append(shown, i) }
+	c.SetItems([]Item{{"stand.png", "Standing position"}, {"aim.png", ""}, {"land.png", "Landing"}})
+	if c.Caption() != "Standing position (1 / 3)" || !c.prev.Disabled() || c.next.Disabled() || c.image.File != "stand.png" {
+		t.Errorf("unexpected first image: %q", c.Caption())
+	}
+
+	test.Tap(c.next)
+	if c.Index() != 1 || c.Caption() != "2 / 3" || c.prev.Disabled() {
+		t.Errorf("unexpected second image: %d %q", c.Index(), c.Caption())
+	}
+	c.SetLabel("Crosshair placement")
+	if c.Caption() != "Crosshair placement (2 / 3)" || c.Items()[1].Label != "Crosshair placement" {
+		t.Errorf("label not changed: %q", c.Caption())
+	}
+
+	c.ShowIndex(2)
+	c.ShowIndex(5)
+	if c.Index() != 2 || !c.next.Disabled() || c.image.File != "land.png" {
+		t.Errorf("unexpected last image: %d", c.Index())
+	}
+	if len(shown) != 3 || shown[0] != 0 || shown[1] != 1 || shown[2] != 2 {
+		t.Errorf("unexpected OnChanged calls: %v", shown)
+	}
+}
+
+// fakeLoader holds loads until they are finished by the test
+type fakeLoader struct {
+	paths []string
+	done  []func(image.Image, error)
+}
+
+func (l *fakeLoader) Load(path string, done func(image.Image, error)) {
+	l.paths = append(l.paths, path)
+	l.done = append(l.done, done)
+}
+
+func TestCarouselLoader(t *testing.T) {
+	test.NewApp()
+	l := &fakeLoader{}
+	c := New(fyne.NewSize(100, 100))
+	c.Loader = l
+	c.SetItems([]Item{{Path: "stand.png"}, {Path: "land.png"}})
+	c.SetLabel("Standing position")
+	c.ShowIndex(1)
+	if len(l.paths) != 2 || l.paths[0] != "stand.png" || l.paths[1] != "land.png" {
+		t.Fatalf("unexpected loads: %v", l.paths)
+	}
+	if c.image.Image != nil || c.image.File != "" {
+		t.Errorf("image shown before it loaded")
+	}
+
+	// The first image finishing late must not replace the second
+	first := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	second := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	l.done[0](first, nil)
+	if c.image.Image != nil {
+		t.Errorf("late load was shown")
+	}
+	l.done[1](second, nil)
+	if c.image.Image != second {
+		t.Errorf("loaded image not shown")
+	}
+}
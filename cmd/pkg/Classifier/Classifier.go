@@ -0,0 +1,102 @@
+package Classifier
+
+// Suggests a nade's side, site and callout from where it is thrown and where it lands,
+// using the outlines in the map registry.
+
+import (
+	"fmt"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// Suggestion for one nade. Fields are empty when the map has no outline there.
+type Suggestion struct {
+	Map       string // map the file is for
+	Site      string // site of the destination
+	Callout   string // callout of the destination
+	ThrowZone string // callout, or site or zone, of the main node
+	Side      string // from the throw zone, e.g. T when thrown from T Spawn
+}
+
+// Classify reads the main and destination nodes of a single nade file
+func Classify(txtPath string) (Suggestion, error) {
+	f, err := Annotation.Load(txtPath)
+	if err != nil {
+		return Suggestion{}, err
+	}
+
+	var mains []Annotation.Node
+	for _, n := range f.Nodes() {
+		if n.SubType() == "main" {
+			mains = append(mains, n)
+		}
+	}
+	if len(mains) != 1 {
+		return Suggestion{}, fmt.Errorf("%s has %d main nodes, expected 1", txtPath, len(mains))
+	}
+	main := mains[0]
+
+	s := Suggestion{Map: f.MapName()}
+	pos := main.Position()
+	throw := Maps.Locate(f.MapName(), pos[0], pos[1])
+	s.ThrowZone = throw.Callout
+	if s.ThrowZone == "" {
+		s.ThrowZone = throw.Area
+	}
+	s.Side = throw.Side
+
+	for _, n := range f.Nodes() {
+		if n.SubType() == "destination" && n.MasterNodeId() == main.Id() {
+			pos := n.Position()
+			land := Maps.Locate(f.MapName(), pos[0], pos[1])
+			if land.InSite {
+				s.Site = land.Area
+			}
+			s.Callout = land.Callout
+			break
+		}
+	}
+	return s, nil
+}
+
+// Apply fills the empty side, site, callout and throw zone of a nade, or all of
+// them when overwrite is set. The callout is only set when it is in the nade's
+// site. It reports whether anything changed.
+func Apply(nade *Tags.AnnotationMetadata, s Suggestion, overwrite bool) bool {
+	changed := false
+	set := func(field *string, value string) {
+		if value == "" || *field == value || (*field != "" && !overwrite) {
+			return
+		}
+		*field = value
+		changed = true
+	}
+	set(&nade.Side, s.Side)
+	set(&nade.Site, s.Site)
+	// Keep the callout within the site, which may have been picked by hand
+	if nade.Site == "" || nade.Site == Maps.SiteOf(s.Map, s.Callout) {
+		set(&nade.Callout, s.Callout)
+	}
+	set(&nade.ThrowZone, s.ThrowZone)
+	return changed
+}
+
+// Backfill classifies every nade and returns the updated list, the names of the
+// nades that changed and a warning for each file that couldn't be classified
+func Backfill(nades []Tags.AnnotationMetadata, overwrite bool) ([]Tags.AnnotationMetadata, []string, []string) {
+	updated := append([]Tags.AnnotationMetadata(nil), nades...)
+	var changed, warnings []string
+	for i := range updated {
+		s, err := Classify(updated[i].FilePath)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipped %s: %v", updated[i].NadeName, err))
+			continue
+		}
+		if Apply(&updated[i], s, overwrite) {
+			changed = append(changed, updated[i].NadeName)
+		}
+	}
+	return updated, changed, warnings
+}
@@ -0,0 +1,131 @@
+package Classifier
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// lineup writes a single inferno nade thrown from x, y and landing at dx, dy,
+// which is all Classify looks at, and returns its path
+func lineup(t *testing.T, x, y, dx, dy float64) string {
+	t.Helper()
+	content := fmt.Sprintf(`{
+	MapName = "de_inferno"
+	MapAnnotationNode0 =
+	{
+		Id = "main-1"
+		SubType = "main"
+		Position = [ %v, %v, 80.0 ]
+		GrenadeType = "molotov"
+	}
+	MapAnnotationNode1 =
+	{
+		Id = "dest-1"
+		SubType = "destination"
+		MasterNodeId = "main-1"
+		Position = [ %v, %v, 138.0 ]
+	}
+}`, x, y, dx, dy)
+	path := filepath.Join(t.TempDir(), "lineup.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestClassify(t *testing.T) {
+	// Thrown from T Spawn into B car
+	s, err := Classify(lineup(t, -1363, 141, 472, 1996))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s != (Suggestion{Map: "de_inferno", Site: "B", Callout: "B Car", ThrowZone: "T Spawn", Side: "T"}) {
+		t.Errorf("unexpected suggestion: %+v", s)
+	}
+
+	// Landing in a zone isn't a site
+	s, _ = Classify(lineup(t, 176, 1026, -1363, 141))
+	if s != (Suggestion{Map: "de_inferno", Callout: "T Spawn", ThrowZone: "Banana"}) {
+		t.Errorf("unexpected suggestion: %+v", s)
+	}
+
+	empty := filepath.Join(t.TempDir(), "Empty.txt")
+	os.WriteFile(empty, []byte(`{ MapName = "de_inferno" }`), 0644)
+	if _, err := Classify(empty); err == nil {
+		t.Errorf("expected error for a file with no main node")
+	}
+}
+
+// The Inferno lineups in local/, against the sites they were tagged with by
+// hand and the callouts their names give
+func TestClassifyRepoNades(t *testing.T) {
+	tests := []struct {
+		name, site, callout, side string
+	}{
+		{"CarMolly", "B", "B Car", ""},
+		{"Car2NewBox", "B", "New Box", ""},
+		{"Boost2Dark", "B", "Dark", ""},
+		{"Corner2Coffins", "B", "Coffins", ""},
+		{"CoffinSelfFlash", "B", "Coffins", ""},
+		{"LeftCubbyFire", "B", "Banana", ""},
+		{"Spawn2HalfWall", "B", "Banana", "T"},
+		{"BracketsAwpFlash", "A", "Brackets", ""},
+		{"DeepBracketRifleFlash", "A", "Brackets", ""},
+		// Pit and Balcony have no outline yet, so only the site is found
+		{"FireExitFlash", "A", "", ""},
+		{"Balc+miniPit", "A", "", ""},
+	}
+	for _, tt := range tests {
+		s, err := Classify(filepath.Join("..", "..", "..", "local", tt.name, tt.name+".txt"))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if s.Site != tt.site || s.Callout != tt.callout || s.Side != tt.side {
+			t.Errorf("%s: got site %q, callout %q, side %q, want %q, %q, %q", tt.name, s.Site, s.Callout, s.Side, tt.site, tt.callout, tt.side)
+		}
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	dir := t.TempDir()
+	path := lineup(t, -1363, 141, 472, 1996)
+	nades := []Tags.AnnotationMetadata{
+		{NadeName: "SpawnCar", FilePath: path},
+		{NadeName: "Picked", FilePath: path, Side: "CT", Site: "A"},
+		{NadeName: "SameSite", FilePath: path, Site: "B"},
+		{NadeName: "Missing", FilePath: filepath.Join(dir, "missing.txt")},
+	}
+
+	updated, changed, warnings := Backfill(nades, false)
+	if len(changed) != 3 || len(warnings) != 1 {
+		t.Errorf("unexpected backfill: %v %v", changed, warnings)
+	}
+	if n := updated[0]; n.Side != "T" || n.Site != "B" || n.Callout != "B Car" || n.ThrowZone != "T Spawn" {
+		t.Errorf("empty fields not filled: %+v", n)
+	}
+	// B Car is in B, so it doesn't fit the A picked by hand
+	if n := updated[1]; n.Side != "CT" || n.Site != "A" || n.Callout != "" {
+		t.Errorf("picked fields should be kept: %+v", n)
+	}
+	if n := updated[2]; n.Site != "B" || n.Callout != "B Car" {
+		t.Errorf("callout in the picked site should be filled: %+v", n)
+	}
+	if nades[0].Site != "" {
+		t.Errorf("input slice should not change")
+	}
+
+	updated, _, _ = Backfill(nades, true)
+	if n := updated[1]; n.Side != "T" || n.Site != "B" || n.Callout != "B Car" {
+		t.Errorf("overwrite should replace picked fields: %+v", n)
+	}
+
+	// Nothing left to fill afterwards
+	if _, changed, _ := Backfill(updated[:1], false); len(changed) != 0 {
+		t.Errorf("expected no changes, got %v", changed)
+	}
+}
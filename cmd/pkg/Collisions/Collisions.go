@@ -0,0 +1,178 @@
+package Collisions
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+)
+
+// DefaultDistance is how close (in game units) two labels can be before they overlap
+const DefaultDistance = 40.0
+
+// Label is the text of a main or aim_target node where it is drawn in game
+type Label struct {
+	File     string
+	NadeName string
+	LineupId string
+	SubType  string
+	MapName  string
+	Position Annotation.Vec3 // Position + TextPositionOffset
+	Offset   Annotation.Vec3 // TextPositionOffset
+}
+
+// Collision is a pair of labels from different lineups that are too close
+type Collision struct {
+	A        Label
+	B        Label
+	Distance float64
+	// SuggestedOffset is a TextPositionOffset for B that moves it clear of A
+	SuggestedOffset Annotation.Vec3
+}
+
+// Report is the result of checking a set of annotation files
+type Report struct {
+	Collisions []Collision
+	Skipped    []string // files that could not be read or parsed
+}
+
+// LabelsFromFile returns the main and aim_target labels in an annotation file
+func LabelsFromFile(path string) ([]Label, error) {
+	f, err := Annotation.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := f.Nodes()
+	// Name each lineup after the title of its main node
+	names := make(map[string]string)
+	for _, n := range nodes {
+		if n.SubType() == "main" {
+			names[n.Id()] = n.TitleText()
+		}
+	}
+
+	var labels []Label
+	for _, n := range nodes {
+		if n.SubType() != "main" && n.SubType() != "aim_target" {
+			continue
+		}
+		name := names[n.LineupId()]
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		labels = append(labels, Label{
+			File:     path,
+			NadeName: name,
+			LineupId: n.LineupId(),
+			SubType:  n.SubType(),
+			MapName:  f.MapName(),
+			Position: n.Position().Add(n.TextPositionOffset()),
+			Offset:   n.TextPositionOffset(),
+		})
+	}
+	return labels, nil
+}
+
+// Analyze compares every pair of labels on the same map and reports the ones
+// closer than distance. Labels of the same lineup are never compared.
+func Analyze(labels []Label, distance float64) []Collision {
+	var collisions []Collision
+	for i := 0; i < len(labels); i++ {
+		for j := i + 1; j < len(labels); j++ {
+			a, b := labels[i], labels[j]
+			if a.MapName != b.MapName || a.LineupId == b.LineupId {
+				continue
+			}
+			d := b.Position.Sub(a.Position).Length()
+			if d >= distance {
+				continue
+			}
+			collisions = append(collisions, Collision{
+				A:               a,
+				B:               b,
+				Distance:        d,
+				SuggestedOffset: suggestOffset(a, b, distance),
+			})
+		}
+	}
+
+	sort.SliceStable(collisions, func(i, j int) bool {
+		return collisions[i].Distance < collisions[j].Distance
+	})
+	return collisions
+}
+
+// suggestOffset stacks B's label above A's so they are distance apart vertically
+func suggestOffset(a, b Label, distance float64) Annotation.Vec3 {
+	offset := b.Offset
+	raise := a.Position[2] - b.Position[2] + distance
+	// Round up to a tidy number, the game files use whole numbers for offsets
+	offset[2] = math.Ceil((offset[2]+raise)/5) * 5
+	return offset
+}
+
+// AnalyzeFiles checks a set of annotation files, such as a pack before it is generated
+func AnalyzeFiles(paths []string, distance float64) Report {
+	var report Report
+	var labels []Label
+	// Generated packs repeat the nodes of the single nade files, only check each lineup once
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		l, err := LabelsFromFile(path)
+		if err != nil {
+			log.Printf("[Collisions] Skipping %s: %v", path, err)
+			report.Skipped = append(report.Skipped, path)
+			continue
+		}
+		for _, label := range l {
+			key := label.LineupId + "/" + label.SubType
+			if label.LineupId != "" && seen[key] {
+				continue
+			}
+			seen[key] = true
+			labels = append(labels, label)
+		}
+	}
+	report.Collisions = Analyze(labels, distance)
+	return report
+}
+
+// AnalyzeLibrary checks every annotation file under root, comparing nades on the same map
+func AnalyzeLibrary(root string, distance float64) (Report, error) {
+	paths, err := FindAnnotationFiles(root)
+	if err != nil {
+		return Report{}, err
+	}
+	return AnalyzeFiles(paths, distance), nil
+}
+
+// FindAnnotationFiles returns every .txt file under root
+func FindAnnotationFiles(root string) ([]string, error) {
+	var paths []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.EqualFold(filepath.Ext(path), ".txt") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking the path %s: %v", root, err)
+	}
+	return paths, nil
+}
+
+// String describes the collision on one line
+func (c Collision) String() string {
+	return fmt.Sprintf("%s: %s %s and %s %s are %.1f units apart (try TextPositionOffset [ %g, %g, %g ] on %s %s)",
+		c.A.MapName, c.A.NadeName, c.A.SubType, c.B.NadeName, c.B.SubType, c.Distance,
+		c.SuggestedOffset[0], c.SuggestedOffset[1], c.SuggestedOffset[2], c.B.NadeName, c.B.SubType)
+}
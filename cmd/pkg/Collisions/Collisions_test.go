@@ -0,0 +1,106 @@
+package Collisions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeLineup writes a main + aim_target annotation file
+func writeLineup(t *testing.T, dir, name, mapName, id string, main, aim [3]float64) string {
+	t.Helper()
+	content := fmt.Sprintf(`{
+	MapName = "%s"
+	MapAnnotationNode0 =
+	{
+		Id = "%s"
+		SubType = "main"
+		Position = [ %g, %g, %g ]
+		TextPositionOffset = [ 0.0, 0.0, 60.0 ]
+		Title =
+		{
+			Text = "%s"
+		}
+	}
+	MapAnnotationNode1 =
+	{
+		Id = "%s-aim"
+		SubType = "aim_target"
+		Position = [ %g, %g, %g ]
+		TextPositionOffset = [ 0.0, 0.0, 30.0 ]
+		MasterNodeId = "%s"
+	}
+	MapAnnotationNode2 =
+	{
+		Id = "%s-dest"
+		SubType = "destination"
+		Position = [ %g, %g, %g ]
+		MasterNodeId = "%s"
+	}
+}`, mapName, id, main[0], main[1], main[2], name, id, aim[0], aim[1], aim[2], id, id, main[0], main[1], main[2], id)
+
+	path := filepath.Join(dir, name+".txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestAnalyzeFiles(t *testing.T) {
+	dir := t.TempDir()
+	fire := writeLineup(t, dir, "BackLogsFire", "de_inferno", "1", [3]float64{0, 0, 0}, [3]float64{100, 0, 0})
+	he := writeLineup(t, dir, "BackLogsHE", "de_inferno", "2", [3]float64{10, 0, 0}, [3]float64{500, 0, 0})
+	// Same spot on another map never collides
+	other := writeLineup(t, dir, "T2Camera", "de_train", "3", [3]float64{0, 0, 0}, [3]float64{100, 0, 0})
+	missing := filepath.Join(dir, "missing.txt")
+
+	report := AnalyzeFiles([]string{fire, he, other, missing}, DefaultDistance)
+	if len(report.Skipped) != 1 || report.Skipped[0] != missing {
+		t.Errorf("unexpected skipped files: %v", report.Skipped)
+	}
+	if len(report.Collisions) != 1 {
+		t.Fatalf("expected 1 collision, got %v", report.Collisions)
+	}
+
+	c := report.Collisions[0]
+	if c.A.NadeName != "BackLogsFire" || c.B.NadeName != "BackLogsHE" || c.A.SubType != "main" || c.Distance != 10 {
+		t.Errorf("unexpected collision: %+v", c)
+	}
+	// B has to go up by the full distance to clear A
+	if c.SuggestedOffset[2] != 100 {
+		t.Errorf("unexpected suggested offset: %v", c.SuggestedOffset)
+	}
+
+	// A bigger distance also catches the aim_targets
+	report = AnalyzeFiles([]string{fire, he}, 1000)
+	if len(report.Collisions) != 4 {
+		t.Errorf("expected 4 collisions with a large distance, got %d", len(report.Collisions))
+	}
+
+	// A pack repeating the same lineups is not compared against itself
+	report = AnalyzeFiles([]string{fire, fire}, DefaultDistance)
+	if len(report.Collisions) != 0 {
+		t.Errorf("expected no collisions for repeated lineup, got %v", report.Collisions)
+	}
+}
+
+func TestAnalyzeLibrary(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "LeftCubbyFire"), 0755)
+	os.Mkdir(filepath.Join(dir, "LeftCubbyHE"), 0755)
+	writeLineup(t, filepath.Join(dir, "LeftCubbyFire"), "LeftCubbyFire", "de_inferno", "1", [3]float64{0, 0, 0}, [3]float64{100, 100, 0})
+	writeLineup(t, filepath.Join(dir, "LeftCubbyHE"), "LeftCubbyHE", "de_inferno", "2", [3]float64{0, 5, 0}, [3]float64{100, 105, 0})
+
+	report, err := AnalyzeLibrary(dir, DefaultDistance)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Collisions) != 2 {
+		t.Errorf("expected main and aim_target collisions, got %v", report.Collisions)
+	}
+
+	if _, err := AnalyzeLibrary(filepath.Join(dir, "non_existent"), DefaultDistance); err == nil {
+		t.Errorf("expected error for invalid directory")
+	}
+}
@@ -0,0 +1,143 @@
+package Drafts
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// Draft is a named File Generator selection that is kept between sessions
+type Draft struct {
+	Name    string    `json:"name"`
+	Files   []string  `json:"files"`
+	Updated time.Time `json:"updated"`
+}
+
+// Store holds every saved draft and remembers which one is active
+type Store struct {
+	Active string  `json:"active"`
+	Drafts []Draft `json:"drafts"`
+
+	path string
+}
+
+// DiffResult lists the nade files that differ between two drafts
+type DiffResult struct {
+	OnlyA []string
+	OnlyB []string
+	Both  []string
+}
+
+// DefaultDraftName is used when no draft has been created yet
+const DefaultDraftName = "Untitled"
+
+// Load reads the drafts file. A missing file returns an empty store.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("[Drafts] %s does not exist, starting with no drafts", path)
+			return s, nil
+		}
+		return s, fmt.Errorf("error reading drafts file %s: %v", path, err)
+	}
+	if len(data) == 0 {
+		return s, nil
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return s, fmt.Errorf("error parsing drafts file %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Save writes the store back to the file it was loaded from
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling drafts: %v", err)
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing drafts file %s: %v", s.path, err)
+	}
+	return nil
+}
+
+// Names returns the draft names in alphabetical order
+func (s *Store) Names() []string {
+	var names []string
+	for _, d := range s.Drafts {
+		names = append(names, d.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the draft with the given name
+func (s *Store) Get(name string) (Draft, bool) {
+	for _, d := range s.Drafts {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Draft{}, false
+}
+
+// Put creates or replaces the draft with the given name
+func (s *Store) Put(name string, files []string) {
+	// Copy so later changes to the caller's slice don't leak into the store
+	saved := append([]string(nil), files...)
+	for i := range s.Drafts {
+		if s.Drafts[i].Name == name {
+			s.Drafts[i].Files = saved
+			s.Drafts[i].Updated = time.Now()
+			return
+		}
+	}
+	s.Drafts = append(s.Drafts, Draft{Name: name, Files: saved, Updated: time.Now()})
+}
+
+// Delete removes the draft with the given name, clearing Active if needed
+func (s *Store) Delete(name string) {
+	for i := range s.Drafts {
+		if s.Drafts[i].Name == name {
+			s.Drafts = append(s.Drafts[:i], s.Drafts[i+1:]...)
+			break
+		}
+	}
+	if s.Active == name {
+		s.Active = ""
+	}
+}
+
+// Diff compares the files of two drafts, keeping the order of each draft
+func Diff(a, b Draft) DiffResult {
+	inA := make(map[string]bool)
+	inB := make(map[string]bool)
+	for _, f := range a.Files {
+		inA[f] = true
+	}
+	for _, f := range b.Files {
+		inB[f] = true
+	}
+
+	var result DiffResult
+	for _, f := range a.Files {
+		if inB[f] {
+			result.Both = append(result.Both, f)
+		} else {
+			result.OnlyA = append(result.OnlyA, f)
+		}
+	}
+	for _, f := range b.Files {
+		if !inA[f] {
+			result.OnlyB = append(result.OnlyB, f)
+		}
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package Drafts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStoreSaveLoad(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "drafts.json")
+
+	// Missing file should give an empty store
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading missing file: %v", err)
+	}
+	if len(s.Drafts) != 0 {
+		t.Errorf("expected no drafts, got %v", s.Drafts)
+	}
+
+	files := []string{"a.txt", "b.txt"}
+	s.Put("inferno B exec v2", files)
+	s.Put("mirage A", []string{"c.txt"})
+	s.Active = "inferno B exec v2"
+	// Changing the caller's slice must not change the stored draft
+	files[0] = "changed.txt"
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading: %v", err)
+	}
+	if loaded.Active != "inferno B exec v2" {
+		t.Errorf("unexpected active draft: %s", loaded.Active)
+	}
+	d, ok := loaded.Get("inferno B exec v2")
+	if !ok || !reflect.DeepEqual(d.Files, []string{"a.txt", "b.txt"}) {
+		t.Errorf("unexpected draft: %+v", d)
+	}
+	if !reflect.DeepEqual(loaded.Names(), []string{"inferno B exec v2", "mirage A"}) {
+		t.Errorf("unexpected names: %v", loaded.Names())
+	}
+
+	// Replacing keeps a single entry
+	loaded.Put("mirage A", []string{"d.txt"})
+	if len(loaded.Drafts) != 2 {
+		t.Errorf("expected 2 drafts after replace, got %d", len(loaded.Drafts))
+	}
+
+	loaded.Delete("inferno B exec v2")
+	if loaded.Active != "" || len(loaded.Drafts) != 1 {
+		t.Errorf("unexpected store after delete: %+v", loaded)
+	}
+
+	// Corrupt file should return an error
+	os.WriteFile(path, []byte("{not json"), 0644)
+	if _, err := Load(path); err == nil {
+		t.Errorf("expected error for corrupt drafts file")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := Draft{Name: "a", Files: []string{"1.txt", "2.txt", "3.txt"}}
+	b := Draft{Name: "b", Files: []string{"3.txt", "4.txt", "1.txt"}}
+
+	result := Diff(a, b)
+	expected := DiffResult{
+		OnlyA: []string{"2.txt"},
+		OnlyB: []string{"4.txt"},
+		Both:  []string{"1.txt", "3.txt"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected diff: got %+v, want %+v", result, expected)
+	}
+}
@@ -0,0 +1,239 @@
+package Drill
+
+// Spaced repetition for learning lineups. Each player has a card per nade
+// saying when it is next due; recalling a lineup well pushes it further out,
+// forgetting it brings it back the same session.
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// Grade is how well a lineup was recalled
+type Grade int
+
+const (
+	Again Grade = iota // couldn't recall it
+	Hard
+	Good
+	Easy
+)
+
+// GradeNames are the button labels of each Grade, in order
+var GradeNames = []string{"Again", "Hard", "Good", "Easy"}
+
+const (
+	// DefaultEase multiplies the interval after each good recall
+	DefaultEase = 2.5
+	// MinEase keeps hard lineups from being shown every day forever
+	MinEase = 1.3
+	// RetryDelay is when a forgotten lineup comes back
+	RetryDelay = 10 * time.Minute
+	// LearnedDays is the interval at which a lineup counts as learned
+	LearnedDays = 21
+)
+
+// Card is what one player has learned about one nade
+type Card struct {
+	Ease     float64   `json:"ease"`
+	Interval float64   `json:"interval_days"`
+	Reps     int       `json:"reps"` // good recalls in a row
+	Lapses   int       `json:"lapses"`
+	Due      time.Time `json:"due"`
+	Reviewed time.Time `json:"reviewed"`
+}
+
+// Review returns the card after a recall graded g at now
+func (c Card) Review(g Grade, now time.Time) Card {
+	if c.Ease == 0 {
+		c.Ease = DefaultEase
+	}
+	c.Reviewed = now
+	if g == Again {
+		c.Reps = 0
+		c.Lapses++
+		c.Interval = 0
+		c.Ease = math.Max(MinEase, c.Ease-0.2)
+		c.Due = now.Add(RetryDelay)
+		return c
+	}
+
+	c.Reps++
+	switch {
+	case c.Reps == 1:
+		c.Interval = 1
+	case c.Reps == 2:
+		c.Interval = 3
+	default:
+		c.Interval *= c.Ease
+	}
+	switch g {
+	case Hard:
+		c.Ease = math.Max(MinEase, c.Ease-0.15)
+		c.Interval = math.Max(1, c.Interval*0.6)
+	case Easy:
+		c.Ease += 0.15
+		c.Interval *= 1.3
+	}
+	c.Interval = math.Round(c.Interval*10) / 10
+	c.Due = now.Add(time.Duration(c.Interval * 24 * float64(time.Hour)))
+	return c
+}
+
+// Store holds the cards of every player, by player and then nade name
+type Store struct {
+	Players map[string]map[string]Card `json:"players"`
+
+	path string
+}
+
+// Load reads the drill file. A missing file returns an empty store.
+func Load(path string) (*Store, error) {
+	s := &Store{Players: make(map[string]map[string]Card), path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("[Drill] %s does not exist, starting with no players", path)
+			return s, nil
+		}
+		return s, fmt.Errorf("error reading drill file %s: %v", path, err)
+	}
+	if len(data) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return s, fmt.Errorf("error parsing drill file %s: %v", path, err)
+	}
+	if s.Players == nil {
+		s.Players = make(map[string]map[string]Card)
+	}
+	return s, nil
+}
+
+// Save writes the store back to the file it was loaded from
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling drill stats: %v", err)
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing drill file %s: %v", s.path, err)
+	}
+	return nil
+}
+
+// PlayerNames returns the players in alphabetical order
+func (s *Store) PlayerNames() []string {
+	var names []string
+	for name := range s.Players {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Cards returns a player's cards, which is empty for a new player
+func (s *Store) Cards(player string) map[string]Card {
+	return s.Players[player]
+}
+
+// Grade records a recall of nade by player and returns the updated card
+func (s *Store) Grade(player, nade string, g Grade, now time.Time) Card {
+	if s.Players[player] == nil {
+		s.Players[player] = make(map[string]Card)
+	}
+	c := s.Players[player][nade].Review(g, now)
+	s.Players[player][nade] = c
+	return c
+}
+
+// Next picks the nade to drill from names: the most overdue card first, then
+// a nade that was never drilled, in the order given. ok is false when nothing
+// is due.
+func Next(cards map[string]Card, names []string, now time.Time) (name string, ok bool) {
+	var due string
+	var dueAt time.Time
+	for _, n := range names {
+		c, seen := cards[n]
+		if seen && !c.Due.After(now) && (due == "" || c.Due.Before(dueAt)) {
+			due, dueAt = n, c.Due
+		}
+	}
+	if due != "" {
+		return due, true
+	}
+	for _, n := range names {
+		if _, seen := cards[n]; !seen {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+// Stats sums up a player's progress over a set of nades
+type Stats struct {
+	Total   int
+	New     int // never drilled
+	Due     int // drilled and due again
+	Learned int // due no sooner than LearnedDays after the last recall
+}
+
+// Summary counts the player's cards for names
+func Summary(cards map[string]Card, names []string, now time.Time) Stats {
+	st := Stats{Total: len(names)}
+	for _, n := range names {
+		c, seen := cards[n]
+		switch {
+		case !seen:
+			st.New++
+		case !c.Due.After(now):
+			st.Due++
+		case c.Interval >= LearnedDays:
+			st.Learned++
+		}
+	}
+	return st
+}
+
+func (st Stats) String() string {
+	return fmt.Sprintf("%d nades: %d due, %d new, %d learned", st.Total, st.Due, st.New, st.Learned)
+}
+
+// LandingImage is the screenshot of where a nade lands, found by its label or
+// file name. ok is false when the nade has none.
+func LandingImage(images []Tags.Image) (img Tags.Image, ok bool) {
+	land := Tags.ImageLabels[len(Tags.ImageLabels)-1]
+	for _, i := range images {
+		if i.Label == land || (i.Label == "" && Tags.ImageRole(i.Path) == Tags.RoleLand) {
+			return i, true
+		}
+	}
+	return Tags.Image{}, false
+}
+
+// Instructions reads how to throw a lineup from its annotation file: the
+// text of the standing node and of the aim node
+func Instructions(path string) (stand, aim string, err error) {
+	f, err := Annotation.Load(path)
+	if err != nil {
+		return "", "", err
+	}
+	for _, n := range f.Nodes() {
+		switch {
+		case n.SubType() == "main" && stand == "":
+			stand = n.DescText()
+		case n.SubType() == "aim_target" && aim == "":
+			aim = n.DescText()
+		}
+	}
+	return stand, aim, nil
+}
@@ -0,0 +1,147 @@
+package Drill
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestReview(t *testing.T) {
+	c := Card{}.Review(Good, now)
+	if c.Interval != 1 || c.Reps != 1 || !c.Due.Equal(now.Add(24*time.Hour)) || c.Ease != DefaultEase {
+		t.Errorf("unexpected first review: %+v", c)
+	}
+	c = c.Review(Good, c.Due)
+	if c.Interval != 3 {
+		t.Errorf("expected 3 days after the second review, got %v", c.Interval)
+	}
+	c = c.Review(Good, c.Due)
+	if c.Interval != 7.5 {
+		t.Errorf("expected the interval to grow by the ease, got %v", c.Interval)
+	}
+	easy := c.Review(Easy, c.Due)
+	hard := c.Review(Hard, c.Due)
+	if !(easy.Interval > hard.Interval) || easy.Ease <= c.Ease || hard.Ease >= c.Ease {
+		t.Errorf("easy should grow faster than hard: %+v %+v", easy, hard)
+	}
+
+	forgot := c.Review(Again, now)
+	if forgot.Reps != 0 || forgot.Lapses != 1 || forgot.Interval != 0 || !forgot.Due.Equal(now.Add(RetryDelay)) {
+		t.Errorf("unexpected forgotten card: %+v", forgot)
+	}
+	for i := 0; i < 20; i++ {
+		forgot = forgot.Review(Again, now)
+	}
+	if forgot.Ease != MinEase {
+		t.Errorf("ease should stop at %v, got %v", MinEase, forgot.Ease)
+	}
+}
+
+func TestNextAndSummary(t *testing.T) {
+	names := []string{"Coffins", "Banana", "Library", "Pit"}
+	cards := map[string]Card{
+		"Coffins": {Interval: 30, Due: now.Add(48 * time.Hour)},
+		"Banana":  {Interval: 1, Due: now.Add(-time.Hour)},
+		"Library": {Interval: 3, Due: now.Add(-48 * time.Hour)},
+	}
+	if n, ok := Next(cards, names, now); !ok || n != "Library" {
+		t.Errorf("expected the most overdue nade, got %q", n)
+	}
+	delete(cards, "Library")
+	delete(cards, "Banana")
+	if n, ok := Next(cards, names, now); !ok || n != "Banana" {
+		t.Errorf("expected the first new nade, got %q", n)
+	}
+	if _, ok := Next(cards, []string{"Coffins"}, now); ok {
+		t.Errorf("nothing should be due")
+	}
+
+	cards["Banana"] = Card{Interval: 1, Due: now}
+	st := Summary(cards, names, now)
+	if st != (Stats{Total: 4, New: 2, Due: 1, Learned: 1}) {
+		t.Errorf("unexpected summary: %+v", st)
+	}
+	if st.String() != "4 nades: 1 due, 2 new, 1 learned" {
+		t.Errorf("unexpected text %q", st.String())
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drill.json")
+	s, err := Load(path)
+	if err != nil || len(s.PlayerNames()) != 0 {
+		t.Fatalf("unexpected empty store: %v %v", s.PlayerNames(), err)
+	}
+	s.Grade("rookie", "Coffins", Good, now)
+	s.Grade("rookie", "Coffins", Again, now.Add(time.Hour))
+	s.Grade("awper", "Banana", Easy, now)
+	if err := s.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := loaded.PlayerNames(); len(names) != 2 || names[0] != "awper" {
+		t.Errorf("unexpected players: %v", names)
+	}
+	if c := loaded.Cards("rookie")["Coffins"]; c.Lapses != 1 || !c.Due.Equal(now.Add(time.Hour+RetryDelay)) {
+		t.Errorf("unexpected card after reload: %+v", c)
+	}
+	if len(loaded.Cards("nobody")) != 0 {
+		t.Errorf("a new player should have no cards")
+	}
+
+	os.WriteFile(path, []byte("{broken"), 0644)
+	if _, err := Load(path); err == nil {
+		t.Errorf("expected error for a broken file")
+	}
+}
+
+func TestLandingImageAndInstructions(t *testing.T) {
+	images := []Tags.Image{{Path: "a/Coffins.png"}, {Path: "a/Coffins_land.png"}}
+	if img, ok := LandingImage(images); !ok || img.Path != "a/Coffins_land.png" {
+		t.Errorf("expected the landing found by name, got %+v", img)
+	}
+	images = append([]Tags.Image{{Path: "a/shot.png", Label: "Landing"}}, images...)
+	if img, _ := LandingImage(images); img.Path != "a/shot.png" {
+		t.Errorf("expected the landing found by label, got %+v", img)
+	}
+	if _, ok := LandingImage([]Tags.Image{{Path: "a/Coffins.png"}}); ok {
+		t.Errorf("expected no landing image")
+	}
+
+	path := filepath.Join(t.TempDir(), "Coffins.txt")
+	os.WriteFile(path, []byte(`{
+	MapName = "de_inferno"
+	MapAnnotationNode0 = 
+	{
+		SubType = "main"
+		Desc = 
+		{
+			Text = "Stand in the corner"
+		}
+	}
+	MapAnnotationNode1 = 
+	{
+		SubType = "aim_target"
+		Desc = 
+		{
+			Text = "Aim at the chimney"
+		}
+	}
+}`), 0644)
+	stand, aim, err := Instructions(path)
+	if err != nil || stand != "Stand in the corner" || aim != "Aim at the chimney" {
+		t.Errorf("unexpected instructions %q %q %v", stand, aim, err)
+	}
+	if _, _, err := Instructions(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Errorf("expected error for a missing file")
+	}
+}
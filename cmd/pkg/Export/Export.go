@@ -0,0 +1,553 @@
+package Export
+
+// Builds a printable stratbook for each map from tags.json: every nade with its
+// screenshot, tags, description and the text from the annotation file, grouped
+// by site and grenade type. Written as HTML, Markdown or PDF.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+	_ "golang.org/x/image/webp"
+)
+
+// Formats Write understands
+const (
+	HTML     = "html"
+	Markdown = "md"
+	PDF      = "pdf"
+)
+
+// Formats lists every format, for menus and flags
+var Formats = []string{HTML, Markdown, PDF}
+
+// Entry is one nade in the book
+type Entry struct {
+	Tags.AnnotationMetadata
+	Stand  string // Title and Desc of the main node: where to stand
+	Aim    string // Title and Desc of the aim_target node: where to aim
+	Anchor string
+}
+
+// Group is every nade of one grenade type in a section
+type Group struct {
+	Type    string
+	Anchor  string
+	Entries []Entry
+}
+
+// Section is every nade landing on one site
+type Section struct {
+	Site   string
+	Anchor string
+	Groups []Group
+}
+
+// Book is the stratbook of one map
+type Book struct {
+	Title      string
+	MapName    string
+	WorkshopID string
+	Generated  time.Time
+	Sections   []Section
+}
+
+// Order of the grenade types in a section; others follow alphabetically
+var typeOrder = map[string]int{"smoke": 0, "flash": 1, "molotov": 2, "incendiary": 3, "he": 4}
+
+// noSite is the section for nades without a site
+const noSite = "Other"
+
+// Books groups the nades into one book per map. If files is not empty only the
+// nades whose FilePath is in it are included, so a book can follow the File
+// Generator selection.
+func Books(nades []Tags.AnnotationMetadata, files []string) []Book {
+	selected := make(map[string]bool)
+	for _, f := range files {
+		selected[f] = true
+	}
+
+	byMap := make(map[Maps.Key][]Tags.AnnotationMetadata)
+	var keys []Maps.Key
+	for _, nade := range nades {
+		if len(selected) > 0 && !selected[nade.FilePath] {
+			continue
+		}
+		key := Maps.Key{Name: nade.MapName, WorkshopID: nade.WorkshopID}
+		if _, ok := byMap[key]; !ok {
+			keys = append(keys, key)
+		}
+		byMap[key] = append(byMap[key], nade)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Name != keys[j].Name {
+			return keys[i].Name < keys[j].Name
+		}
+		return keys[i].WorkshopID < keys[j].WorkshopID
+	})
+
+	var books []Book
+	for _, key := range keys {
+		book := Book{
+			Title:      "CS-StratBook: " + key.DisplayName(),
+			MapName:    key.Name,
+			WorkshopID: key.WorkshopID,
+			Generated:  time.Now(),
+		}
+		book.Sections = sections(key.Name, byMap[key])
+		books = append(books, book)
+	}
+	return books
+}
+
+func siteOf(nade Tags.AnnotationMetadata) string {
+	if nade.Site == "" {
+		return noSite
+	}
+	return nade.Site
+}
+
+// sections groups the nades of one map by site, then by type. Sites are in
+// registry order, then any the registry doesn't know, then Other.
+func sections(mapName string, nades []Tags.AnnotationMetadata) []Section {
+	present := make(map[string]bool)
+	for _, nade := range nades {
+		present[siteOf(nade)] = true
+	}
+	var order []string
+	for _, site := range Maps.Sites(mapName) {
+		if present[site] {
+			order = append(order, site)
+			delete(present, site)
+		}
+	}
+	other := present[noSite]
+	delete(present, noSite)
+	var unknown []string
+	for site := range present {
+		unknown = append(unknown, site)
+	}
+	sort.Strings(unknown)
+	order = append(order, unknown...)
+	if other {
+		order = append(order, noSite)
+	}
+
+	var result []Section
+	for _, site := range order {
+		byType := make(map[string][]Entry)
+		for _, nade := range nades {
+			if siteOf(nade) == site {
+				byType[nade.NadeType] = append(byType[nade.NadeType], entry(nade))
+			}
+		}
+
+		var types []string
+		for t := range byType {
+			types = append(types, t)
+		}
+		sort.Slice(types, func(i, j int) bool {
+			oi, iok := typeOrder[types[i]]
+			oj, jok := typeOrder[types[j]]
+			if iok != jok {
+				return iok
+			}
+			if oi != oj {
+				return oi < oj
+			}
+			return types[i] < types[j]
+		})
+
+		section := Section{Site: site, Anchor: anchor(site)}
+		for _, t := range types {
+			entries := byType[t]
+			sort.Slice(entries, func(i, j int) bool { return entries[i].NadeName < entries[j].NadeName })
+			for i := range entries {
+				entries[i].Anchor = anchor(site + "-" + t + "-" + entries[i].NadeName)
+			}
+			section.Groups = append(section.Groups, Group{Type: typeName(t), Anchor: anchor(site + "-" + t), Entries: entries})
+		}
+		result = append(result, section)
+	}
+	return result
+}
+
+// typeName is the heading of a grenade type group
+func typeName(t string) string {
+	switch t {
+	case "":
+		return "Unknown"
+	case "he":
+		return "HE"
+	}
+	return strings.ToUpper(t[:1]) + t[1:]
+}
+
+// anchor turns a heading into an id usable in links
+func anchor(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// entry reads the stand and aim text from the nade's annotation file
+func entry(nade Tags.AnnotationMetadata) Entry {
+	e := Entry{AnnotationMetadata: nade}
+	f, err := Annotation.Load(nade.FilePath)
+	if err != nil {
+		log.Printf("[Export] Could not read %s: %v", nade.FilePath, err)
+		return e
+	}
+	for _, n := range f.Nodes() {
+		text := joinText(n.TitleText(), n.DescText())
+		switch n.SubType() {
+		case "main":
+			if e.Stand == "" {
+				e.Stand = text
+			}
+		case "aim_target":
+			if e.Aim == "" {
+				e.Aim = text
+			}
+		}
+	}
+	return e
+}
+
+func joinText(title, desc string) string {
+	switch {
+	case title == "":
+		return desc
+	case desc == "":
+		return title
+	}
+	return title + ": " + desc
+}
+
+// relPath returns target relative to dir, so links work from the exported file
+func relPath(dir, target string) string {
+	if target == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return filepath.ToSlash(target)
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
+var htmlTemplate = template.Must(template.New("book").Funcs(template.FuncMap{"img": func(string) string { return "" }}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: auto; padding: 1em; }
+.nade { page-break-inside: avoid; border-top: 1px solid #ccc; padding: 0.5em 0; }
+.nade img { max-width: 100%; }
+.tags { color: #555; }
+@media print { nav { page-break-after: always; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="tags">{{.MapName}}, generated {{.Generated.Format "2006-01-02"}}</p>
+<nav>
+<h2>Contents</h2>
+<ul>
+{{- range .Sections}}
+<li><a href="#{{.Anchor}}">{{.Site}}</a>
+<ul>
+{{- range .Groups}}
+<li><a href="#{{.Anchor}}">{{.Type}}</a> ({{len .Entries}})</li>
+{{- end}}
+</ul>
+</li>
+{{- end}}
+</ul>
+</nav>
+{{- range .Sections}}
+<h2 id="{{.Anchor}}">{{.Site}}</h2>
+{{- range .Groups}}
+<h3 id="{{.Anchor}}">{{.Type}}</h3>
+{{- range .Entries}}
+<div class="nade" id="{{.Anchor}}">
+<h4>{{.NadeName}}</h4>
+<p class="tags">{{with .Side}}{{.}} · {{end}}{{with .Site}}{{.}} · {{end}}{{with .Callout}}{{.}} · {{end}}{{.NadeType}}</p>
+{{- with .Description}}
+<p>{{.}}</p>
+{{- end}}
+{{- with .Stand}}
+<p><b>Stand:</b> {{.}}</p>
+{{- end}}
+{{- with .Aim}}
+<p><b>Aim:</b> {{.}}</p>
+{{- end}}
+{{- with img .ImagePath}}
+<img src="{{.}}" alt="">
+{{- end}}
+</div>
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the book as a single HTML page. Screenshots are linked
+// relative to dir, the folder the page is saved in.
+func WriteHTML(w io.Writer, book Book, dir string) error {
+	t, err := htmlTemplate.Clone()
+	if err != nil {
+		return err
+	}
+	t.Funcs(template.FuncMap{"img": func(path string) string { return relPath(dir, path) }})
+	return t.Execute(w, book)
+}
+
+// WriteMarkdown writes the book as Markdown. Screenshots are linked relative to dir.
+func WriteMarkdown(w io.Writer, book Book, dir string) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n%s, generated %s\n\n## Contents\n\n", book.Title, book.MapName, book.Generated.Format("2006-01-02"))
+	for _, s := range book.Sections {
+		fmt.Fprintf(&b, "- [%s](#%s)\n", s.Site, s.Anchor)
+		for _, g := range s.Groups {
+			fmt.Fprintf(&b, "  - [%s](#%s) (%d)\n", g.Type, g.Anchor, len(g.Entries))
+		}
+	}
+	for _, s := range book.Sections {
+		fmt.Fprintf(&b, "\n<a id=\"%s\"></a>\n## %s\n", s.Anchor, s.Site)
+		for _, g := range s.Groups {
+			fmt.Fprintf(&b, "\n<a id=\"%s\"></a>\n### %s\n", g.Anchor, g.Type)
+			for _, e := range g.Entries {
+				fmt.Fprintf(&b, "\n#### %s\n\n", e.NadeName)
+				fmt.Fprintf(&b, "*%s*\n\n", strings.Join(tagLine(e), " · "))
+				if e.Description != "" {
+					fmt.Fprintf(&b, "%s\n\n", e.Description)
+				}
+				if e.Stand != "" {
+					fmt.Fprintf(&b, "**Stand:** %s\n\n", e.Stand)
+				}
+				if e.Aim != "" {
+					fmt.Fprintf(&b, "**Aim:** %s\n\n", e.Aim)
+				}
+				if e.ImagePath != "" {
+					fmt.Fprintf(&b, "![%s](%s)\n", e.NadeName, strings.ReplaceAll(relPath(dir, e.ImagePath), " ", "%20"))
+				}
+			}
+		}
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func tagLine(e Entry) []string {
+	var tags []string
+	for _, t := range []string{e.Side, e.Site, e.Callout, e.NadeType} {
+		if t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// WritePDF writes the book as an A4 PDF with a linked contents page and bookmarks
+func WritePDF(w io.Writer, book Book) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(book.Title, true)
+	pdf.SetAutoPageBreak(true, 15)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 5, fmt.Sprintf("%s - %d", tr(book.Title), pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	width := pageWidth - left - right
+
+	// Contents first; the links are pointed at the sections as they are written
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.MultiCell(width, 10, tr(book.Title), "", "L", false)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(width, 6, tr(book.MapName+", generated "+book.Generated.Format("2006-01-02")), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(width, 8, "Contents", "", 1, "L", false, 0, "")
+	links := make(map[string]int)
+	for _, s := range book.Sections {
+		links[s.Anchor] = pdf.AddLink()
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(width, 6, tr(s.Site), "", 1, "L", false, links[s.Anchor], "")
+		for _, g := range s.Groups {
+			links[g.Anchor] = pdf.AddLink()
+			pdf.SetFont("Helvetica", "", 11)
+			pdf.CellFormat(width, 6, tr(fmt.Sprintf("    %s (%d)", g.Type, len(g.Entries))), "", 1, "L", false, links[g.Anchor], "")
+		}
+	}
+
+	for _, s := range book.Sections {
+		pdf.AddPage()
+		pdf.SetLink(links[s.Anchor], -1, -1)
+		pdf.Bookmark(tr(s.Site), 0, -1)
+		pdf.SetFont("Helvetica", "B", 18)
+		pdf.CellFormat(width, 10, tr(s.Site), "", 1, "L", false, 0, "")
+		for _, g := range s.Groups {
+			pdf.SetLink(links[g.Anchor], -1, -1)
+			pdf.Bookmark(tr(g.Type), 1, -1)
+			pdf.SetFont("Helvetica", "B", 14)
+			pdf.CellFormat(width, 9, tr(g.Type), "", 1, "L", false, 0, "")
+			for _, e := range g.Entries {
+				writePDFEntry(pdf, tr, width, e)
+			}
+		}
+	}
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+func writePDFEntry(pdf *fpdf.Fpdf, tr func(string) string, width float64, e Entry) {
+	pdf.Ln(2)
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.MultiCell(width, 6, tr(e.NadeName), "", "L", false)
+	pdf.SetFont("Helvetica", "I", 9)
+	pdf.MultiCell(width, 5, tr(strings.Join(tagLine(e), " - ")), "", "L", false)
+	pdf.SetFont("Helvetica", "", 10)
+	if e.Description != "" {
+		pdf.MultiCell(width, 5, tr(e.Description), "", "L", false)
+	}
+	if e.Stand != "" {
+		pdf.MultiCell(width, 5, tr("Stand: "+e.Stand), "", "L", false)
+	}
+	if e.Aim != "" {
+		pdf.MultiCell(width, 5, tr("Aim: "+e.Aim), "", "L", false)
+	}
+	if info := registerImage(pdf, e.ImagePath); info != nil {
+		w := width * 0.75
+		h := w * info.Height() / info.Width()
+		_, pageHeight := pdf.GetPageSize()
+		if pdf.GetY()+h > pageHeight-20 {
+			pdf.AddPage()
+		}
+		pdf.ImageOptions(e.ImagePath, pdf.GetX(), pdf.GetY()+1, w, h, true, fpdf.ImageOptions{ImageType: "JPG"}, 0, "")
+	}
+	pdf.Ln(3)
+}
+
+// registerImage adds a screenshot to the PDF. Images are decoded and re-encoded
+// as JPEG so any PNG or JPEG works and a bad file is skipped instead of failing
+// the whole document.
+func registerImage(pdf *fpdf.Fpdf, path string) *fpdf.ImageInfoType {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("[Export] Skipping image %s: %v", path, err)
+		return nil
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		log.Printf("[Export] Skipping image %s: %v", path, err)
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		log.Printf("[Export] Skipping image %s: %v", path, err)
+		return nil
+	}
+	return pdf.RegisterImageOptionsReader(path, fpdf.ImageOptions{ImageType: "JPG"}, &buf)
+}
+
+// unsafeName matches the characters left out of file names
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// FileName is the name a book is saved under, e.g. de_inferno.html, or
+// de_inferno_3070284539.html for a workshop copy of the map. A MapName such as
+// workshop/3070284539/de_foo is named after its last part.
+func FileName(book Book, format string) string {
+	name := unsafeName.ReplaceAllString(path.Base(book.MapName), "_")
+	if strings.Trim(name, "._") == "" {
+		name = "unknown_map"
+	}
+	if book.WorkshopID != "" {
+		name += "_" + unsafeName.ReplaceAllString(book.WorkshopID, "_")
+	}
+	return name + "." + format
+}
+
+// Write saves each book into dir in the given format and returns the paths written.
+// A book that fails doesn't stop the others; their errors are returned together.
+func Write(dir string, books []Book, format string) ([]string, error) {
+	switch format {
+	case HTML, Markdown, PDF:
+	default:
+		return nil, fmt.Errorf("unknown export format %q, use html, md or pdf", format)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	var errs []error
+	for _, book := range books {
+		path := filepath.Join(dir, FileName(book, format))
+		var buf bytes.Buffer
+		switch format {
+		case HTML:
+			err = WriteHTML(&buf, book, absDir)
+		case Markdown:
+			err = WriteMarkdown(&buf, book, absDir)
+		case PDF:
+			err = WritePDF(&buf, book)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to export %s: %v", book.Title, err))
+			continue
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write %s: %v", path, err))
+			continue
+		}
+		log.Printf("[Export] Wrote %s", path)
+		written = append(written, path)
+	}
+	return written, errors.Join(errs...)
+}
@@ -0,0 +1,169 @@
+package Export
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// carMolly has the stand and aim text that a book prints under the nade
+const carMolly = `{
+	MapName = "de_inferno"
+	MapAnnotationNode0 =
+	{
+		SubType = "main"
+		Title =
+		{
+			Text = "Car Molly"
+		}
+		Desc =
+		{
+			Text = "stand in the corner"
+		}
+		GrenadeType = "molotov"
+	}
+	MapAnnotationNode1 =
+	{
+		SubType = "aim_target"
+		Title =
+		{
+			Text = "aim at the lamp"
+		}
+	}
+}`
+
+func writeNades(t *testing.T) (string, []Tags.AnnotationMetadata) {
+	t.Helper()
+	dir := t.TempDir()
+	txt := filepath.Join(dir, "CarMolly", "CarMolly.txt")
+	img := filepath.Join(dir, "CarMolly", "Car Molly.png")
+	os.MkdirAll(filepath.Dir(txt), 0755)
+	os.WriteFile(txt, []byte(carMolly), 0644)
+	file, _ := os.Create(img)
+	png.Encode(file, image.NewRGBA(image.Rect(0, 0, 16, 9)))
+	file.Close()
+
+	return dir, []Tags.AnnotationMetadata{
+		{NadeName: "CarMolly", FilePath: txt, ImagePath: img, MapName: "de_inferno", Site: "B", Side: "T", Callout: "B Car", NadeType: "molotov", Description: "Burns car"},
+		{NadeName: "BananaSmoke", FilePath: txt, MapName: "de_inferno", Site: "B", NadeType: "smoke"},
+		{NadeName: "PitFlash", FilePath: filepath.Join(dir, "missing.txt"), MapName: "de_inferno", Site: "A", NadeType: "flash"},
+		{NadeName: "Somewhere", FilePath: txt, MapName: "de_inferno", NadeType: "he"},
+		{NadeName: "T2Camera", FilePath: "train.txt", MapName: "de_train", Site: "A", NadeType: "smoke"},
+	}
+}
+
+func TestBooks(t *testing.T) {
+	_, nades := writeNades(t)
+
+	books := Books(nades, nil)
+	if len(books) != 2 || books[0].MapName != "de_inferno" || books[0].Title != "CS-StratBook: Inferno" {
+		t.Fatalf("unexpected books: %+v", books)
+	}
+	inferno := books[0]
+	var sites []string
+	for _, s := range inferno.Sections {
+		sites = append(sites, s.Site)
+	}
+	if strings.Join(sites, ",") != "A,B,Other" {
+		t.Errorf("unexpected site order: %v", sites)
+	}
+	b := inferno.Sections[1]
+	if len(b.Groups) != 2 || b.Groups[0].Type != "Smoke" || b.Groups[1].Type != "Molotov" {
+		t.Errorf("unexpected groups: %+v", b.Groups)
+	}
+	car := b.Groups[1].Entries[0]
+	if car.Stand != "Car Molly: stand in the corner" || car.Aim != "aim at the lamp" {
+		t.Errorf("unexpected in-file text: %q %q", car.Stand, car.Aim)
+	}
+
+	// Only the selected files
+	books = Books(nades, []string{"train.txt"})
+	if len(books) != 1 || books[0].MapName != "de_train" {
+		t.Errorf("expected only the train book, got %+v", books)
+	}
+
+	// A workshop copy of a map gets a book of its own
+	workshop := Tags.AnnotationMetadata{NadeName: "WorkshopSmoke", FilePath: "workshop.txt", MapName: "de_inferno", WorkshopID: "3070284539", NadeType: "smoke"}
+	books = Books(append(nades, workshop), nil)
+	if len(books) != 3 || books[1].WorkshopID != "3070284539" || books[1].Title != "CS-StratBook: Inferno (Workshop 3070284539)" || len(books[1].Sections) != 1 {
+		t.Fatalf("unexpected workshop book: %+v", books)
+	}
+	if FileName(books[0], "html") == FileName(books[1], "html") {
+		t.Errorf("the books would overwrite each other: %s", FileName(books[1], "html"))
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir, nades := writeNades(t)
+	book := Books(nades, nil)[0]
+	outDir := filepath.Join(dir, "out")
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, book, outDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{`<a href="#b-molotov">Molotov</a> (1)`, `id="b-molotov-carmolly"`, `<b>Aim:</b> aim at the lamp`, `src="../CarMolly/Car%20Molly.png"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html is missing %s", want)
+		}
+	}
+
+	buf.Reset()
+	if err := WriteMarkdown(&buf, book, outDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	md := buf.String()
+	for _, want := range []string{"  - [Molotov](#b-molotov) (1)", "#### CarMolly", "*T · B · B Car · molotov*", "![CarMolly](../CarMolly/Car%20Molly.png)"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown is missing %s", want)
+		}
+	}
+
+	buf.Reset()
+	if err := WritePDF(&buf, book); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+		t.Errorf("not a pdf")
+	}
+
+	paths, err := Write(outDir, []Book{book}, PDF)
+	if err != nil || len(paths) != 1 || filepath.Base(paths[0]) != "de_inferno.pdf" {
+		t.Errorf("unexpected write: %v, %v", paths, err)
+	}
+	if _, err := Write(outDir, []Book{book}, "docx"); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
+
+func TestWriteEveryBook(t *testing.T) {
+	outDir := t.TempDir()
+	books := []Book{
+		{MapName: "de_inferno", Title: "Inferno"},
+		{MapName: "workshop/3070284539/de_foo", WorkshopID: "3070284539", Title: "Foo"},
+		{MapName: "", Title: "No map"},
+	}
+	if name := FileName(books[1], Markdown); name != "de_foo_3070284539.md" {
+		t.Errorf("unexpected workshop file name %s", name)
+	}
+	if name := FileName(books[2], Markdown); name != "unknown_map.md" {
+		t.Errorf("unexpected file name for an empty map %s", name)
+	}
+
+	// A folder in the way of the first book doesn't stop the others
+	os.Mkdir(filepath.Join(outDir, "de_inferno.md"), 0755)
+	paths, err := Write(outDir, books, Markdown)
+	if err == nil || !strings.Contains(err.Error(), "de_inferno.md") {
+		t.Errorf("expected the inferno book to fail, got %v", err)
+	}
+	if len(paths) != 2 || filepath.Base(paths[0]) != "de_foo_3070284539.md" || filepath.Base(paths[1]) != "unknown_map.md" {
+		t.Errorf("unexpected paths %v", paths)
+	}
+}
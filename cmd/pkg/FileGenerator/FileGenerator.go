@@ -3,15 +3,25 @@ package FileGenerator
 // Usage: go run main.go -o OutPutfile.txt <file1.txt> <file2.txt> ... <fileN.txt>
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/PracConfig"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Styles"
 )
 
 type NadeList struct {
 	Files []string
+	// OnChanged is called after the list is modified
+	OnChanged func()
 }
 
 // removeFirstDigits strips leading digits from a string
@@ -32,6 +42,7 @@ func (nl *NadeList) AddNade(filePath string) {
 		}
 	}
 	nl.Files = append(nl.Files, filePath)
+	nl.changed()
 }
 
 // RemoveNade removes a nade file path if present
@@ -39,27 +50,214 @@ func (nl *NadeList) RemoveNade(filePath string) {
 	for i, f := range nl.Files {
 		if f == filePath {
 			nl.Files = append(nl.Files[:i], nl.Files[i+1:]...)
+			nl.changed()
 			return
 		}
 	}
 }
 
+// SetFiles replaces the whole list, used when switching drafts
+func (nl *NadeList) SetFiles(files []string) {
+	nl.Files = append([]string(nil), files...)
+	nl.changed()
+}
+
+func (nl *NadeList) changed() {
+	if nl.OnChanged != nil {
+		nl.OnChanged()
+	}
+}
+
+// Result describes a generated annotation file
+type Result struct {
+	OutputPath string
+	NodeCount  int
+	Skipped    []string // input files that could not be read
+	Merged     []string // lineups that now share another lineup's standing node
+	Warnings   []string
+	// TeleportPath is the companion .cfg written when Options.TeleportDir is set
+	TeleportPath string
+}
+
+// Options change how a pack is built. The zero value copies the nodes as they are.
+type Options struct {
+	// MergeTolerance merges lineups whose main nodes are within this many
+	// units of each other into one standing node. 0 turns merging off.
+	MergeTolerance float64
+	// Style is applied to every node of the pack when set
+	Style *Styles.Profile
+	// TeleportDir, when set, is the folder to write <pack>_teleports.cfg to,
+	// which binds keys that step through the pack's lineups. It should be
+	// CS2's cfg folder so exec can find it.
+	TeleportDir string
+}
+
+// DefaultMergeTolerance is about half a player width
+const DefaultMergeTolerance = 16.0
+
+var mapNameRegex = regexp.MustCompile(`MapName = "([^"]*)"`)
+
+// annotations_load takes the pack name as a single console argument, so keep names to safe characters
+var outputNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// NormalizeOutputName trims the name, adds the .txt extension and checks
+// that CS2's annotations_load can load it
+func NormalizeOutputName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("output name %q must not contain a folder", name)
+	}
+	if strings.EqualFold(filepath.Ext(name), ".txt") {
+		name = name[:len(name)-len(".txt")]
+	}
+	if name == "" {
+		return "", errors.New("output name is required")
+	}
+	if !outputNameRegex.MatchString(name) {
+		return "", fmt.Errorf("output name %q can only contain letters, numbers, '_' and '-'", name)
+	}
+	return name + ".txt", nil
+}
+
+// FindCollisions warns when a pack name is already used by a folder in
+// outputDir or by an existing nade
+func FindCollisions(outputDir, outputName string, nadeNames []string) []string {
+	var collisions []string
+	baseName := strings.TrimSuffix(outputName, filepath.Ext(outputName))
+
+	if info, err := os.Stat(filepath.Join(outputDir, baseName)); err == nil && info.IsDir() {
+		collisions = append(collisions, fmt.Sprintf("a folder named %s already exists in %s", baseName, outputDir))
+	}
+	for _, nadeName := range nadeNames {
+		if strings.EqualFold(nadeName, baseName) {
+			collisions = append(collisions, fmt.Sprintf("a nade named %s already exists", nadeName))
+			break
+		}
+	}
+	return collisions
+}
+
 // FileGeneratorFromList is called from the UI, wraps FileGenerator
-func FileGeneratorFromList(outputFile string, nl *NadeList) {
-	FileGenerator(outputFile, nl.Files)
+func FileGeneratorFromList(outputFile string, nl *NadeList, opts Options) (Result, error) {
+	return FileGeneratorWithOptions(outputFile, nl.Files, opts)
+}
+
+// FileGeneratorWithOptions builds a pack, parsing the nodes when an option needs to change them
+func FileGeneratorWithOptions(outputFile string, inputFiles []string, opts Options) (Result, error) {
+	teleportDir := opts.TeleportDir
+	opts.TeleportDir = ""
+	var result Result
+	var err error
+	if opts == (Options{}) {
+		result, err = FileGenerator(outputFile, inputFiles)
+	} else {
+		result, err = generateParsed(outputFile, inputFiles, opts)
+	}
+	if err != nil || teleportDir == "" {
+		return result, err
+	}
+
+	// The teleports follow the lineups in the pack as written, after any merging
+	spots, err := PracConfig.Spots([]string{outputFile})
+	if err != nil {
+		return result, err
+	}
+	pack := strings.TrimSuffix(filepath.Base(outputFile), filepath.Ext(outputFile))
+	path := filepath.Join(teleportDir, pack+"_teleports.cfg")
+	if err := os.WriteFile(path, TeleportConfig(pack, spots), 0644); err != nil {
+		return result, fmt.Errorf("error writing to file %s: %v", path, err)
+	}
+	result.TeleportPath = path
+	log.Println("Teleport config created:", path)
+	return result, nil
 }
 
-// FileGenerator merges nade metadata files and renumbers MapAnnotationNodes
-func FileGenerator(outputFile string, inputFiles []string) {
+// Keys bound by the teleport config
+const (
+	TeleportNextKey = "kp_plus"
+	TeleportPrevKey = "kp_minus"
+)
+
+// consoleText keeps the characters that are safe in an unquoted echo inside an alias
+var consoleText = regexp.MustCompile(`[^A-Za-z0-9 _+.,-]`)
+
+// TeleportConfig returns a config of alias chains that setpos/setang to each
+// spot in order. Each step redefines the next and previous aliases around
+// itself, so TeleportNextKey moves on to the next lineup and TeleportPrevKey
+// goes back, wrapping around at the ends.
+func TeleportConfig(pack string, spots []PracConfig.Spot) []byte {
+	var sb strings.Builder
+	prefix := "sb_" + consoleText.ReplaceAllString(pack, "_")
+	step := func(i int) string { return fmt.Sprintf("%s_%d", prefix, (i+len(spots))%len(spots)) }
+
+	fmt.Fprintf(&sb, "// Teleports through the lineups of %s, written by CS_StratBook\n", pack)
+	fmt.Fprintf(&sb, "// %s goes to the next lineup, %s to the previous one\n", TeleportNextKey, TeleportPrevKey)
+	if len(spots) == 0 {
+		sb.WriteString("echo \"No lineups in " + consoleText.ReplaceAllString(pack, "") + "\"\n")
+		return []byte(sb.String())
+	}
+	for i, s := range spots {
+		name := strings.TrimSpace(consoleText.ReplaceAllString(s.Name, ""))
+		fmt.Fprintf(&sb, "alias \"%s\" \"%s;alias stratbook_next %s;alias stratbook_prev %s;echo %d of %d - %s\"\n",
+			step(i), s.Setpos(), step(i+1), step(i-1), i+1, len(spots), name)
+	}
+	// Before the first press next goes to the first lineup and previous to the last
+	fmt.Fprintf(&sb, "alias \"stratbook_next\" \"%s\"\n", step(0))
+	fmt.Fprintf(&sb, "alias \"stratbook_prev\" \"%s\"\n", step(-1))
+	fmt.Fprintf(&sb, "bind \"%s\" \"stratbook_next\"\n", TeleportNextKey)
+	fmt.Fprintf(&sb, "bind \"%s\" \"stratbook_prev\"\n", TeleportPrevKey)
+	return []byte(sb.String())
+}
+
+// Pack builds a pack in a temporary folder and returns its contents, for
+// callers that send the pack somewhere instead of saving it
+func Pack(inputFiles []string, opts Options) ([]byte, Result, error) {
+	dir, err := os.MkdirTemp("", "stratbook-pack")
+	if err != nil {
+		return nil, Result{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	result, err := FileGeneratorWithOptions(filepath.Join(dir, "pack.txt"), inputFiles, opts)
+	if err != nil {
+		return nil, result, err
+	}
+	data, err := os.ReadFile(result.OutputPath)
+	result.OutputPath = ""
+	return data, result, err
+}
+
+// FileGenerator merges nade metadata files and renumbers MapAnnotationNodes.
+// Input files that can't be read are skipped and reported in the result.
+func FileGenerator(outputFile string, inputFiles []string) (Result, error) {
+	result := Result{OutputPath: outputFile}
+	if len(inputFiles) == 0 {
+		return result, errors.New("no input files selected")
+	}
+
 	var bigout []string
 	var start string
+	var firstMap string
+	readCount := 0
 	mapindex := 0
 
-	for i, fileName := range inputFiles {
+	for _, fileName := range inputFiles {
 		// Read file
 		fileText, err := os.ReadFile(fileName)
 		if err != nil {
-			log.Fatalf("Error reading file %s: %v", fileName, err)
+			log.Printf("Error reading file %s: %v", fileName, err)
+			result.Skipped = append(result.Skipped, fileName)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("skipped %s: %v", fileName, err))
+			continue
+		}
+
+		// All nades in a pack should be for the same map
+		if m := mapNameRegex.FindStringSubmatch(string(fileText)); len(m) > 1 {
+			if firstMap == "" {
+				firstMap = m[1]
+			} else if m[1] != firstMap {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s is for %s, pack is for %s", fileName, m[1], firstMap))
+			}
 		}
 
 		// Convert from []byte to string and remove last '}'
@@ -68,11 +266,15 @@ func FileGenerator(outputFile string, inputFiles []string) {
 
 		// Split the files at "MapAnnotationNode"
 		fileSplit := strings.Split(fileTextStr, "MapAnnotationNode")
+		if len(fileSplit) < 2 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s has no MapAnnotationNode entries", fileName))
+		}
 
-		// Store the first section separately (only from the first file)
-		if i == 0 {
+		// Store the first section separately (only from the first file that could be read)
+		if readCount == 0 {
 			start = fileSplit[0]
 		}
+		readCount++
 
 		// Append MapAnnotationNode entries, renumbering them
 		for j := 1; j < len(fileSplit); j++ {
@@ -88,13 +290,158 @@ func FileGenerator(outputFile string, inputFiles []string) {
 		}
 	}
 
+	if readCount == 0 {
+		return result, fmt.Errorf("none of the %d input files could be read", len(inputFiles))
+	}
+
 	// Merge everything: beginning of file1 + fixed MapAnnotationNodes + trailing }
 	newfile := start + strings.Join(bigout, "") + "}"
 
 	// Write output to file
 	if rerr := os.WriteFile(outputFile, []byte(newfile), 0644); rerr != nil {
-		log.Fatalf("Error writing to file %s: %v", outputFile, rerr)
+		return result, fmt.Errorf("error writing to file %s: %v", outputFile, rerr)
+	}
+
+	result.NodeCount = mapindex
+	log.Println("Merged file created successfully:", outputFile)
+	return result, nil
+}
+
+// generateParsed merges the input files node by node so the nodes can be changed before writing
+func generateParsed(outputFile string, inputFiles []string, opts Options) (Result, error) {
+	result := Result{OutputPath: outputFile}
+	if len(inputFiles) == 0 {
+		return result, errors.New("no input files selected")
+	}
+
+	var pack *Annotation.File
+	var nodes []Annotation.Node
+	for _, fileName := range inputFiles {
+		f, err := Annotation.Load(fileName)
+		if err != nil {
+			log.Printf("Error reading file %s: %v", fileName, err)
+			result.Skipped = append(result.Skipped, fileName)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("skipped %s: %v", fileName, err))
+			continue
+		}
+		fileNodes := f.Nodes()
+		if len(fileNodes) == 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s has no MapAnnotationNode entries", fileName))
+		}
+
+		// The first file that could be read gives the header and map
+		if pack == nil {
+			pack = f
+		} else if f.MapName() != pack.MapName() {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s is for %s, pack is for %s", fileName, f.MapName(), pack.MapName()))
+		}
+		nodes = append(nodes, fileNodes...)
+	}
+
+	if pack == nil {
+		return result, fmt.Errorf("none of the %d input files could be read", len(inputFiles))
+	}
+
+	nodes, repeated := dropRepeats(nodes)
+	for _, title := range repeated {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%s is in the pack twice, kept once", title))
+	}
+	if opts.MergeTolerance > 0 {
+		var merged []string
+		nodes, merged = MergeSharedSpots(nodes, opts.MergeTolerance)
+		result.Merged = merged
+	}
+	if opts.Style != nil {
+		Styles.Apply(nodes, *opts.Style)
+	}
+
+	pack.SetNodes(nodes)
+	if err := pack.Save(outputFile); err != nil {
+		return result, fmt.Errorf("error writing to file %s: %v", outputFile, err)
 	}
 
+	result.NodeCount = len(nodes)
 	log.Println("Merged file created successfully:", outputFile)
+	return result, nil
+}
+
+// MergeSharedSpots finds main nodes that are within tolerance of an earlier
+// main node for the same grenade type and folds them into it. Lineups for other
+// grenades keep their own node, as the stand marker shows its type. The kept
+// node gets a combined title and the aim_target and destination nodes of the
+// folded lineups are pointed at it through MasterNodeId. It returns the
+// remaining nodes and a line per merge.
+func MergeSharedSpots(nodes []Annotation.Node, tolerance float64) ([]Annotation.Node, []string) {
+	var kept []Annotation.Node
+	var merged []string
+	// Id of a folded main node -> Id of the main node it was folded into
+	alias := make(map[string]string)
+	var mains []Annotation.Node
+
+	for _, n := range nodes {
+		if n.SubType() != "main" {
+			continue
+		}
+		var into *Annotation.Node
+		for i := range mains {
+			if mains[i].GrenadeType() == n.GrenadeType() && mains[i].Position().Sub(n.Position()).Length() <= tolerance {
+				into = &mains[i]
+				break
+			}
+		}
+		if into == nil {
+			mains = append(mains, n)
+			continue
+		}
+
+		merged = append(merged, fmt.Sprintf("%s stands with %s", n.TitleText(), into.TitleText()))
+		alias[n.Id()] = into.Id()
+		into.SetTitleText(joinText(into.TitleText(), n.TitleText()))
+		into.SetDescText(joinText(into.DescText(), n.DescText()))
+	}
+
+	for _, n := range nodes {
+		if _, folded := alias[n.Id()]; folded && n.SubType() == "main" {
+			continue
+		}
+		if target, ok := alias[n.MasterNodeId()]; ok {
+			n.Value.Set("MasterNodeId", Annotation.NewString(target))
+		}
+		kept = append(kept, n)
+	}
+	return kept, merged
+}
+
+// dropRepeats keeps the first node with each Id. A lineup picked twice, e.g. on
+// its own and inside a pack, would otherwise repeat its Ids, and its aim_target
+// and destination couldn't tell which main node they belong to. It returns the
+// titles of the repeated main nodes.
+func dropRepeats(nodes []Annotation.Node) ([]Annotation.Node, []string) {
+	var kept []Annotation.Node
+	var repeated []string
+	seen := make(map[string]bool)
+	for _, n := range nodes {
+		if id := n.Id(); id != "" {
+			if seen[id] {
+				if n.SubType() == "main" {
+					repeated = append(repeated, n.TitleText())
+				}
+				continue
+			}
+			seen[id] = true
+		}
+		kept = append(kept, n)
+	}
+	return kept, repeated
+}
+
+// joinText combines two labels, leaving out empty and repeated ones
+func joinText(a, b string) string {
+	if b == "" || strings.Contains(a, b) {
+		return a
+	}
+	if a == "" {
+		return b
+	}
+	return a + " / " + b
 }
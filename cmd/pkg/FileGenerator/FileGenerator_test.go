@@ -1,8 +1,14 @@
 package FileGenerator
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Styles"
 )
 
 // Helper function to create a temporary test file
@@ -39,7 +45,13 @@ func TestFileGenerator(t *testing.T) {
 	defer cleanupOut()
 
 	// Run FileGenerator
-	FileGenerator(outputFile, []string{file1, file2})
+	result, err := FileGenerator(outputFile, []string{file1, file2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.NodeCount != 6 || len(result.Skipped) != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
 
 	// Read the output file
 	outputContent, err := os.ReadFile(outputFile)
@@ -55,3 +67,270 @@ func TestFileGenerator(t *testing.T) {
 		t.Errorf("Unexpected output:\nGot:\n%s\n\nExpected:\n%s", string(outputContent), expectedOutput)
 	}
 }
+
+// Test that unreadable input files are skipped instead of stopping the program
+func TestFileGeneratorSkipsMissingFiles(t *testing.T) {
+	file1, cleanup1 := createTempFile(t, "HeaderContent\nMapAnnotationNode0SomeData}")
+	defer cleanup1()
+
+	outputFile, cleanupOut := createTempFile(t, "")
+	defer cleanupOut()
+
+	missing := file1 + ".missing"
+	result, err := FileGenerator(outputFile, []string{missing, file1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.NodeCount != 1 || len(result.Skipped) != 1 || result.Skipped[0] != missing {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	outputContent, _ := os.ReadFile(outputFile)
+	expectedOutput := "HeaderContent\nMapAnnotationNode0SomeData\n}"
+	if string(outputContent) != expectedOutput {
+		t.Errorf("Unexpected output:\nGot:\n%s\n\nExpected:\n%s", string(outputContent), expectedOutput)
+	}
+
+	// Every input missing is an error
+	if _, err := FileGenerator(outputFile, []string{missing}); err == nil {
+		t.Errorf("expected error when no input file can be read")
+	}
+
+	// So is writing to a folder that doesn't exist
+	if _, err := FileGenerator(filepath.Join(missing, "out.txt"), []string{file1}); err == nil {
+		t.Errorf("expected error for unwritable output")
+	}
+}
+
+func TestNormalizeOutputName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"Top_Bannana_Control", "Top_Bannana_Control.txt", false},
+		{"  Random_A_Site.txt ", "Random_A_Site.txt", false},
+		{"inferno-b.TXT", "inferno-b.txt", false},
+		{"", "", true},
+		{".txt", "", true},
+		{"inferno B exec", "", true},
+		{"pack;quit", "", true},
+		{"pack.cfg", "", true},
+		{"folder/pack", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeOutputName(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("NormalizeOutputName(%q) = %q, %v; want %q, error %v", tt.input, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestFindCollisions(t *testing.T) {
+	tempDir := t.TempDir()
+	os.Mkdir(filepath.Join(tempDir, "Top_Bannana_Control"), 0755)
+
+	if c := FindCollisions(tempDir, "Top_Bannana_Control.txt", nil); len(c) != 1 {
+		t.Errorf("expected folder collision, got %v", c)
+	}
+	if c := FindCollisions(tempDir, "backlogsfire.txt", []string{"T2Camera", "BackLogsFire"}); len(c) != 1 {
+		t.Errorf("expected nade collision, got %v", c)
+	}
+	if c := FindCollisions(tempDir, "New_Pack.txt", []string{"T2Camera"}); len(c) != 0 {
+		t.Errorf("expected no collisions, got %v", c)
+	}
+}
+
+// lineupFile builds a main/aim_target/destination annotation in the game's layout
+func lineupFile(id, title, nadeType string, x float64) string {
+	return fmt.Sprintf(`<!-- kv3 -->
+{
+	MapName = "de_inferno"
+	MapAnnotationNode0 = 
+	{
+		Id = "%[1]s"
+		SubType = "main"
+		Position = [ %[4]g, 0.0, 0.0 ]
+		Title = 
+		{
+			Text = "%[2]s"
+		}
+		Desc = 
+		{
+			Text = "stand on the logs"
+		}
+		GrenadeType = "%[3]s"
+	}
+	MapAnnotationNode1 = 
+	{
+		Id = "%[1]s-aim"
+		SubType = "aim_target"
+		Position = [ 100.0, 0.0, 0.0 ]
+		MasterNodeId = "%[1]s"
+	}
+	MapAnnotationNode2 = 
+	{
+		Id = "%[1]s-dest"
+		SubType = "destination"
+		Position = [ 500.0, 0.0, 0.0 ]
+		MasterNodeId = "%[1]s"
+	}
+}`, id, title, nadeType, x)
+}
+
+func TestFileGeneratorMergeSharedSpots(t *testing.T) {
+	fire, cleanup1 := createTempFile(t, lineupFile("fire", "BackLogsFire", "incendiary", 0))
+	defer cleanup1()
+	molly, cleanup2 := createTempFile(t, lineupFile("molly", "BackLogsMolly", "incendiary", 5))
+	defer cleanup2()
+	he, cleanup3 := createTempFile(t, lineupFile("he", "BackLogsHE", "he", 5))
+	defer cleanup3()
+	far, cleanup4 := createTempFile(t, lineupFile("far", "T2Camera", "smoke", 1000))
+	defer cleanup4()
+
+	outputFile, cleanupOut := createTempFile(t, "")
+	defer cleanupOut()
+
+	result, err := FileGeneratorWithOptions(outputFile, []string{fire, molly, he, far}, Options{MergeTolerance: DefaultMergeTolerance})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 12 nodes in, the molly stand marker is folded into the fire one. The HE
+	// stands there too but keeps its own marker, which shows its grenade.
+	if result.NodeCount != 11 || len(result.Merged) != 1 || result.Merged[0] != "BackLogsMolly stands with BackLogsFire" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	pack, err := Annotation.Load(outputFile)
+	if err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	nodes := pack.Nodes()
+	if nodes[0].TitleText() != "BackLogsFire / BackLogsMolly" || nodes[0].DescText() != "stand on the logs" {
+		t.Errorf("unexpected combined main node: %q %q", nodes[0].TitleText(), nodes[0].DescText())
+	}
+	mains := 0
+	for i, n := range nodes {
+		if n.Key != fmt.Sprintf("MapAnnotationNode%d", i) {
+			t.Errorf("node %d not renumbered: %s", i, n.Key)
+		}
+		if n.SubType() == "main" {
+			mains++
+		}
+		if strings.HasPrefix(n.Id(), "molly-") && n.MasterNodeId() != "fire" {
+			t.Errorf("%s should point at the shared main node, got %s", n.Id(), n.MasterNodeId())
+		}
+		if (strings.HasPrefix(n.Id(), "far-") || strings.HasPrefix(n.Id(), "he-")) && !strings.HasPrefix(n.Id(), n.MasterNodeId()+"-") {
+			t.Errorf("%s should keep its own main node, got %s", n.Id(), n.MasterNodeId())
+		}
+	}
+	if mains != 3 {
+		t.Errorf("expected 3 main nodes, got %d", mains)
+	}
+
+	// No options keeps every node
+	result, err = FileGeneratorWithOptions(outputFile, []string{fire, he}, Options{})
+	if err != nil || result.NodeCount != 6 {
+		t.Errorf("unexpected result without merging: %+v, %v", result, err)
+	}
+}
+
+// A nade picked twice, e.g. on its own and inside a pack, is kept once
+func TestFileGeneratorRepeatedNade(t *testing.T) {
+	fire, cleanup1 := createTempFile(t, lineupFile("fire", "BackLogsFire", "incendiary", 0))
+	defer cleanup1()
+	molly, cleanup2 := createTempFile(t, lineupFile("molly", "BackLogsMolly", "incendiary", 5))
+	defer cleanup2()
+
+	outputFile, cleanupOut := createTempFile(t, "")
+	defer cleanupOut()
+
+	result, err := FileGeneratorWithOptions(outputFile, []string{fire, fire, molly}, Options{MergeTolerance: DefaultMergeTolerance})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The second fire is dropped and the molly stand marker folded into the first
+	if result.NodeCount != 5 || len(result.Merged) != 1 || len(result.Warnings) != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if want := "BackLogsFire is in the pack twice, kept once"; result.Warnings[0] != want {
+		t.Errorf("unexpected warning %q, want %q", result.Warnings[0], want)
+	}
+
+	pack, err := Annotation.Load(outputFile)
+	if err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	ids := make(map[string]int)
+	for _, n := range pack.Nodes() {
+		ids[n.Id()]++
+		if n.MasterNodeId() != "" && n.MasterNodeId() != "fire" {
+			t.Errorf("%s should point at the kept main node, got %s", n.Id(), n.MasterNodeId())
+		}
+	}
+	if ids["fire"] != 1 || ids["fire-aim"] != 1 || ids["fire-dest"] != 1 || ids["molly"] != 0 {
+		t.Errorf("unexpected nodes: %v", ids)
+	}
+}
+
+func TestFileGeneratorStyle(t *testing.T) {
+	fire, cleanup1 := createTempFile(t, lineupFile("fire", "BackLogsFire", "incendiary", 0))
+	defer cleanup1()
+
+	outputFile, cleanupOut := createTempFile(t, "")
+	defer cleanupOut()
+
+	style := Styles.Builtin["colorblind"]
+	if _, err := FileGeneratorWithOptions(outputFile, []string{fire}, Options{Style: &style}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	outputContent, _ := os.ReadFile(outputFile)
+	if !strings.Contains(string(outputContent), "Color = [ 213, 94, 0 ]") {
+		t.Errorf("style not applied:\n%s", outputContent)
+	}
+}
+
+func TestFileGeneratorTeleports(t *testing.T) {
+	fire, cleanup1 := createTempFile(t, lineupFile("fire", "BackLogsFire", "incendiary", 0))
+	defer cleanup1()
+	far, cleanup2 := createTempFile(t, lineupFile("far", "T2; Camera", "smoke", 1000))
+	defer cleanup2()
+
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "BExecute.txt")
+	cfgDir := t.TempDir()
+	result, err := FileGeneratorWithOptions(outputFile, []string{fire, far}, Options{TeleportDir: cfgDir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.TeleportPath != filepath.Join(cfgDir, "BExecute_teleports.cfg") || result.NodeCount != 6 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	data, err := os.ReadFile(result.TeleportPath)
+	if err != nil {
+		t.Fatalf("failed to read teleport config: %v", err)
+	}
+	cfg := string(data)
+	for _, want := range []string{
+		`alias "sb_BExecute_0" "setpos 0 0 0;setang 0 0 0;alias stratbook_next sb_BExecute_1;alias stratbook_prev sb_BExecute_1;echo 1 of 2 - BackLogsFire"`,
+		`alias "sb_BExecute_1" "setpos 1000 0 0;setang 0 0 0;alias stratbook_next sb_BExecute_0;alias stratbook_prev sb_BExecute_0;echo 2 of 2 - T2 Camera"`,
+		`alias "stratbook_next" "sb_BExecute_0"`,
+		`alias "stratbook_prev" "sb_BExecute_1"`,
+		`bind "` + TeleportNextKey + `" "stratbook_next"`,
+		`bind "` + TeleportPrevKey + `" "stratbook_prev"`,
+	} {
+		if !strings.Contains(cfg, want) {
+			t.Errorf("teleport config is missing %q:\n%s", want, cfg)
+		}
+	}
+
+	// Without TeleportDir no config is written
+	result, err = FileGeneratorWithOptions(outputFile, []string{fire}, Options{})
+	if err != nil || result.TeleportPath != "" {
+		t.Errorf("unexpected result: %+v %v", result, err)
+	}
+	if cfg := string(TeleportConfig("Empty", nil)); !strings.Contains(cfg, "No lineups") || strings.Contains(cfg, "bind") {
+		t.Errorf("unexpected config for an empty pack:\n%s", cfg)
+	}
+}
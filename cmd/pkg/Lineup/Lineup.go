@@ -0,0 +1,320 @@
+package Lineup
+
+// Builds a lineup annotation file from the setpos/setang string getpos prints,
+// instead of recording it in game with the annotation tool.
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+)
+
+// GrenadeTypes are the GrenadeType values the game uses
+var GrenadeTypes = []string{"smoke", "flash", "he", "molotov", "incendiary"}
+
+// EyeHeight is how far above setpos, which is the player's feet, a standing player's eyes are
+const EyeHeight = 64.0
+
+// AimDistance is how far in front of the eyes the aim_target node is placed
+const AimDistance = 100.0
+
+// header is the first line of every annotation file the game saves
+const header = "<!-- kv3 encoding:text:version{e21c7f3c-8a33-41c5-9977-a76d3a32aa0d} format:generic:version{7412167c-06e9-4698-aff2-e63eb59037e7} -->"
+
+// Setpos is where a player stands and looks, as printed by getpos
+type Setpos struct {
+	Position Annotation.Vec3
+	Angles   Annotation.Vec3 // pitch, yaw, roll
+}
+
+const number = `(-?\d+(?:\.\d+)?(?:[eE][-+]?\d+)?)`
+
+var (
+	setposRe = regexp.MustCompile(`setpos(?:_exact)?\s+` + number + `\s+` + number + `\s+` + number)
+	setangRe = regexp.MustCompile(`setang(?:_exact)?\s+` + number + `\s+` + number + `(?:\s+` + number + `)?`)
+)
+
+// ParseSetpos reads a "setpos x y z;setang pitch yaw roll" string. The roll is optional.
+func ParseSetpos(s string) (Setpos, error) {
+	var sp Setpos
+	pos := setposRe.FindStringSubmatch(s)
+	if pos == nil {
+		return sp, fmt.Errorf("no setpos x y z in %q", s)
+	}
+	ang := setangRe.FindStringSubmatch(s)
+	if ang == nil {
+		return sp, fmt.Errorf("no setang pitch yaw in %q", s)
+	}
+	for i := 0; i < 3; i++ {
+		sp.Position[i], _ = strconv.ParseFloat(pos[i+1], 64)
+		if ang[i+1] != "" {
+			sp.Angles[i], _ = strconv.ParseFloat(ang[i+1], 64)
+		}
+	}
+	return sp, nil
+}
+
+// ParsePosition reads a position given as "x y z", "x, y, z" or a setpos string
+func ParsePosition(s string) (Annotation.Vec3, error) {
+	var v Annotation.Vec3
+	if m := setposRe.FindStringSubmatch(s); m != nil {
+		for i := range v {
+			v[i], _ = strconv.ParseFloat(m[i+1], 64)
+		}
+		return v, nil
+	}
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' })
+	if len(fields) != 3 {
+		return v, fmt.Errorf("expected x y z, got %q", s)
+	}
+	for i, f := range fields {
+		n, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return v, fmt.Errorf("expected x y z, got %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// Eye is the position of a standing player's eyes
+func (sp Setpos) Eye() Annotation.Vec3 {
+	return sp.Position.Add(Annotation.Vec3{0, 0, EyeHeight})
+}
+
+// Forward is the unit vector the player looks along
+func (sp Setpos) Forward() Annotation.Vec3 {
+	pitch := sp.Angles[0] * math.Pi / 180
+	yaw := sp.Angles[1] * math.Pi / 180
+	return Annotation.Vec3{math.Cos(pitch) * math.Cos(yaw), math.Cos(pitch) * math.Sin(yaw), -math.Sin(pitch)}
+}
+
+// AimPoint is where the aim_target node goes, AimDistance in front of the eyes.
+// It is rounded to the six decimals the game writes.
+func (sp Setpos) AimPoint() Annotation.Vec3 {
+	f := sp.Forward()
+	p := sp.Eye().Add(Annotation.Vec3{f[0] * AimDistance, f[1] * AimDistance, f[2] * AimDistance})
+	for i := range p {
+		p[i] = math.Round(p[i]*1e6) / 1e6
+	}
+	return p
+}
+
+// Clicks for Throw.Click
+const (
+	ClickLeft  = "left"
+	ClickRight = "right"
+	ClickBoth  = "both"
+)
+
+// clickWords is how players write each click in aim text. A left click is
+// the normal throw and goes unsaid.
+var clickWords = map[string]string{
+	ClickRight: "right click",
+	ClickBoth:  "left+right click",
+}
+
+// Throw is how the grenade is thrown
+type Throw struct {
+	Jump   bool
+	Run    bool
+	Walk   bool
+	Crouch bool
+	Click  string // ClickLeft, ClickRight or ClickBoth, "" is a normal left click
+}
+
+// Describe returns the throw in words for the aim text, such as "run + jumpthrow, right click",
+// or "" for a standing left click throw
+func (t Throw) Describe() string {
+	var moves []string
+	if t.Crouch {
+		moves = append(moves, "crouch")
+	}
+	if t.Walk {
+		moves = append(moves, "walk")
+	}
+	if t.Run {
+		moves = append(moves, "run")
+	}
+	if t.Jump {
+		moves = append(moves, "jumpthrow")
+	}
+	text := strings.Join(moves, " + ")
+	if click := clickWords[t.Click]; click != "" {
+		if text != "" {
+			text += ", "
+		}
+		text += click
+	}
+	return text
+}
+
+// Lineup is everything needed to write a lineup file
+type Lineup struct {
+	Name        string // file and folder name
+	MapName     string
+	GrenadeType string
+	Stand       Setpos
+	Landing     Annotation.Vec3
+	Throw       Throw
+	Title       string // shown on the standing and aim nodes, Name when empty
+	StandText   string // how to find the spot
+	AimText     string // where to aim
+}
+
+// Validate checks the lineup can be written and loaded by the game
+func (l Lineup) Validate() error {
+	if strings.TrimSpace(l.Name) == "" {
+		return errors.New("lineup needs a name")
+	}
+	if strings.ContainsAny(l.Name, `/\:*?"<>|`) {
+		return fmt.Errorf("name %q can't be used as a file name", l.Name)
+	}
+	if !Maps.Valid(l.MapName) {
+		return fmt.Errorf("%q is not a valid map name", l.MapName)
+	}
+	valid := false
+	for _, g := range GrenadeTypes {
+		valid = valid || g == l.GrenadeType
+	}
+	if !valid {
+		return fmt.Errorf("grenade type %q must be one of %s", l.GrenadeType, strings.Join(GrenadeTypes, ", "))
+	}
+	switch l.Throw.Click {
+	case "", ClickLeft, ClickRight, ClickBoth:
+	default:
+		return fmt.Errorf("click %q must be %s, %s or %s", l.Throw.Click, ClickLeft, ClickRight, ClickBoth)
+	}
+	return nil
+}
+
+// newID returns a random UUID like the ones the game gives nodes
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// text returns a Title or Desc block
+func text(s string, fontSize int, fadeIn, fadeOut float64) *Annotation.Value {
+	v := Annotation.NewObject()
+	v.Set("Text", Annotation.NewString(s))
+	v.Set("FontSize", Annotation.NewInt(fontSize))
+	v.Set("FadeInDist", Annotation.NewNumber(fadeIn))
+	v.Set("FadeOutDist", Annotation.NewNumber(fadeOut))
+	v.Set("ShowBackground", Annotation.NewBool(true))
+	return v
+}
+
+// node returns the fields every node starts with
+func node(id, subType string, pos, angles Annotation.Vec3) *Annotation.Value {
+	v := Annotation.NewObject()
+	v.Set("Enabled", Annotation.NewBool(true))
+	v.Set("Type", Annotation.NewString("grenade"))
+	v.Set("Id", Annotation.NewString(id))
+	v.Set("SubType", Annotation.NewString(subType))
+	v.Set("Position", Annotation.NewVec3(pos))
+	v.Set("Angles", Annotation.NewVec3(angles))
+	v.Set("VisiblePfx", Annotation.NewBool(true))
+	return v
+}
+
+// Build returns the main, aim_target and destination nodes of the lineup, laid
+// out like a file saved by the game, with fresh Ids
+func Build(l Lineup) (*Annotation.File, error) {
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	title := l.Title
+	if title == "" {
+		title = l.Name
+	}
+	aimText := l.AimText
+	if throw := l.Throw.Describe(); throw != "" {
+		if aimText != "" {
+			aimText += "; "
+		}
+		aimText += throw
+	}
+	look := Annotation.Vec3{l.Stand.Angles[0], l.Stand.Angles[1], 0}
+	mainID := newID()
+
+	main := node(mainID, "main", l.Stand.Position, Annotation.Vec3{0, l.Stand.Angles[1], 0})
+	main.Set("Color", &Annotation.Value{Kind: Annotation.Array, Items: []*Annotation.Value{
+		Annotation.NewInt(255), Annotation.NewInt(255), Annotation.NewInt(255),
+	}})
+	main.Set("TextPositionOffset", Annotation.NewVec3(Annotation.Vec3{0, 0, 65}))
+	main.Set("TextFacePlayer", Annotation.NewBool(true))
+	main.Set("TextHorizontalAlign", Annotation.NewString("center"))
+	main.Set("RevealOnSuccess", Annotation.NewBool(false))
+	main.Set("Title", text(title, 125, 600, 40))
+	main.Set("Desc", text(l.StandText, 75, 300, 40))
+	main.Set("StreakLimitGuidesOn", Annotation.NewInt(2))
+	main.Set("StreakLimitGuidesOff", Annotation.NewInt(2))
+	main.Set("JumpThrow", Annotation.NewBool(l.Throw.Jump))
+	main.Set("GrenadeType", Annotation.NewString(l.GrenadeType))
+
+	aim := node(newID(), "aim_target", l.Stand.AimPoint(), look)
+	aim.Set("TextPositionOffset", Annotation.NewVec3(Annotation.Vec3{0, 15, 15}))
+	aim.Set("TextFacePlayer", Annotation.NewBool(false))
+	aim.Set("TextHorizontalAlign", Annotation.NewString("center"))
+	aim.Set("RevealOnSuccess", Annotation.NewBool(false))
+	aim.Set("Title", text(title, 125, 50, -1))
+	aim.Set("Desc", text(aimText, 75, 50, -1))
+	aim.Set("MasterNodeId", Annotation.NewString(mainID))
+
+	dest := node(newID(), "destination", l.Landing, look)
+	dest.Set("TextPositionOffset", Annotation.NewVec3(Annotation.Vec3{}))
+	dest.Set("TextFacePlayer", Annotation.NewBool(false))
+	dest.Set("TextHorizontalAlign", Annotation.NewString("center"))
+	dest.Set("RevealOnSuccess", Annotation.NewBool(false))
+	dest.Set("Title", text("", 75, 50, -1))
+	dest.Set("Desc", text("", 75, 50, -1))
+	dest.Set("MasterNodeId", Annotation.NewString(mainID))
+	dest.Set("DistanceThreshold", Annotation.NewNumber(80))
+
+	root := Annotation.NewObject()
+	root.Set("MapName", Annotation.NewString(l.MapName))
+	root.Set("WorkshopSubmissionID", Annotation.NewString(""))
+	root.Set("ScreenText", Annotation.NewObject())
+	f := &Annotation.File{Header: header, Root: root}
+	f.SetNodes([]Annotation.Node{{Value: main}, {Value: aim}, {Value: dest}})
+	return f, nil
+}
+
+// Path is where Write puts a lineup: <annotationPath>/local/<Name>/<Name>.txt
+func Path(annotationPath, name string) string {
+	return filepath.Join(annotationPath, "local", name, name+".txt")
+}
+
+// Write builds the lineup and saves it to Path. An existing file is never replaced.
+func Write(annotationPath string, l Lineup) (string, error) {
+	f, err := Build(l)
+	if err != nil {
+		return "", err
+	}
+	path := Path(annotationPath, l.Name)
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := f.Save(path); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,147 @@
+package Lineup
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Lint"
+)
+
+func TestParseSetpos(t *testing.T) {
+	sp, err := ParseSetpos("setpos 882.904114 709.498657 97.205231;setang -9.730464 -50.834412 0.000000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sp.Position != (Annotation.Vec3{882.904114, 709.498657, 97.205231}) || sp.Angles != (Annotation.Vec3{-9.730464, -50.834412, 0}) {
+		t.Errorf("unexpected setpos: %+v", sp)
+	}
+	if sp, err := ParseSetpos("setpos_exact 1 2 3; setang_exact 4 5"); err != nil || sp.Angles != (Annotation.Vec3{4, 5, 0}) {
+		t.Errorf("unexpected setpos_exact: %+v %v", sp, err)
+	}
+	for _, bad := range []string{"", "setpos 1 2 3", "setang 1 2 3", "setpos 1 2;setang 1 2"} {
+		if _, err := ParseSetpos(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+
+	for _, s := range []string{"1323.7 633.2 393.1", "1323.7, 633.2, 393.1", "setpos 1323.7 633.2 393.1;setang 0 0 0"} {
+		if v, err := ParsePosition(s); err != nil || v != (Annotation.Vec3{1323.7, 633.2, 393.1}) {
+			t.Errorf("unexpected position for %q: %v %v", s, v, err)
+		}
+	}
+	if _, err := ParsePosition("1 2"); err == nil {
+		t.Errorf("expected error for two numbers")
+	}
+}
+
+func TestAimPoint(t *testing.T) {
+	// Looking straight along +x from the origin
+	sp := Setpos{}
+	if p := sp.AimPoint(); p != (Annotation.Vec3{AimDistance, 0, EyeHeight}) {
+		t.Errorf("unexpected aim point %v", p)
+	}
+	// Looking down 90 degrees
+	sp.Angles = Annotation.Vec3{90, 0, 0}
+	if p := sp.AimPoint(); math.Abs(p[0]) > 1e-6 || p[2] != EyeHeight-AimDistance {
+		t.Errorf("unexpected aim point looking down %v", p)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	for throw, want := range map[Throw]string{
+		{}:                               "",
+		{Click: ClickLeft}:               "",
+		{Click: ClickRight}:              "right click",
+		{Click: ClickBoth}:               "left+right click",
+		{Run: true, Jump: true}:          "run + jumpthrow",
+		{Jump: true, Click: ClickBoth}:   "jumpthrow, left+right click",
+		{Crouch: true, Click: ClickLeft}: "crouch",
+	} {
+		if got := throw.Describe(); got != want {
+			t.Errorf("%+v described as %q, want %q", throw, got, want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	stand, _ := ParseSetpos("setpos 882.904114 709.498657 97.205231;setang -9.730464 -50.834412 0.000000")
+	l := Lineup{
+		Name:        "BracketFlash",
+		MapName:     "de_inferno",
+		GrenadeType: "flash",
+		Stand:       stand,
+		Landing:     Annotation.Vec3{1323.73877, 633.216675, 393.128571},
+		Throw:       Throw{Jump: true, Click: ClickRight},
+		StandText:   "Align where the two boards meet",
+		AimText:     "Aim at top of wire",
+	}
+
+	dir := t.TempDir()
+	path, err := Write(dir, l)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(dir, "local", "BracketFlash", "BracketFlash.txt") {
+		t.Errorf("unexpected path %s", path)
+	}
+	if findings := Lint.LintFile(path, Lint.Config{}); len(findings) != 0 {
+		t.Errorf("written lineup has lint findings: %v", findings)
+	}
+
+	f, err := Annotation.Load(path)
+	if err != nil {
+		t.Fatalf("written lineup doesn't parse: %v", err)
+	}
+	nodes := f.Nodes()
+	if len(nodes) != 3 || nodes[0].SubType() != "main" || nodes[1].SubType() != "aim_target" || nodes[2].SubType() != "destination" {
+		t.Fatalf("unexpected nodes: %v", nodes)
+	}
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuid.MatchString(nodes[0].Id()) || nodes[0].Id() == nodes[1].Id() {
+		t.Errorf("unexpected ids %q %q", nodes[0].Id(), nodes[1].Id())
+	}
+	if nodes[1].MasterNodeId() != nodes[0].Id() || nodes[2].MasterNodeId() != nodes[0].Id() {
+		t.Errorf("aim_target and destination must point at the main node")
+	}
+	if nodes[0].Position() != stand.Position || nodes[0].Angles() != (Annotation.Vec3{0, -50.834412, 0}) {
+		t.Errorf("unexpected main node %v %v", nodes[0].Position(), nodes[0].Angles())
+	}
+	// The game's own BracketFlash aim node is at [945.151917, 633.081665, 176.932602]
+	if d := nodes[1].Position().Sub(Annotation.Vec3{945.151917, 633.081665, 176.932602}).Length(); d > 2 {
+		t.Errorf("aim_target %v is %.1f units from where the game put it", nodes[1].Position(), d)
+	}
+	if nodes[2].Position() != l.Landing {
+		t.Errorf("unexpected destination %v", nodes[2].Position())
+	}
+	if nodes[0].TitleText() != "BracketFlash" || nodes[1].DescText() != "Aim at top of wire; jumpthrow, right click" {
+		t.Errorf("unexpected text %q %q", nodes[0].TitleText(), nodes[1].DescText())
+	}
+	if !nodes[0].Value.Get("JumpThrow").Bool || nodes[0].GrenadeType() != "flash" || f.MapName() != "de_inferno" {
+		t.Errorf("unexpected main node fields")
+	}
+
+	if _, err := Write(dir, l); err == nil {
+		t.Errorf("expected error when the lineup already exists")
+	}
+	data, _ := os.ReadFile(path)
+	if len(data) == 0 {
+		t.Errorf("existing lineup was overwritten")
+	}
+
+	bad := []Lineup{
+		{Name: "", MapName: "de_inferno", GrenadeType: "flash"},
+		{Name: "a/b", MapName: "de_inferno", GrenadeType: "flash"},
+		{Name: "x", MapName: "de inferno", GrenadeType: "flash"},
+		{Name: "x", MapName: "de_inferno", GrenadeType: "decoy"},
+		{Name: "x", MapName: "de_inferno", GrenadeType: "flash", Throw: Throw{Click: "middle"}},
+	}
+	for _, b := range bad {
+		if _, err := Build(b); err == nil {
+			t.Errorf("expected error for %+v", b)
+		}
+	}
+}
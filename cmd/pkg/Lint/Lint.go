@@ -0,0 +1,342 @@
+package Lint
+
+// Finds problems in annotation files that would otherwise only show up in game.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+)
+
+// Severity of a finding
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Rule is one check. Every rule except parse-error can be turned off.
+type Rule struct {
+	ID          string
+	Description string
+	Severity    Severity
+	check       func(f *Annotation.File) []string
+}
+
+// Finding is a problem found by a rule
+type Finding struct {
+	RuleID   string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	Message  string   `json:"message"`
+}
+
+// Report is the result of linting a set of files
+type Report struct {
+	Files    int       `json:"files"`
+	Findings []Finding `json:"findings"`
+}
+
+// Config turns rules off by ID
+type Config struct {
+	Disabled map[string]bool
+}
+
+// ParseErrorRule is reported when a file can't be read as KV3
+const ParseErrorRule = "parse-error"
+
+// Rules are every check the linter runs, in report order
+var Rules = []Rule{
+	{"missing-grenade-type", "main nodes must have a GrenadeType", Error, checkGrenadeType},
+	{"map-name", "MapName must be set to a map name such as de_inferno, cs_office or a workshop map", Error, checkMapName},
+	{"empty-title", "main and aim_target nodes should have a title", Warning, checkEmptyTitle},
+	{"disabled-node", "nodes with Enabled = false are not shown in game", Warning, checkDisabled},
+	{"duplicate-id", "node Ids must be unique within a file", Error, checkDuplicateIds},
+	{"orphan-node", "aim_target and destination nodes must point at a main node in the file", Error, checkOrphans},
+	{"destination-without-aim-target", "a lineup with a destination needs an aim_target", Error, checkDestinations},
+}
+
+// RuleIDs returns the ID of every rule that can be turned off
+func RuleIDs() []string {
+	var ids []string
+	for _, r := range Rules {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func checkGrenadeType(f *Annotation.File) []string {
+	var problems []string
+	for _, n := range f.Nodes() {
+		if n.SubType() == "main" && n.GrenadeType() == "" {
+			problems = append(problems, fmt.Sprintf("%s (%s) has no GrenadeType", n.Key, n.TitleText()))
+		}
+	}
+	return problems
+}
+
+func checkMapName(f *Annotation.File) []string {
+	name := f.MapName()
+	if name == "" {
+		return []string{"MapName is missing"}
+	}
+	if !Maps.Valid(name) {
+		return []string{fmt.Sprintf("MapName %q is not a valid map name", name)}
+	}
+	return nil
+}
+
+func checkEmptyTitle(f *Annotation.File) []string {
+	var problems []string
+	for _, n := range f.Nodes() {
+		if (n.SubType() == "main" || n.SubType() == "aim_target") && strings.TrimSpace(n.TitleText()) == "" {
+			problems = append(problems, fmt.Sprintf("%s (%s) has an empty title", n.Key, n.SubType()))
+		}
+	}
+	return problems
+}
+
+func checkDisabled(f *Annotation.File) []string {
+	var problems []string
+	for _, n := range f.Nodes() {
+		if enabled := n.Value.Get("Enabled"); enabled != nil && enabled.Kind == Annotation.Bool && !enabled.Bool {
+			problems = append(problems, fmt.Sprintf("%s (%s) is disabled", n.Key, n.TitleText()))
+		}
+	}
+	return problems
+}
+
+func checkDuplicateIds(f *Annotation.File) []string {
+	var problems []string
+	seen := make(map[string]string)
+	for _, n := range f.Nodes() {
+		id := n.Id()
+		if id == "" {
+			problems = append(problems, fmt.Sprintf("%s has no Id", n.Key))
+			continue
+		}
+		if first, ok := seen[id]; ok {
+			problems = append(problems, fmt.Sprintf("%s uses Id %s already used by %s", n.Key, id, first))
+			continue
+		}
+		seen[id] = n.Key
+	}
+	return problems
+}
+
+func checkOrphans(f *Annotation.File) []string {
+	mains := make(map[string]bool)
+	for _, n := range f.Nodes() {
+		if n.SubType() == "main" {
+			mains[n.Id()] = true
+		}
+	}
+	var problems []string
+	for _, n := range f.Nodes() {
+		if n.SubType() != "aim_target" && n.SubType() != "destination" {
+			continue
+		}
+		if !mains[n.MasterNodeId()] {
+			problems = append(problems, fmt.Sprintf("%s (%s) points at missing main node %q", n.Key, n.SubType(), n.MasterNodeId()))
+		}
+	}
+	return problems
+}
+
+func checkDestinations(f *Annotation.File) []string {
+	aims := make(map[string]bool)
+	for _, n := range f.Nodes() {
+		if n.SubType() == "aim_target" {
+			aims[n.MasterNodeId()] = true
+		}
+	}
+	var problems []string
+	for _, n := range f.Nodes() {
+		if n.SubType() == "destination" && !aims[n.MasterNodeId()] {
+			problems = append(problems, fmt.Sprintf("%s is a destination with no aim_target", n.Key))
+		}
+	}
+	return problems
+}
+
+// LintFile runs the enabled rules over one annotation file
+func LintFile(path string, cfg Config) []Finding {
+	f, err := Annotation.Load(path)
+	if err != nil {
+		return []Finding{{RuleID: ParseErrorRule, Severity: Error, File: path, Message: err.Error()}}
+	}
+
+	var findings []Finding
+	for _, r := range Rules {
+		if cfg.Disabled[r.ID] {
+			continue
+		}
+		for _, msg := range r.check(f) {
+			findings = append(findings, Finding{RuleID: r.ID, Severity: r.Severity, File: path, Message: msg})
+		}
+	}
+	return findings
+}
+
+// Run lints every .txt file under root
+func Run(root string, cfg Config) (Report, error) {
+	var report Report
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(path), ".txt") {
+			return nil
+		}
+		report.Files++
+		report.Findings = append(report.Findings, LintFile(path, cfg)...)
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("error walking the path %s: %v", root, err)
+	}
+	return report, nil
+}
+
+// HasErrors reports whether any finding is an error
+func (r Report) HasErrors() bool {
+	for _, f := range r.Findings {
+		if f.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of errors and warnings
+func (r Report) Count() (errors, warnings int) {
+	for _, f := range r.Findings {
+		if f.Severity == Error {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return errors, warnings
+}
+
+// String describes the finding on one line
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s [%s]", f.File, f.Severity, f.Message, f.RuleID)
+}
+
+// WriteText writes one line per finding and a summary
+func WriteText(w io.Writer, r Report) error {
+	for _, f := range r.Findings {
+		if _, err := fmt.Fprintln(w, f.String()); err != nil {
+			return err
+		}
+	}
+	errs, warns := r.Count()
+	_, err := fmt.Fprintf(w, "%d file(s) checked, %d error(s), %d warning(s)\n", r.Files, errs, warns)
+	return err
+}
+
+// WriteJSON writes the report as JSON
+func WriteJSON(w io.Writer, r Report) error {
+	if r.Findings == nil {
+		r.Findings = []Finding{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// SARIF 2.1.0, just the parts code scanning tools need
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+}
+
+// WriteSARIF writes the report in the SARIF format used by code scanning tools
+func WriteSARIF(w io.Writer, r Report) error {
+	driver := sarifDriver{Name: "CS-StratBook lint"}
+	driver.Rules = append(driver.Rules, sarifRule{ParseErrorRule, sarifMessage{"annotation files must be valid KV3"}})
+	for _, rule := range Rules {
+		driver.Rules = append(driver.Rules, sarifRule{rule.ID, sarifMessage{rule.Description}})
+	}
+
+	run := sarifRun{Tool: sarifTool{Driver: driver}, Results: []sarifResult{}}
+	for _, f := range r.Findings {
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(f.File)
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    f.RuleID,
+			Level:     string(f.Severity),
+			Message:   sarifMessage{f.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// Write writes the report in the named format: text, json or sarif
+func Write(w io.Writer, r Report, format string) error {
+	switch format {
+	case "", "text":
+		return WriteText(w, r)
+	case "json":
+		return WriteJSON(w, r)
+	case "sarif":
+		return WriteSARIF(w, r)
+	}
+	return fmt.Errorf("unknown report format %q, use text, json or sarif", format)
+}
@@ -0,0 +1,184 @@
+package Lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const goodFile = `{
+	MapName = "de_inferno"
+	MapAnnotationNode0 =
+	{
+		Enabled = true
+		Id = "main-1"
+		SubType = "main"
+		Title =
+		{
+			Text = "BackLogsFire"
+		}
+		GrenadeType = "incendiary"
+	}
+	MapAnnotationNode1 =
+	{
+		Enabled = true
+		Id = "aim-1"
+		SubType = "aim_target"
+		Title =
+		{
+			Text = "BackLogsFire"
+		}
+		MasterNodeId = "main-1"
+	}
+	MapAnnotationNode2 =
+	{
+		Enabled = true
+		Id = "dest-1"
+		SubType = "destination"
+		MasterNodeId = "main-1"
+	}
+}`
+
+// Every rule fails at least once in this file
+const badFile = `{
+	MapName = "de inferno"
+	MapAnnotationNode0 =
+	{
+		Enabled = false
+		Id = "main-1"
+		SubType = "main"
+		Title =
+		{
+			Text = ""
+		}
+	}
+	MapAnnotationNode1 =
+	{
+		Enabled = true
+		Id = "main-1"
+		SubType = "destination"
+		MasterNodeId = "main-1"
+	}
+	MapAnnotationNode2 =
+	{
+		Enabled = true
+		Id = "dest-2"
+		SubType = "destination"
+		MasterNodeId = "gone"
+	}
+}`
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"BackLogsFire/BackLogsFire.txt":  goodFile,
+		"BackLogsFire/BackLogsFire.json": "{}",
+		"Broken/Broken.txt":              badFile,
+		"Garbage/Garbage.txt":            "{ not kv3",
+	})
+
+	report, err := Run(dir, Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Files != 3 || !report.HasErrors() {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	byRule := make(map[string]int)
+	for _, f := range report.Findings {
+		if strings.Contains(f.File, "BackLogsFire") {
+			t.Errorf("good file should have no findings: %v", f)
+		}
+		byRule[f.RuleID]++
+	}
+	for _, id := range append(RuleIDs(), ParseErrorRule) {
+		if byRule[id] == 0 {
+			t.Errorf("rule %s found nothing", id)
+		}
+	}
+
+	// Turning rules off drops their findings
+	disabled := make(map[string]bool)
+	for _, id := range RuleIDs() {
+		disabled[id] = true
+	}
+	report, _ = Run(dir, Config{Disabled: disabled})
+	if len(report.Findings) != 1 || report.Findings[0].RuleID != ParseErrorRule {
+		t.Errorf("expected only the parse error, got %v", report.Findings)
+	}
+
+	// Warnings alone don't count as errors
+	warnOnly := Report{Findings: []Finding{{Severity: Warning}}}
+	if warnOnly.HasErrors() {
+		t.Errorf("warnings should not be errors")
+	}
+
+	if _, err := Run(filepath.Join(dir, "non_existent"), Config{}); err == nil {
+		t.Errorf("expected error for invalid directory")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	report := Report{Files: 1, Findings: []Finding{
+		{RuleID: "map-name", Severity: Error, File: "a/b.txt", Message: "MapName is missing"},
+		{RuleID: "empty-title", Severity: Warning, File: "a/b.txt", Message: "MapAnnotationNode0 (main) has an empty title"},
+	}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, report, "text"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "a/b.txt: error: MapName is missing [map-name]") ||
+		!strings.Contains(buf.String(), "1 file(s) checked, 1 error(s), 1 warning(s)") {
+		t.Errorf("unexpected text report:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, report, "json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Findings) != 2 {
+		t.Errorf("unexpected json report: %v\n%s", err, buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, report, "sarif"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var sarif struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID string `json:"ruleId"`
+				Level  string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &sarif); err != nil {
+		t.Fatalf("invalid sarif: %v", err)
+	}
+	if sarif.Version != "2.1.0" || len(sarif.Runs) != 1 || len(sarif.Runs[0].Results) != 2 || sarif.Runs[0].Results[1].Level != "warning" {
+		t.Errorf("unexpected sarif report:\n%s", buf.String())
+	}
+
+	if err := Write(&buf, report, "xml"); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
@@ -0,0 +1,338 @@
+package Maps
+
+// Registry of the maps nades can be recorded on, and how to name the ones it doesn't know.
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Map is one known map. Each map is a JSON file. The ones in data/ are built in,
+// and LoadDir adds or replaces maps from a folder, so adding a map or a callout
+// doesn't need a rebuild.
+type Map struct {
+	Name        string    `json:"name"` // MapName as written in annotation files, e.g. de_inferno
+	DisplayName string    `json:"display_name"`
+	Overview    *Overview `json:"overview,omitempty"`
+	Sites       []Area    `json:"sites"`           // where nades land: bomb sites, Mid, hostages...
+	Zones       []Area    `json:"zones,omitempty"` // the rest of the map, e.g. spawns
+	// Placeholder marks outlines that are rough guesses, not traced from the
+	// radar, so suggestions from them need checking
+	Placeholder bool `json:"placeholder,omitempty"`
+}
+
+// Overview is the radar calibration from the game's resource/overviews/<map>.txt.
+// The radar image is 1024 pixels square with pos_x, pos_y at the top left corner
+// and scale world units per pixel.
+type Overview struct {
+	PosX  float64 `json:"pos_x"`
+	PosY  float64 `json:"pos_y"`
+	Scale float64 `json:"scale"`
+}
+
+// Area is a site or zone and the callouts inside it
+type Area struct {
+	Name     string    `json:"name"`
+	Side     string    `json:"side,omitempty"` // T or CT if only that side starts here, e.g. a spawn
+	Polygon  Polygon   `json:"area,omitempty"`
+	Callouts []Callout `json:"callouts,omitempty"`
+}
+
+// Callout is a named spot on a map, e.g. Banana or Top Mid
+type Callout struct {
+	Name    string  `json:"name"`
+	Polygon Polygon `json:"area,omitempty"`
+}
+
+// Polygon is an outline in world x/y coordinates, as in setpos
+type Polygon [][2]float64
+
+// Location is where a point is on a map
+type Location struct {
+	Area    string // site or zone
+	Callout string
+	Side    string
+	InSite  bool // Area is one of the map's sites
+}
+
+// Game modes, taken from the map name prefix
+const (
+	Defuse    = "defuse"
+	Hostage   = "hostage"
+	ArmsRace  = "arms race"
+	OtherMode = ""
+)
+
+// DefaultSites are used for maps that aren't in the registry
+var DefaultSites = []string{"A", "B", "Mid"}
+
+//go:embed data/*.json
+var dataFS embed.FS
+
+// Registry holds the known maps by MapName: the ones in data/ and any loaded by LoadDir
+var Registry = mustLoad(dataFS)
+
+// load reads every map file in fsys matching pattern
+func load(fsys fs.FS, pattern string) (map[string]Map, error) {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	registry := make(map[string]Map)
+	for _, file := range files {
+		m, err := readMap(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		registry[strings.ToLower(m.Name)] = m
+	}
+	return registry, nil
+}
+
+// readMap reads and checks one map file
+func readMap(fsys fs.FS, file string) (Map, error) {
+	var m Map
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return m, fmt.Errorf("failed to read %s: %v", file, err)
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("failed to unmarshal %s: %v", file, err)
+	}
+	if m.Name == "" || m.DisplayName == "" {
+		return m, fmt.Errorf("%s needs a name and display_name", file)
+	}
+	if m.Overview != nil && m.Overview.Scale <= 0 {
+		return m, fmt.Errorf("%s has an overview scale of %v", file, m.Overview.Scale)
+	}
+	return m, nil
+}
+
+// The data is built in, so a bad file is a bug and should stop the program
+func mustLoad(fsys fs.FS) map[string]Map {
+	registry, err := load(fsys, "data/*.json")
+	if err != nil {
+		panic(fmt.Sprintf("map registry: %v", err))
+	}
+	return registry
+}
+
+// LoadDir adds every *.json map file in dir to the Registry, replacing the
+// built in map of the same name. A missing folder is not an error. Bad files
+// are skipped and their errors returned together. Call it before the registry
+// is used, as it isn't safe alongside readers.
+func LoadDir(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	fsys := os.DirFS(dir)
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, file := range files {
+		m, err := readMap(fsys, file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", dir, err))
+			continue
+		}
+		Registry[strings.ToLower(m.Name)] = m
+	}
+	return errors.Join(errs...)
+}
+
+// Workshop maps can be saved under their workshop folder, e.g. workshop/3070284539/de_foo
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+(/[A-Za-z0-9_-]+)*$`)
+
+// Valid reports whether name can be a MapName. Maps that aren't in the registry are allowed.
+func Valid(name string) bool {
+	return validName.MatchString(name)
+}
+
+// Lookup returns the registry entry for name
+func Lookup(name string) (Map, bool) {
+	m, ok := Registry[strings.ToLower(name)]
+	return m, ok
+}
+
+// Names returns every map in the registry, sorted
+func Names() []string {
+	var names []string
+	for name := range Registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Mode returns the game mode of the map from its prefix
+func Mode(name string) string {
+	switch base := strings.ToLower(path.Base(name)); {
+	case strings.HasPrefix(base, "de_"):
+		return Defuse
+	case strings.HasPrefix(base, "cs_"):
+		return Hostage
+	case strings.HasPrefix(base, "ar_"):
+		return ArmsRace
+	}
+	return OtherMode
+}
+
+// Key tells apart the maps nades are thrown on. A workshop map can share its
+// MapName with an official map or another workshop map, so the workshop ID is part of it.
+type Key struct {
+	Name       string
+	WorkshopID string
+}
+
+// DisplayName returns the name to show for the map
+func (k Key) DisplayName() string {
+	return DisplayName(k.Name, k.WorkshopID)
+}
+
+// DisplayName returns the name to show for a map. Maps not in the registry are
+// named from their MapName, with the workshop ID added if there is one.
+func DisplayName(name, workshopID string) string {
+	if m, ok := Lookup(name); ok && workshopID == "" {
+		return m.DisplayName
+	}
+
+	base := path.Base(name)
+	for _, prefix := range []string{"de_", "cs_", "ar_"} {
+		base = strings.TrimPrefix(base, prefix)
+	}
+	words := strings.Fields(strings.ReplaceAll(base, "_", " "))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	display := strings.Join(words, " ")
+	if display == "" {
+		display = name
+	}
+	if workshopID != "" {
+		display += " (Workshop " + workshopID + ")"
+	}
+	return display
+}
+
+// Sites returns the site names of a map, or DefaultSites if it isn't in the registry
+func Sites(name string) []string {
+	m, ok := Lookup(name)
+	if !ok {
+		return DefaultSites
+	}
+	var sites []string
+	for _, a := range m.Sites {
+		sites = append(sites, a.Name)
+	}
+	return sites
+}
+
+// Callouts returns every callout of a map, sites first, in file order
+func Callouts(name string) []string {
+	m, _ := Lookup(name)
+	var callouts []string
+	for _, a := range append(append([]Area(nil), m.Sites...), m.Zones...) {
+		for _, c := range a.Callouts {
+			callouts = append(callouts, c.Name)
+		}
+	}
+	return callouts
+}
+
+// SiteOf returns the site or zone a callout is in
+func SiteOf(name, callout string) string {
+	m, _ := Lookup(name)
+	for _, a := range append(append([]Area(nil), m.Sites...), m.Zones...) {
+		for _, c := range a.Callouts {
+			if strings.EqualFold(c.Name, callout) {
+				return a.Name
+			}
+		}
+	}
+	return ""
+}
+
+// ValidSite reports whether site is empty or one of the map's sites
+func ValidSite(name, site string) bool {
+	return site == "" || contains(Sites(name), site)
+}
+
+// ValidCallout reports whether callout is empty or one of the map's callouts.
+// Maps that aren't in the registry take any callout.
+func ValidCallout(name, callout string) bool {
+	if _, ok := Lookup(name); !ok {
+		return true
+	}
+	return callout == "" || contains(Callouts(name), callout)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether x, y is inside the polygon
+func (p Polygon) Contains(x, y float64) bool {
+	inside := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		xi, yi, xj, yj := p[i][0], p[i][1], p[j][0], p[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// size is the area of the polygon
+func (p Polygon) size() float64 {
+	var sum float64
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		sum += p[j][0]*p[i][1] - p[i][0]*p[j][1]
+	}
+	return math.Abs(sum) / 2
+}
+
+// Locate finds the callout and the site or zone that x, y is in. Outlines can
+// overlap; the smallest one containing the point wins. The Location is empty
+// if the map has no outlines there.
+func Locate(name string, x, y float64) Location {
+	m, _ := Lookup(name)
+
+	var loc Location
+	best := math.Inf(1)
+	check := func(p Polygon, l Location) {
+		if len(p) >= 3 && p.Contains(x, y) && p.size() < best {
+			best = p.size()
+			loc = l
+		}
+	}
+
+	// A callout outline beats any site or zone outline
+	for i, a := range append(append([]Area(nil), m.Sites...), m.Zones...) {
+		for _, c := range a.Callouts {
+			check(c.Polygon, Location{Area: a.Name, Callout: c.Name, Side: a.Side, InSite: i < len(m.Sites)})
+		}
+	}
+	if loc.Area != "" {
+		return loc
+	}
+	for i, a := range append(append([]Area(nil), m.Sites...), m.Zones...) {
+		check(a.Polygon, Location{Area: a.Name, Side: a.Side, InSite: i < len(m.Sites)})
+	}
+	return loc
+}
@@ -0,0 +1,165 @@
+package Maps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDisplayName(t *testing.T) {
+	tests := []struct {
+		name, workshopID, want string
+	}{
+		{"de_dust2", "", "Dust II"},
+		{"DE_INFERNO", "", "Inferno"},
+		{"cs_office", "", "Office"},
+		{"ar_pool_day", "", "Pool Day"},
+		{"de_cool_map", "", "Cool Map"},
+		{"de_cool_map", "3070284539", "Cool Map (Workshop 3070284539)"},
+		{"workshop/3070284539/de_cool_map", "3070284539", "Cool Map (Workshop 3070284539)"},
+		{"surf_", "", "Surf"},
+		{"de_", "", "de_"},
+	}
+	for _, tt := range tests {
+		if got := DisplayName(tt.name, tt.workshopID); got != tt.want {
+			t.Errorf("DisplayName(%q, %q) = %q, want %q", tt.name, tt.workshopID, got, tt.want)
+		}
+	}
+}
+
+func TestValidAndMode(t *testing.T) {
+	for _, name := range []string{"de_inferno", "cs_italy", "ar_shoots", "aim_map", "workshop/3070284539/de_cool_map"} {
+		if !Valid(name) {
+			t.Errorf("expected %q to be valid", name)
+		}
+	}
+	for _, name := range []string{"", "de inferno", "de_inferno\"", "/de_inferno", "workshop//de_x"} {
+		if Valid(name) {
+			t.Errorf("expected %q to be invalid", name)
+		}
+	}
+
+	modes := map[string]string{
+		"de_nuke":                Defuse,
+		"cs_office":              Hostage,
+		"ar_baggage":             ArmsRace,
+		"aim_map":                OtherMode,
+		"workshop/1/cs_cool_map": Hostage,
+	}
+	for name, want := range modes {
+		if got := Mode(name); got != want {
+			t.Errorf("Mode(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	for _, name := range Names() {
+		if m, ok := Lookup(name); !ok || m.Name != name || !Valid(name) {
+			t.Errorf("bad registry entry %q: %+v", name, m)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	if len(Registry) == 0 {
+		t.Fatal("registry is empty")
+	}
+	for name, m := range Registry {
+		seen := make(map[string]bool)
+		for _, c := range Callouts(name) {
+			if seen[c] {
+				t.Errorf("%s: callout %q is listed twice", name, c)
+			}
+			seen[c] = true
+		}
+		if m.Name != name {
+			t.Errorf("%s is stored under %s", m.Name, name)
+		}
+	}
+
+	if sites := Sites("de_inferno"); len(sites) != 3 || sites[2] != "Mid" {
+		t.Errorf("unexpected inferno sites: %v", sites)
+	}
+	if sites := Sites("de_cool_map"); len(sites) != len(DefaultSites) {
+		t.Errorf("unknown maps should use the default sites, got %v", sites)
+	}
+	if SiteOf("de_inferno", "banana") != "B" || SiteOf("de_inferno", "Apartments") != "A" || SiteOf("de_inferno", "T Ramp") != "T Spawn" || SiteOf("de_inferno", "Palace") != "" {
+		t.Errorf("unexpected SiteOf results")
+	}
+	if !ValidSite("de_nuke", "Outside") || ValidSite("de_nuke", "Mid") || !ValidSite("de_nuke", "") {
+		t.Errorf("unexpected ValidSite results")
+	}
+	if !ValidCallout("de_mirage", "Apps") || ValidCallout("de_mirage", "Banana") || !ValidCallout("de_cool_map", "Anything") {
+		t.Errorf("unexpected ValidCallout results")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	good := fstest.MapFS{"data/de_x.json": {Data: []byte(`{"name": "de_x", "display_name": "X", "sites": [{"name": "A", "callouts": [{"name": "Pit"}]}]}`)}}
+	registry, err := load(good, "data/*.json")
+	if err != nil || registry["de_x"].Sites[0].Callouts[0].Name != "Pit" {
+		t.Errorf("unexpected registry: %v, %v", registry, err)
+	}
+
+	for _, bad := range []string{`{`, `{"name": "de_x"}`, `{"name": "de_x", "display_name": "X", "overview": {"scale": 0}}`} {
+		if _, err := load(fstest.MapFS{"data/de_x.json": {Data: []byte(bad)}}, "data/*.json"); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	saved := make(map[string]Map)
+	for k, v := range Registry {
+		saved[k] = v
+	}
+	t.Cleanup(func() { Registry = saved })
+
+	if err := LoadDir(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Errorf("a missing folder should be skipped: %v", err)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "de_inferno.json"), []byte(`{"name": "de_inferno", "display_name": "Inferno", "sites": [{"name": "A", "callouts": [{"name": "New Box"}]}]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "de_foo.json"), []byte(`{"name": "de_foo", "display_name": "Foo"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{`), 0644)
+	if err := LoadDir(dir); err == nil {
+		t.Errorf("expected an error for bad.json")
+	}
+	if SiteOf("de_inferno", "New Box") != "A" || SiteOf("de_inferno", "Banana") != "" {
+		t.Errorf("de_inferno.json should replace the built in map")
+	}
+	if DisplayName("de_foo", "") != "Foo" || DisplayName("de_mirage", "") != "Mirage" {
+		t.Errorf("unexpected maps after loading the folder: %v", Names())
+	}
+}
+
+func TestLocate(t *testing.T) {
+	tests := []struct {
+		x, y float64
+		want Location
+	}{
+		{472, 1996, Location{Area: "B", Callout: "B Car", InSite: true}},  // CarMolly landing
+		{176, 1026, Location{Area: "B", Callout: "Banana", InSite: true}}, // bottom of banana
+		{547, 3151, Location{Area: "B", Callout: "Coffins", InSite: true}},
+		{1053, 2633, Location{Area: "B", Callout: "B Site", InSite: true}},
+		{2118, -431, Location{Area: "A", InSite: true}}, // no callout outline, falls back to the site
+		{-1363, 141, Location{Area: "T Spawn", Callout: "T Spawn", Side: "T"}},
+		{-5000, -5000, Location{}},
+	}
+	for _, tt := range tests {
+		if got := Locate("de_inferno", tt.x, tt.y); got != tt.want {
+			t.Errorf("Locate(%v, %v) = %+v, want %+v", tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	// Maps without outlines locate nothing
+	if got := Locate("de_cool_map", 0, 0); got != (Location{}) {
+		t.Errorf("expected no location, got %+v", got)
+	}
+
+	square := Polygon{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	if !square.Contains(5, 5) || square.Contains(15, 5) || square.size() != 100 {
+		t.Errorf("unexpected polygon results")
+	}
+}
@@ -2,30 +2,40 @@ package MetadataExplorer
 
 import (
 	"encoding/json"
+	"image"
 	"log"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Carousel"
 	"github.com/yahzoos/CS-StratBook/cmd/pkg/FileGenerator"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Radar"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
 )
 
 // Metadata represents the structure of each entry in the JSON file
 type Metadata struct {
-	FileName     string `json:"file_name"`
-	FilePath     string `json:"file_path"`
-	ImagePath    string `json:"image_path"`
-	NadeName     string `json:"nade_name"`
-	Description  string `json:"description"`
-	MapName      string `json:"map_name"`
-	Side         string `json:"side"`
-	NadeType     string `json:"nade_type"`
-	SiteLocation string `json:"site"`
+	FileName     string       `json:"file_name"`
+	FilePath     string       `json:"file_path"`
+	ImagePath    string       `json:"image_path"`
+	Images       []Tags.Image `json:"images,omitempty"`
+	NadeName     string       `json:"nade_name"`
+	Description  string       `json:"description"`
+	MapName      string       `json:"map_name"`
+	WorkshopID   string       `json:"workshop_id,omitempty"`
+	Side         string       `json:"side"`
+	NadeType     string       `json:"nade_type"`
+	SiteLocation string       `json:"site"`
+	Callout      string       `json:"callout,omitempty"`
+	ThrowZone    string       `json:"throw_zone,omitempty"`
 }
 
 // Wrapper struct to correctly map the JSON file structure
@@ -46,41 +56,73 @@ func LoadMetadata(filePath string) ([]Metadata, error) {
 		return nil, err
 	}
 
+	// Nades saved with just image_path get it as their only image
+	for i, nade := range wrapper.Nades {
+		if len(nade.Images) == 0 && nade.ImagePath != "" {
+			wrapper.Nades[i].Images = []Tags.Image{{Path: nade.ImagePath}}
+		}
+	}
 	return wrapper.Nades, nil
 }
 
-func generateMaps(metadata []Metadata) []string {
-	m := make(map[string]bool)
+// generateMaps returns the display name of every map in the metadata, and the map each one is for
+func generateMaps(metadata []Metadata) ([]string, map[string]Maps.Key) {
+	names := make(map[string]Maps.Key)
 	var uniqueMaps []string
 	for _, nades := range metadata {
-		if !m[nades.MapName] {
-			m[nades.MapName] = true
-			uniqueMaps = append(uniqueMaps, nades.MapName)
+		key := Maps.Key{Name: nades.MapName, WorkshopID: nades.WorkshopID}
+		display := key.DisplayName()
+		if _, ok := names[display]; !ok {
+			names[display] = key
+			uniqueMaps = append(uniqueMaps, display)
 		}
 	}
-	return uniqueMaps
+	return uniqueMaps, names
 }
 
 // FilterOptions holds the selected user filters
 type FilterOptions struct {
-	MapPick  string
-	T        bool
-	CT       bool
-	Smokes   bool
-	Flashes  bool
-	Molotovs bool
-	HEs      bool
-	ASite    bool
-	BSite    bool
-	MidSite  bool
+	MapPick    string
+	WorkshopID string // of the picked map, empty for official maps
+	T          bool
+	CT         bool
+	Smokes     bool
+	Flashes    bool
+	Molotovs   bool
+	HEs        bool
+	Sites      map[string]bool // ticked sites of the picked map
+	Callout    string
 }
 
 var filters = FilterOptions{}
 
+// FiltersFromQuery reads filters from a URL query: map, workshop, side (T, CT),
+// type (smoke, flash, molotov, he), site and callout. side, type and site can repeat.
+func FiltersFromQuery(q url.Values) FilterOptions {
+	f := FilterOptions{MapPick: q.Get("map"), WorkshopID: q.Get("workshop"), Sites: make(map[string]bool), Callout: q.Get("callout")}
+	for _, side := range q["side"] {
+		f.T = f.T || side == "T"
+		f.CT = f.CT || side == "CT"
+	}
+	for _, t := range q["type"] {
+		f.Smokes = f.Smokes || t == "smoke"
+		f.Flashes = f.Flashes || t == "flash"
+		f.Molotovs = f.Molotovs || t == "molotov"
+		f.HEs = f.HEs || t == "he"
+	}
+	for _, site := range q["site"] {
+		f.Sites[site] = true
+	}
+	return f
+}
+
+// anyCallout clears the callout filter
+const anyCallout = "Any callout"
+
 func FilterMetadata(metadata []Metadata, filters FilterOptions) []Metadata {
 	var filtered []Metadata
 	for _, nade := range metadata {
-		if strings.ToLower(nade.MapName) != strings.ToLower(filters.MapPick) {
+		if strings.ToLower(nade.MapName) != strings.ToLower(filters.MapPick) || nade.WorkshopID != filters.WorkshopID {
 			continue
 		}
 		if (filters.T || filters.CT) &&
@@ -95,10 +137,10 @@ func FilterMetadata(metadata []Metadata, filters FilterOptions) []Metadata {
 				(filters.HEs && nade.NadeType == "he")) {
 			continue
 		}
-		if (filters.ASite || filters.BSite || filters.MidSite) &&
-			!((filters.ASite && nade.SiteLocation == "A") ||
-				(filters.BSite && nade.SiteLocation == "B") ||
-				(filters.MidSite && nade.SiteLocation == "MID")) {
+		if anySite(filters.Sites) && !filters.Sites[nade.SiteLocation] {
+			continue
+		}
+		if filters.Callout != "" && nade.Callout != filters.Callout {
 			continue
 		}
 		filtered = append(filtered, nade)
@@ -106,6 +148,15 @@ func FilterMetadata(metadata []Metadata, filters FilterOptions) []Metadata {
 	return filtered
 }
 
+func anySite(sites map[string]bool) bool {
+	for _, ticked := range sites {
+		if ticked {
+			return true
+		}
+	}
+	return false
+}
+
 type ReloadFunc func()
 
 // ExplorerResult bundles UI + nade list + metadata
@@ -113,24 +164,30 @@ type ExplorerResult struct {
 	UI       fyne.CanvasObject
 	NadeList *FileGenerator.NadeList
 	Metadata []Metadata
+	// Update swaps in new metadata without rebuilding the tab, keeping the filters
+	Update func(metadata []Metadata)
 }
 
-// Main entrypoint
-func MetadataExplorer(filePath string, reloadFunc ReloadFunc) ExplorerResult {
+// Main entrypoint. Pass the current nadeList to keep the selection across reloads, or nil for a new one.
+// radarPath is the folder with the radar images.
+func MetadataExplorer(filePath, radarPath string, reloadFunc ReloadFunc, nadeList *FileGenerator.NadeList) ExplorerResult {
 	metadata, err := LoadMetadata(filePath)
 	if err != nil {
 		log.Printf("Error loading metadata: %v", err)
 	}
-	nadeList := &FileGenerator.NadeList{}
-	ui := createUI(metadata, filePath, reloadFunc, nadeList)
+	if nadeList == nil {
+		nadeList = &FileGenerator.NadeList{}
+	}
+	ui, update := createUI(metadata, filePath, radarPath, reloadFunc, nadeList)
 	return ExplorerResult{
 		UI:       ui,
 		NadeList: nadeList,
 		Metadata: metadata,
+		Update:   update,
 	}
 }
 
-func createUI(metadata []Metadata, filePath string, reloadFunc ReloadFunc, nadeList *FileGenerator.NadeList) fyne.CanvasObject {
+func createUI(metadata []Metadata, filePath, radarPath string, reloadFunc ReloadFunc, nadeList *FileGenerator.NadeList) (fyne.CanvasObject, func([]Metadata)) {
 	var filteredNades []Metadata
 	var fileNamedata [][]string
 	var selectedRow int
@@ -138,15 +195,29 @@ func createUI(metadata []Metadata, filePath string, reloadFunc ReloadFunc, nadeL
 	var currentSelectedNade *Metadata
 	var metadataBox *fyne.Container
 
+	// update runs on the tags.json watcher's goroutine, so the nades, filters and
+	// table rows are only used under mu. Widgets are changed after unlocking,
+	// since their callbacks lock it again.
+	var mu sync.Mutex
+	locked := func(f func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		f()
+	}
+	selected := func() (nade *Metadata) {
+		locked(func() { nade = currentSelectedNade })
+		return nade
+	}
+
 	// Buttons
 	addBtn := widget.NewButton("Add", func() {
-		if currentSelectedNade != nil {
-			nadeList.AddNade(currentSelectedNade.FilePath)
+		if nade := selected(); nade != nil {
+			nadeList.AddNade(nade.FilePath)
 		}
 	})
 	removeBtn := widget.NewButton("Remove", func() {
-		if currentSelectedNade != nil {
-			nadeList.RemoveNade(currentSelectedNade.FilePath)
+		if nade := selected(); nade != nil {
+			nadeList.RemoveNade(nade.FilePath)
 		}
 	})
 	editBtn := widget.NewButton("Edit", func() {})
@@ -159,95 +230,224 @@ func createUI(metadata []Metadata, filePath string, reloadFunc ReloadFunc, nadeL
 		metadataBox.Add(widget.NewLabel("ImagePath: " + nade.ImagePath))
 		metadataBox.Add(widget.NewLabel("NadeName: " + nade.NadeName))
 		metadataBox.Add(widget.NewLabel("Description: " + nade.Description))
-		metadataBox.Add(widget.NewLabel("MapName: " + nade.MapName))
+		metadataBox.Add(widget.NewLabel("MapName: " + nade.MapName + " (" + Maps.DisplayName(nade.MapName, nade.WorkshopID) + ")"))
 		metadataBox.Add(widget.NewLabel("Side: " + nade.Side))
 		metadataBox.Add(widget.NewLabel("NadeType: " + nade.NadeType))
 		metadataBox.Add(widget.NewLabel("SiteLocation: " + nade.SiteLocation))
+		metadataBox.Add(widget.NewLabel("Callout: " + nade.Callout))
+		metadataBox.Add(widget.NewLabel("ThrowZone: " + nade.ThrowZone))
 		metadataBox.Add(buttonBar)
 		metadataBox.Refresh()
 	}
 
 	// Initialize
-	fileNamedata = [][]string{{"Name", "Side", "Type", "Site", "Description"}}
+	fileNamedata = [][]string{{"Name", "Side", "Type", "Site", "Callout", "Description"}}
 	selectedRow = -1
 
 	// Filters UI
-	u := generateMaps(metadata)
+	// Site and callout filters come from the map registry, so they are rebuilt when the map changes
+	site := container.New(layout.NewGridLayout(4))
+	calloutSelect := widget.NewSelect(nil, func(callout string) {
+		if callout == anyCallout {
+			callout = ""
+		}
+		locked(func() { filters.Callout = callout })
+	})
+	calloutSelect.PlaceHolder = "Any callout"
+	updateSiteFilters := func(mapName string) {
+		locked(func() {
+			filters.Sites = make(map[string]bool)
+			filters.Callout = ""
+		})
+		site.Objects = nil
+		for _, name := range Maps.Sites(mapName) {
+			name := name
+			site.Add(widget.NewCheck(name, func(ticked bool) { locked(func() { filters.Sites[name] = ticked }) }))
+		}
+		site.Refresh()
+		calloutSelect.Options = append([]string{anyCallout}, Maps.Callouts(mapName)...)
+		calloutSelect.ClearSelected()
+	}
+
+	u, mapNames := generateMaps(metadata)
 	selectMap := widget.NewSelect(u, func(mappick string) {
 		log.Println("Select set to", mappick)
-		filters.MapPick = mappick
+		var pick string
+		locked(func() {
+			filters.MapPick = mapNames[mappick].Name
+			filters.WorkshopID = mapNames[mappick].WorkshopID
+			pick = filters.MapPick
+		})
+		updateSiteFilters(pick)
 	})
+	updateSiteFilters(filters.MapPick)
 	reloadBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() {
 		reloadFunc()
 	})
 	selectedmap := container.NewBorder(nil, nil, nil, reloadBtn, selectMap)
 
-	tSidebox := widget.NewCheck("T", func(t bool) { filters.T = t })
-	ctSidebox := widget.NewCheck("CT", func(ct bool) { filters.CT = ct })
+	tSidebox := widget.NewCheck("T", func(t bool) { locked(func() { filters.T = t }) })
+	ctSidebox := widget.NewCheck("CT", func(ct bool) { locked(func() { filters.CT = ct }) })
 	side := container.New(layout.NewGridLayout(4), tSidebox, ctSidebox)
 
-	smokeSidebox := widget.NewCheck("Smoke", func(smoke bool) { filters.Smokes = smoke })
-	flashSidebox := widget.NewCheck("Flash", func(flash bool) { filters.Flashes = flash })
-	molotovSidebox := widget.NewCheck("Molotov", func(molotov bool) { filters.Molotovs = molotov })
-	heSidebox := widget.NewCheck("HE_Grenade", func(he bool) { filters.HEs = he })
+	smokeSidebox := widget.NewCheck("Smoke", func(smoke bool) { locked(func() { filters.Smokes = smoke }) })
+	flashSidebox := widget.NewCheck("Flash", func(flash bool) { locked(func() { filters.Flashes = flash }) })
+	molotovSidebox := widget.NewCheck("Molotov", func(molotov bool) { locked(func() { filters.Molotovs = molotov }) })
+	heSidebox := widget.NewCheck("HE_Grenade", func(he bool) { locked(func() { filters.HEs = he }) })
 	nade := container.New(layout.NewGridLayout(4), smokeSidebox, flashSidebox, molotovSidebox, heSidebox)
 
-	aSiteLocation := widget.NewCheck("A", func(a bool) { filters.ASite = a })
-	bSiteLocation := widget.NewCheck("B", func(b bool) { filters.BSite = b })
-	midSiteLocation := widget.NewCheck("Mid", func(mid bool) { filters.MidSite = mid })
-	site := container.New(layout.NewGridLayout(4), aSiteLocation, bSiteLocation, midSiteLocation)
-
 	list = widget.NewTable(
-		func() (int, int) { return len(fileNamedata), len(fileNamedata[0]) },
+		func() (rows int, cols int) {
+			locked(func() { rows, cols = len(fileNamedata), len(fileNamedata[0]) })
+			return rows, cols
+		},
 		func() fyne.CanvasObject { return widget.NewLabel("") },
 		func(i widget.TableCellID, o fyne.CanvasObject) {
 			label := o.(*widget.Label)
-			label.SetText(fileNamedata[i.Row][i.Col])
-			if i.Row == selectedRow {
-				label.TextStyle.Bold = true
-			} else {
-				label.TextStyle.Bold = false
-			}
+			text, bold := "", false
+			locked(func() {
+				if i.Row < len(fileNamedata) {
+					text = fileNamedata[i.Row][i.Col]
+				}
+				bold = i.Row == selectedRow
+			})
+			label.SetText(text)
+			label.TextStyle.Bold = bold
 			label.Refresh()
 		},
 	)
 
-	var bottomright *canvas.Image
+	bottomright := Carousel.New(fyne.NewSize(200, 150))
 	list.OnSelected = func(id widget.TableCellID) {
 		if id.Row < 1 {
 			return
 		}
-		selectedRow = id.Row
+		var selectedNade *Metadata
+		locked(func() {
+			// The rows may have been replaced since the table was drawn
+			if id.Row-1 >= len(filteredNades) {
+				return
+			}
+			selectedRow = id.Row
+			nade := filteredNades[id.Row-1]
+			currentSelectedNade = &nade
+			selectedNade = &nade
+		})
+		if selectedNade == nil {
+			return
+		}
 		list.Refresh()
-		selectedNade := filteredNades[id.Row-1]
-		bottomright.File = selectedNade.ImagePath
-		bottomright.Refresh()
-		updateMetadataBox(selectedNade)
-		currentSelectedNade = &selectedNade
+		bottomright.SetItems(CarouselItems(selectedNade.Images))
+		updateMetadataBox(*selectedNade)
 	}
 
-	filterButton := widget.NewButton("Apply Filters", func() {
-		fileNamedata = fileNamedata[:1]
-		filteredNades = FilterMetadata(metadata, filters)
-		for _, nade := range filteredNades {
-			newslice := []string{nade.NadeName, nade.Side, nade.NadeType, nade.SiteLocation, nade.Description}
-			fileNamedata = append(fileNamedata, newslice)
-		}
-		selectedRow = -1
+	applyFilters := func() {
+		var rows [][]string
+		locked(func() {
+			// A new slice each time, so the rows handed out below never change
+			rows = [][]string{fileNamedata[0]}
+			filteredNades = FilterMetadata(metadata, filters)
+			for _, nade := range filteredNades {
+				newslice := []string{nade.NadeName, nade.Side, nade.NadeType, nade.SiteLocation, nade.Callout, nade.Description}
+				rows = append(rows, newslice)
+			}
+			fileNamedata = rows
+			selectedRow = -1
+		})
 		list.Refresh()
-		recalculateColumnWidths(list, fileNamedata)
-	})
+		recalculateColumnWidths(list, rows)
+	}
+	filterButton := widget.NewButton("Apply Filters", applyFilters)
 
 	metadataBox = container.NewVBox(widget.NewLabel("Select a nade to view details"), buttonBar)
 
-	topleft := container.NewVBox(selectedmap, side, nade, site, filterButton)
+	// Shows the nades in the table on the map; tapping a landing spot selects its row
+	radarButton := widget.NewButton("Show on Radar", func() {
+		var mapPick string
+		var nades []Metadata
+		locked(func() { mapPick, nades = filters.MapPick, filteredNades })
+		if len(nades) == 0 {
+			return
+		}
+		showRadar(mapPick, radarPath, nades, func(row int) {
+			list.Select(widget.TableCellID{Row: row + 1, Col: 0})
+		})
+	})
+
+	topleft := container.NewVBox(selectedmap, side, nade, site, calloutSelect, container.NewGridWithColumns(2, filterButton, radarButton))
 	recalculateColumnWidths(list, fileNamedata)
 	topright := container.NewHScroll(list)
 	bottomleft := metadataBox
-	bottomright = canvas.NewImageFromFile("")
-	bottomright.FillMode = canvas.ImageFillContain
 
-	return container.New(layout.NewGridLayout(2), topleft, topright, bottomleft, bottomright)
+	update := func(m []Metadata) {
+		var options []string
+		var pick string
+		locked(func() {
+			metadata = m
+			u, mapNames = generateMaps(metadata)
+			options, pick = u, filters.MapPick
+		})
+		selectMap.Options = options
+		selectMap.Refresh()
+		if pick != "" {
+			applyFilters()
+		}
+	}
+
+	return container.New(layout.NewGridLayout(2), topleft, topright, bottomleft, bottomright), update
+}
+
+// CarouselItems lists a nade's images for a Carousel
+func CarouselItems(images []Tags.Image) []Carousel.Item {
+	var items []Carousel.Item
+	for _, img := range images {
+		items = append(items, Carousel.Item{Path: img.Path, Label: img.Label})
+	}
+	return items
+}
+
+// showRadar opens a window with the nades drawn on the radar of mapName.
+// onSelect gets the index in nades of a tapped landing spot.
+func showRadar(mapName, radarPath string, nades []Metadata, onSelect func(i int)) {
+	w := fyne.CurrentApp().NewWindow("Radar: " + Maps.DisplayName(mapName, nades[0].WorkshopID))
+
+	ov, err := Radar.OverviewFor(mapName)
+	if err != nil {
+		log.Printf("Error showing radar: %v", err)
+		w.SetContent(widget.NewLabel(err.Error() + ". Add an overview to the map registry."))
+		w.Show()
+		return
+	}
+
+	var lineups []Radar.Lineup
+	var rows []int
+	for i, nade := range nades {
+		ls, err := Radar.LoadLineups(nade.NadeName, nade.FilePath)
+		if err != nil {
+			log.Printf("Error loading lineups from %s: %v", nade.FilePath, err)
+			continue
+		}
+		for range ls {
+			rows = append(rows, i)
+		}
+		lineups = append(lineups, ls...)
+	}
+
+	var background image.Image
+	status := "No radar image found in " + radarPath
+	if path := Radar.FindImage(radarPath, mapName); path != "" {
+		if background, err = Radar.LoadImage(path); err != nil {
+			log.Printf("Error loading radar image: %v", err)
+			status = err.Error()
+		} else {
+			status = path
+		}
+	}
+
+	view := Radar.NewView(background, ov, lineups, func(i int) { onSelect(rows[i]) })
+	w.SetContent(container.NewBorder(nil, widget.NewLabel(status), nil, nil, view))
+	w.Resize(fyne.NewSize(700, 740))
+	w.Show()
 }
 
 // Function to dynamically set column widths based on content
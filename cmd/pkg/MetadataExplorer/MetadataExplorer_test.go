@@ -0,0 +1,49 @@
+package MetadataExplorer
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestFiltersFromQuery(t *testing.T) {
+	q, _ := url.ParseQuery("map=de_nuke&side=CT&type=flash&type=he&site=A&callout=Heaven")
+	f := FiltersFromQuery(q)
+	if f.MapPick != "de_nuke" || f.T || !f.CT || !f.Flashes || !f.HEs || f.Smokes || !f.Sites["A"] || f.Callout != "Heaven" {
+		t.Errorf("unexpected filters: %+v", f)
+	}
+
+	nades := []Metadata{
+		{NadeName: "a", MapName: "de_nuke", Side: "CT", NadeType: "flash", SiteLocation: "A", Callout: "Heaven"},
+		{NadeName: "b", MapName: "de_nuke", Side: "T", NadeType: "flash", SiteLocation: "A", Callout: "Heaven"},
+		{NadeName: "c", MapName: "de_inferno", Side: "CT", NadeType: "flash", SiteLocation: "A", Callout: "Heaven"},
+	}
+	if got := FilterMetadata(nades, f); len(got) != 1 || got[0].NadeName != "a" {
+		t.Errorf("unexpected filtered nades: %v", got)
+	}
+}
+
+// A workshop copy of a map is listed and filtered on its own
+func TestWorkshopMaps(t *testing.T) {
+	nades := []Metadata{
+		{NadeName: "a", MapName: "de_inferno"},
+		{NadeName: "b", MapName: "de_inferno", WorkshopID: "3070284539"},
+		{NadeName: "c", MapName: "de_inferno"},
+	}
+	names, keys := generateMaps(nades)
+	if len(names) != 2 || names[1] != "Inferno (Workshop 3070284539)" || keys[names[1]].WorkshopID != "3070284539" {
+		t.Fatalf("unexpected maps: %v %v", names, keys)
+	}
+
+	got := FilterMetadata(nades, FilterOptions{MapPick: keys[names[0]].Name, WorkshopID: keys[names[0]].WorkshopID})
+	if len(got) != 2 || got[0].NadeName != "a" || got[1].NadeName != "c" {
+		t.Errorf("unexpected official nades: %v", got)
+	}
+	got = FilterMetadata(nades, FilterOptions{MapPick: keys[names[1]].Name, WorkshopID: keys[names[1]].WorkshopID})
+	if len(got) != 1 || got[0].NadeName != "b" {
+		t.Errorf("unexpected workshop nades: %v", got)
+	}
+	q, _ := url.ParseQuery("map=de_inferno&workshop=3070284539")
+	if got := FilterMetadata(nades, FiltersFromQuery(q)); len(got) != 1 || got[0].NadeName != "b" {
+		t.Errorf("unexpected workshop nades from a query: %v", got)
+	}
+}
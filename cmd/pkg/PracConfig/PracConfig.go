@@ -0,0 +1,175 @@
+package PracConfig
+
+// Writes practice server configs: the settings from prac.cfg with the
+// trajectory time, ammo and bots adjustable, the map and pack to load, and
+// optionally keys that teleport to each lineup of the pack.
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+)
+
+//go:embed prac.cfg.tmpl
+var defaultTemplate string
+
+// DefaultKeys are bound to the lineups of a pack in order, one lineup per key
+var DefaultKeys = []string{"kp_1", "kp_2", "kp_3", "kp_4", "kp_5", "kp_6", "kp_7", "kp_8", "kp_9", "kp_0"}
+
+// Options are the adjustable settings of a practice config
+type Options struct {
+	TrajectoryTime int    // seconds the grenade trail stays on screen
+	InfiniteAmmo   bool   // never run out of grenades
+	KickBots       bool   // remove the bots
+	Map            string // map to load, "" to stay on the current map
+	Pack           string // annotation pack to load with annotations_load, "" for none
+	Spots          []Spot // lineups to bind to Keys, in order
+	Keys           []string
+}
+
+// DefaultOptions match the prac.cfg that ships with the app
+func DefaultOptions() Options {
+	return Options{TrajectoryTime: 15, InfiniteAmmo: true, KickBots: true, Keys: DefaultKeys}
+}
+
+// Spot is where a lineup is thrown from and where it aims
+type Spot struct {
+	Name     string
+	Position Annotation.Vec3
+	Angles   Annotation.Vec3
+}
+
+// Setpos returns the console commands that teleport to the spot
+func (s Spot) Setpos() string {
+	f := func(n float64) string { return strconv.FormatFloat(n, 'f', -1, 64) }
+	return fmt.Sprintf("setpos %s %s %s;setang %s %s %s",
+		f(s.Position[0]), f(s.Position[1]), f(s.Position[2]),
+		f(s.Angles[0]), f(s.Angles[1]), f(s.Angles[2]))
+}
+
+// Spots reads the lineups of annotation files in order. Each main node is a
+// spot, looking the way its aim_target does when it has one.
+func Spots(files []string) ([]Spot, error) {
+	var spots []Spot
+	for _, path := range files {
+		f, err := Annotation.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		aims := make(map[string]Annotation.Vec3)
+		for _, n := range f.Nodes() {
+			if n.SubType() == "aim_target" {
+				aims[n.MasterNodeId()] = n.Angles()
+			}
+		}
+		for _, n := range f.Nodes() {
+			if n.SubType() != "main" {
+				continue
+			}
+			s := Spot{Name: n.TitleText(), Position: n.Position(), Angles: n.Angles()}
+			if a, ok := aims[n.Id()]; ok {
+				s.Angles = a
+			}
+			if s.Name == "" {
+				s.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			}
+			spots = append(spots, s)
+		}
+	}
+	return spots, nil
+}
+
+// bind is one key of the config
+type bind struct {
+	Key     string
+	Command string
+	Name    string
+}
+
+// Render returns the config. Spots beyond the number of keys are reported in
+// the warnings and left unbound.
+func Render(opts Options) ([]byte, []string, error) {
+	return RenderTemplate(defaultTemplate, opts)
+}
+
+// RenderTemplate is Render with a custom text/template, which gets the Options
+// fields and Binds, each with a Key, Command and Name
+func RenderTemplate(text string, opts Options) ([]byte, []string, error) {
+	if opts.TrajectoryTime <= 0 {
+		return nil, nil, errors.New("trajectory time must be at least 1 second")
+	}
+	for _, s := range []string{opts.Map, opts.Pack} {
+		if strings.ContainsAny(s, " ;\"\n") {
+			return nil, nil, fmt.Errorf("%q can't be used in a console command", s)
+		}
+	}
+
+	var warnings []string
+	var binds []bind
+	for i, s := range opts.Spots {
+		if i >= len(opts.Keys) {
+			warnings = append(warnings, fmt.Sprintf("%d lineup(s) not bound, there are only %d keys", len(opts.Spots)-i, len(opts.Keys)))
+			break
+		}
+		binds = append(binds, bind{Key: opts.Keys[i], Command: s.Setpos(), Name: s.Name})
+	}
+
+	tmpl, err := template.New("prac").Parse(text)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config template: %v", err)
+	}
+	var buf bytes.Buffer
+	data := struct {
+		Options
+		Binds []bind
+	}{opts, binds}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, nil, fmt.Errorf("failed to render config: %v", err)
+	}
+	return append(bytes.TrimSpace(buf.Bytes()), '\n'), warnings, nil
+}
+
+// CfgDir finds CS2's cfg folder from a folder inside the game, such as the
+// annotation folder. It returns "" when path isn't inside game/csgo.
+func CfgDir(path string) string {
+	dir := filepath.Clean(path)
+	for {
+		if strings.EqualFold(filepath.Base(dir), "csgo") && strings.EqualFold(filepath.Base(filepath.Dir(dir)), "game") {
+			return filepath.Join(dir, "cfg")
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Write renders the config to <dir>/<name>.cfg and returns the path
+func Write(dir, name string, opts Options) (string, []string, error) {
+	return WriteTemplate(dir, name, defaultTemplate, opts)
+}
+
+// WriteTemplate is Write with a custom template, see RenderTemplate
+func WriteTemplate(dir, name, text string, opts Options) (string, []string, error) {
+	data, warnings, err := RenderTemplate(text, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".cfg") {
+		name += ".cfg"
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", nil, fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return path, warnings, nil
+}
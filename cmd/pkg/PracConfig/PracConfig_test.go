@@ -0,0 +1,157 @@
+package PracConfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+)
+
+func TestRenderDefault(t *testing.T) {
+	data, warnings, err := Render(DefaultOptions())
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("unexpected result: %v %v", warnings, err)
+	}
+	// The prac.cfg in the repository is the default config
+	want, err := os.ReadFile(filepath.Join("..", "..", "..", "prac.cfg"))
+	if err != nil {
+		t.Fatalf("failed to read prac.cfg: %v", err)
+	}
+	if string(data) != string(want) {
+		t.Errorf("default config differs from prac.cfg:\n%s", data)
+	}
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" && seen[line] {
+			t.Errorf("duplicate line %q", line)
+		}
+		seen[line] = true
+	}
+}
+
+func TestRender(t *testing.T) {
+	opts := Options{
+		TrajectoryTime: 8,
+		Map:            "de_inferno",
+		Pack:           "BExecute",
+		Spots: []Spot{
+			{Name: "Coffins", Position: Annotation.Vec3{1, 2.5, 3}, Angles: Annotation.Vec3{-9.5, 50, 0}},
+			{Name: "Banana", Position: Annotation.Vec3{4, 5, 6}},
+			{Name: "Spare", Position: Annotation.Vec3{7, 8, 9}},
+		},
+		Keys: []string{"kp_1", "kp_2"},
+	}
+	data, warnings, err := Render(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := string(data)
+	for _, want := range []string{
+		"\nmap de_inferno\n",
+		"sv_infinite_ammo 0\n",
+		"sv_grenade_trajectory_prac_trailtime 8\n",
+		"annotations_load BExecute\n",
+		`bind "kp_1" "setpos 1 2.5 3;setang -9.5 50 0" // Coffins`,
+		`bind "kp_2" "setpos 4 5 6;setang 0 0 0" // Banana`,
+	} {
+		if !strings.Contains(cfg, want) {
+			t.Errorf("config is missing %q:\n%s", want, cfg)
+		}
+	}
+	if strings.Contains(cfg, "bot_kick") || strings.Contains(cfg, "Spare") {
+		t.Errorf("unexpected lines:\n%s", cfg)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected a warning for the unbound lineup, got %v", warnings)
+	}
+	if strings.Index(cfg, "map de_inferno") > strings.Index(cfg, "sv_cheats") {
+		t.Errorf("map should load before the settings")
+	}
+
+	if _, _, err := Render(Options{TrajectoryTime: 0}); err == nil {
+		t.Errorf("expected error for no trajectory time")
+	}
+	if _, _, err := Render(Options{TrajectoryTime: 5, Pack: "a;quit"}); err == nil {
+		t.Errorf("expected error for a pack name with a command in it")
+	}
+	if _, _, err := RenderTemplate("{{.Nope}}", DefaultOptions()); err == nil {
+		t.Errorf("expected error for a bad template")
+	}
+	if data, _, err := RenderTemplate("annotations_load {{.Pack}}", Options{TrajectoryTime: 1, Pack: "x"}); err != nil || string(data) != "annotations_load x\n" {
+		t.Errorf("unexpected custom template output %q %v", data, err)
+	}
+}
+
+const lineup = `<!-- kv3 encoding:text:version{e21c7f3c-8a33-41c5-9977-a76d3a32aa0d} format:generic:version{7412167c-06e9-4698-aff2-e63eb59037e7} -->
+{
+	MapName = "de_inferno"
+	MapAnnotationNode0 = 
+	{
+		Id = "m1"
+		SubType = "main"
+		Position = [ 10.0, 20.0, 30.0 ]
+		Angles = [ 0.0, 45.0, 0.0 ]
+		Title = 
+		{
+			Text = "Coffins"
+		}
+	}
+	MapAnnotationNode1 = 
+	{
+		Id = "a1"
+		SubType = "aim_target"
+		Position = [ 1.0, 1.0, 1.0 ]
+		Angles = [ -12.5, 45.0, 0.0 ]
+		MasterNodeId = "m1"
+	}
+	MapAnnotationNode2 = 
+	{
+		Id = "m2"
+		SubType = "main"
+		Position = [ 40.0, 50.0, 60.0 ]
+		Angles = [ 0.0, 90.0, 0.0 ]
+	}
+}`
+
+func TestSpotsAndWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Pack.txt")
+	os.WriteFile(path, []byte(lineup), 0644)
+
+	spots, err := Spots([]string{path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(spots) != 2 || spots[0].Name != "Coffins" || spots[0].Angles != (Annotation.Vec3{-12.5, 45, 0}) {
+		t.Errorf("unexpected first spot: %+v", spots)
+	}
+	if spots[1].Name != "Pack" || spots[1].Position != (Annotation.Vec3{40, 50, 60}) || spots[1].Angles != (Annotation.Vec3{0, 90, 0}) {
+		t.Errorf("unexpected second spot: %+v", spots[1])
+	}
+	if _, err := Spots([]string{filepath.Join(dir, "missing.txt")}); err == nil {
+		t.Errorf("expected error for a missing file")
+	}
+
+	opts := DefaultOptions()
+	opts.Spots = spots
+	out, warnings, err := Write(dir, "prac_Pack", opts)
+	if err != nil || len(warnings) != 0 || out != filepath.Join(dir, "prac_Pack.cfg") {
+		t.Fatalf("unexpected result %s %v %v", out, warnings, err)
+	}
+	data, _ := os.ReadFile(out)
+	if !strings.Contains(string(data), `bind "kp_2" "setpos 40 50 60;setang 0 90 0" // Pack`) {
+		t.Errorf("unexpected config:\n%s", data)
+	}
+}
+
+func TestCfgDir(t *testing.T) {
+	game := filepath.Join("Steam", "steamapps", "common", "Counter-Strike Global Offensive", "game", "csgo")
+	if got := CfgDir(filepath.Join(game, "annotations", "local")); got != filepath.Join(game, "cfg") {
+		t.Errorf("unexpected cfg folder %s", got)
+	}
+	if got := CfgDir(filepath.Join("home", "csgo", "annotations")); got != "" {
+		t.Errorf("expected no cfg folder, got %s", got)
+	}
+}
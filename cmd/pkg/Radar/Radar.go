@@ -0,0 +1,272 @@
+package Radar
+
+// Draws lineups onto a map's radar image, from where each nade is thrown to where it lands.
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+)
+
+// Size of a radar image in pixels, as in the game
+const Size = 1024
+
+// Lineup is one nade to draw, in world coordinates
+type Lineup struct {
+	Name        string
+	GrenadeType string
+	Throw       [2]float64
+	Land        [2]float64
+	HasLand     bool // false if the file has no destination node
+}
+
+// Colors of the throw lines by grenade type
+var Colors = map[string]color.RGBA{
+	"smoke":      {200, 200, 200, 255},
+	"flash":      {255, 230, 0, 255},
+	"molotov":    {255, 110, 0, 255},
+	"incendiary": {255, 110, 0, 255},
+	"he":         {230, 30, 30, 255},
+}
+
+var defaultColor = color.RGBA{0, 200, 255, 255}
+
+// ColorOf returns the line color for a grenade type
+func ColorOf(grenadeType string) color.RGBA {
+	if c, ok := Colors[grenadeType]; ok {
+		return c
+	}
+	return defaultColor
+}
+
+// Pixel converts world x, y to radar pixel coordinates
+func Pixel(ov Maps.Overview, x, y float64) (float64, float64) {
+	return (x - ov.PosX) / ov.Scale, (ov.PosY - y) / ov.Scale
+}
+
+// World converts radar pixel coordinates back to world x, y
+func World(ov Maps.Overview, px, py float64) (float64, float64) {
+	return px*ov.Scale + ov.PosX, ov.PosY - py*ov.Scale
+}
+
+// OverviewFor returns the radar calibration of a map
+func OverviewFor(mapName string) (Maps.Overview, error) {
+	m, ok := Maps.Lookup(mapName)
+	if !ok || m.Overview == nil {
+		return Maps.Overview{}, fmt.Errorf("no radar calibration for %s", mapName)
+	}
+	return *m.Overview, nil
+}
+
+// LoadLineups reads every main node of an annotation file and its destination
+func LoadLineups(name, txtPath string) ([]Lineup, error) {
+	f, err := Annotation.Load(txtPath)
+	if err != nil {
+		return nil, err
+	}
+
+	destinations := make(map[string]Annotation.Vec3)
+	for _, n := range f.Nodes() {
+		if n.SubType() == "destination" {
+			destinations[n.MasterNodeId()] = n.Position()
+		}
+	}
+
+	var lineups []Lineup
+	for _, n := range f.Nodes() {
+		if n.SubType() != "main" {
+			continue
+		}
+		pos := n.Position()
+		l := Lineup{Name: name, GrenadeType: n.GrenadeType(), Throw: [2]float64{pos[0], pos[1]}}
+		if n.TitleText() != "" && n.TitleText() != name {
+			l.Name = name + ": " + n.TitleText()
+		}
+		if land, ok := destinations[n.Id()]; ok {
+			l.Land = [2]float64{land[0], land[1]}
+			l.HasLand = true
+		}
+		lineups = append(lineups, l)
+	}
+	return lineups, nil
+}
+
+// FindImage looks for the radar image of a map in dir: <map>_radar.png as the
+// game names them, or <map>.png or <map>.jpg. It returns "" if there is none.
+func FindImage(dir, mapName string) string {
+	for _, name := range []string{mapName + "_radar.png", mapName + ".png", mapName + "_radar.jpg", mapName + ".jpg"} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// LoadImage decodes a PNG or JPEG radar image
+func LoadImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+	return img, nil
+}
+
+// Render draws the lineups over the radar image. A nil background gives a plain dark square.
+func Render(background image.Image, ov Maps.Overview, lineups []Lineup) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, Size, Size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{30, 30, 30, 255}}, image.Point{}, draw.Src)
+	if background != nil {
+		drawScaled(img, background)
+	}
+
+	for _, l := range lineups {
+		c := ColorOf(l.GrenadeType)
+		tx, ty := Pixel(ov, l.Throw[0], l.Throw[1])
+		if l.HasLand {
+			lx, ly := Pixel(ov, l.Land[0], l.Land[1])
+			drawLine(img, tx, ty, lx, ly, 1.5, c)
+			fillCircle(img, lx, ly, 7, color.RGBA{0, 0, 0, 255})
+			fillCircle(img, lx, ly, 5, c)
+		}
+		fillCircle(img, tx, ty, 3, c)
+	}
+	return img
+}
+
+// drawScaled stretches src over dst with nearest neighbour sampling
+func drawScaled(dst *image.RGBA, src image.Image) {
+	b := src.Bounds()
+	for y := 0; y < Size; y++ {
+		sy := b.Min.Y + y*b.Dy()/Size
+		for x := 0; x < Size; x++ {
+			dst.Set(x, y, src.At(b.Min.X+x*b.Dx()/Size, sy))
+		}
+	}
+}
+
+func fillCircle(img *image.RGBA, cx, cy, r float64, c color.RGBA) {
+	for y := int(cy - r); y <= int(cy+r); y++ {
+		for x := int(cx - r); x <= int(cx+r); x++ {
+			if dx, dy := float64(x)-cx, float64(y)-cy; dx*dx+dy*dy <= r*r {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+func drawLine(img *image.RGBA, x0, y0, x1, y1, width float64, c color.RGBA) {
+	steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		fillCircle(img, x0+(x1-x0)*t, y0+(y1-y0)*t, width, c)
+	}
+}
+
+// WritePNG renders the lineups and writes them as a PNG
+func WritePNG(w io.Writer, background image.Image, ov Maps.Overview, lineups []Lineup) error {
+	return png.Encode(w, Render(background, ov, lineups))
+}
+
+// WriteSVG writes the lineups as an SVG. backgroundHref is the radar image to
+// link behind them, or "" for a plain background.
+func WriteSVG(w io.Writer, backgroundHref string, ov Maps.Overview, lineups []Lineup) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", Size, Size, Size, Size)
+	fmt.Fprintf(&buf, "  <rect width=\"%d\" height=\"%d\" fill=\"#1e1e1e\"/>\n", Size, Size)
+	if backgroundHref != "" {
+		fmt.Fprintf(&buf, "  <image href=\"%s\" width=\"%d\" height=\"%d\"/>\n", html.EscapeString(backgroundHref), Size, Size)
+	}
+	for _, l := range lineups {
+		c := ColorOf(l.GrenadeType)
+		hex := fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+		tx, ty := Pixel(ov, l.Throw[0], l.Throw[1])
+		fmt.Fprintf(&buf, "  <g>\n    <title>%s</title>\n", html.EscapeString(l.Name))
+		if l.HasLand {
+			lx, ly := Pixel(ov, l.Land[0], l.Land[1])
+			fmt.Fprintf(&buf, "    <line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" stroke=\"%s\" stroke-width=\"3\"/>\n", tx, ty, lx, ly, hex)
+			fmt.Fprintf(&buf, "    <circle cx=\"%.1f\" cy=\"%.1f\" r=\"5\" fill=\"%s\" stroke=\"#000\" stroke-width=\"2\"/>\n", lx, ly, hex)
+		}
+		fmt.Fprintf(&buf, "    <circle cx=\"%.1f\" cy=\"%.1f\" r=\"3\" fill=\"%s\"/>\n  </g>\n", tx, ty, hex)
+	}
+	buf.WriteString("</svg>\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Nearest returns the index of the landing spot closest to pixel px, py within
+// radius pixels, or -1 if there is none
+func Nearest(ov Maps.Overview, lineups []Lineup, px, py, radius float64) int {
+	best, bestDist := -1, radius
+	for i, l := range lineups {
+		if !l.HasLand {
+			continue
+		}
+		lx, ly := Pixel(ov, l.Land[0], l.Land[1])
+		if d := math.Hypot(lx-px, ly-py); d <= bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// View shows lineups on a radar and reports taps on landing spots
+type View struct {
+	widget.BaseWidget
+	Overview Maps.Overview
+	Lineups  []Lineup
+	// OnTapped is called with the index of the lineup whose landing spot was tapped
+	OnTapped func(i int)
+
+	image *canvas.Image
+}
+
+// NewView renders the lineups over background, which may be nil
+func NewView(background image.Image, ov Maps.Overview, lineups []Lineup, onTapped func(i int)) *View {
+	v := &View{Overview: ov, Lineups: lineups, OnTapped: onTapped}
+	v.image = canvas.NewImageFromImage(Render(background, ov, lineups))
+	v.image.FillMode = canvas.ImageFillContain
+	v.image.SetMinSize(fyne.NewSize(512, 512))
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *View) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(v.image)
+}
+
+// Tapped finds the landing spot under the tap. The radar is drawn square and centered.
+func (v *View) Tapped(ev *fyne.PointEvent) {
+	size := v.Size()
+	side := math.Min(float64(size.Width), float64(size.Height))
+	if side <= 0 || v.OnTapped == nil {
+		return
+	}
+	scale := side / Size
+	px := (float64(ev.Position.X) - (float64(size.Width)-side)/2) / scale
+	py := (float64(ev.Position.Y) - (float64(size.Height)-side)/2) / scale
+	// Allow a few screen pixels of slack however small the radar is drawn
+	if i := Nearest(v.Overview, v.Lineups, px, py, 12/scale); i >= 0 {
+		v.OnTapped(i)
+	}
+}
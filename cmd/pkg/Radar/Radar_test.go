@@ -0,0 +1,135 @@
+package Radar
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// plotted has two mains, one with a destination, for the radar to draw
+const plotted = `{
+	MapName = "de_inferno"
+	MapAnnotationNode0 =
+	{
+		Id = "main-1"
+		SubType = "main"
+		Position = [ 153.0, 779.0, 80.0 ]
+		Title =
+		{
+			Text = "Car Molly"
+		}
+		GrenadeType = "molotov"
+	}
+	MapAnnotationNode1 =
+	{
+		Id = "dest-1"
+		SubType = "destination"
+		MasterNodeId = "main-1"
+		Position = [ 472.0, 1996.0, 138.0 ]
+	}
+	MapAnnotationNode2 =
+	{
+		Id = "main-2"
+		SubType = "main"
+		Position = [ -1363.0, 141.0, -64.0 ]
+		GrenadeType = "smoke"
+	}
+}`
+
+func TestPixel(t *testing.T) {
+	ov, err := OverviewFor("de_inferno")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The top left corner of the radar is pos_x, pos_y
+	if px, py := Pixel(ov, ov.PosX, ov.PosY); px != 0 || py != 0 {
+		t.Errorf("expected 0, 0, got %v, %v", px, py)
+	}
+	x, y := World(ov, 100, 200)
+	if px, py := Pixel(ov, x, y); math.Abs(px-100) > 1e-9 || math.Abs(py-200) > 1e-9 {
+		t.Errorf("round trip gave %v, %v", px, py)
+	}
+	if _, err := OverviewFor("de_cool_map"); err == nil {
+		t.Errorf("expected error for a map with no calibration")
+	}
+}
+
+func TestLoadAndRender(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "CarMolly.txt")
+	os.WriteFile(path, []byte(plotted), 0644)
+
+	lineups, err := LoadLineups("CarMolly", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lineups) != 2 || !lineups[0].HasLand || lineups[1].HasLand || lineups[0].GrenadeType != "molotov" || lineups[0].Name != "CarMolly: Car Molly" {
+		t.Fatalf("unexpected lineups: %+v", lineups)
+	}
+
+	ov, _ := OverviewFor("de_inferno")
+	img := Render(nil, ov, lineups)
+	lx, ly := Pixel(ov, 472, 1996)
+	if got := img.RGBAAt(int(lx), int(ly)); got != Colors["molotov"] {
+		t.Errorf("expected the landing spot in molotov color, got %v", got)
+	}
+
+	// A background image is stretched to fill the radar
+	bg := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	bg.Set(0, 0, color.RGBA{0, 0, 255, 255})
+	if got := Render(bg, ov, nil).RGBAAt(10, 10); got != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("background not drawn, got %v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := WritePNG(&buf, nil, ov, lineups); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded, err := png.Decode(&buf); err != nil || decoded.Bounds().Dx() != Size {
+		t.Errorf("invalid png: %v", err)
+	}
+
+	buf.Reset()
+	if err := WriteSVG(&buf, "de_inferno_radar.png", ov, lineups); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svg := buf.String()
+	if !strings.Contains(svg, `href="de_inferno_radar.png"`) || strings.Count(svg, "<line") != 1 || strings.Count(svg, "<g>") != 2 {
+		t.Errorf("unexpected svg:\n%s", svg)
+	}
+
+	if i := Nearest(ov, lineups, lx+3, ly-3, 10); i != 0 {
+		t.Errorf("expected lineup 0 near its landing spot, got %d", i)
+	}
+	if i := Nearest(ov, lineups, 0, 0, 10); i != -1 {
+		t.Errorf("expected no lineup at the corner, got %d", i)
+	}
+}
+
+func TestFindImage(t *testing.T) {
+	dir := t.TempDir()
+	if FindImage(dir, "de_inferno") != "" {
+		t.Errorf("expected no image in an empty folder")
+	}
+	path := filepath.Join(dir, "de_inferno_radar.png")
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	file, _ := os.Create(path)
+	png.Encode(file, img)
+	file.Close()
+
+	if FindImage(dir, "de_inferno") != path {
+		t.Errorf("expected %s", path)
+	}
+	if loaded, err := LoadImage(path); err != nil || loaded.Bounds().Dx() != 4 {
+		t.Errorf("unexpected image: %v", err)
+	}
+	if _, err := LoadImage(filepath.Join(dir, "missing.png")); err == nil {
+		t.Errorf("expected error for missing image")
+	}
+}
@@ -0,0 +1,286 @@
+package ScanCache
+
+// Remembers every annotation file a scan has read, keyed by path, size,
+// modification time and content hash. Re-scans only read files that changed,
+// and can tell when a tagged annotation was edited or moved.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// Entry is what the cache knows about one .txt file
+type Entry struct {
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mod_time"`
+	Hash       string    `json:"hash"` // sha256 of the content
+	MapName    string    `json:"map_name"`
+	WorkshopID string    `json:"workshop_id,omitempty"`
+	NadeType   string    `json:"nade_type"`
+	Error      string    `json:"error,omitempty"` // why the file could not be parsed
+}
+
+// Cache holds an Entry per file path
+type Cache struct {
+	Entries map[string]Entry `json:"entries"`
+
+	path string
+}
+
+// Rename is a file that moved without its content changing
+type Rename struct {
+	From string
+	To   string
+}
+
+// Result describes what a scan found
+type Result struct {
+	Files    map[string]Tags.FileInfo // as returned by Tags.GetFilePaths
+	New      []string                 // files not seen before
+	Changed  []string                 // files whose content changed
+	Removed  []string                 // files that are gone
+	Renamed  []Rename
+	Read     int      // files read because they were new or their size or time changed
+	Warnings []string // files that could not be paired, from Tags.ScanFiles
+}
+
+// Load reads the cache file. A missing file returns an empty cache.
+func Load(path string) (*Cache, error) {
+	c := &Cache{Entries: make(map[string]Entry), path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("[ScanCache] %s does not exist, starting with an empty cache", path)
+			return c, nil
+		}
+		return c, fmt.Errorf("error reading scan cache %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		// The cache can always be rebuilt, so a broken one is dropped
+		log.Printf("[ScanCache] Error parsing %s, starting over: %v", path, err)
+		c.Entries = make(map[string]Entry)
+	}
+	if c.Entries == nil {
+		c.Entries = make(map[string]Entry)
+	}
+	return c, nil
+}
+
+// Save writes the cache back to the file it was loaded from
+func (c *Cache) Save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding scan cache: %v", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing scan cache %s: %v", c.path, err)
+	}
+	return nil
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// entryFor reads and parses a file
+func entryFor(path string, info os.FileInfo) (Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	e := Entry{Size: info.Size(), ModTime: info.ModTime(), Hash: hash(data)}
+	f, err := Annotation.Parse(data)
+	if err != nil {
+		e.Error = err.Error()
+		return e, nil
+	}
+	e.MapName, e.WorkshopID, e.NadeType = Tags.AnnotationInfo(f)
+	return e, nil
+}
+
+// Scan walks root and updates the cache. Only files whose size or
+// modification time changed are read.
+func (c *Cache) Scan(root string) (Result, error) {
+	files, warnings, err := Tags.ScanFiles(root)
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{Files: files, Warnings: warnings}
+
+	seen := make(map[string]bool)
+	var added []string
+	for _, f := range files {
+		if f.TxtPath == "" {
+			continue
+		}
+		path := filepath.Clean(f.TxtPath)
+		seen[path] = true
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Printf("[ScanCache] Error reading %s: %v", path, err)
+			continue
+		}
+
+		old, known := c.Entries[path]
+		if known && old.Size == info.Size() && old.ModTime.Equal(info.ModTime()) {
+			continue
+		}
+		res.Read++
+		e, err := entryFor(path, info)
+		if err != nil {
+			log.Printf("[ScanCache] Error reading %s: %v", path, err)
+			continue
+		}
+		c.Entries[path] = e
+		switch {
+		case !known:
+			added = append(added, path)
+		case old.Hash != e.Hash:
+			res.Changed = append(res.Changed, path)
+		}
+	}
+
+	// Files under root that weren't found are gone, unless they turn up under another path
+	removedByHash := make(map[string][]string)
+	for path, e := range c.Entries {
+		if seen[path] || !under(root, path) {
+			continue
+		}
+		removedByHash[e.Hash] = append(removedByHash[e.Hash], path)
+		delete(c.Entries, path)
+	}
+	sort.Strings(added)
+	for _, path := range added {
+		h := c.Entries[path].Hash
+		if from := removedByHash[h]; len(from) > 0 {
+			sort.Strings(from)
+			res.Renamed = append(res.Renamed, Rename{From: from[0], To: path})
+			removedByHash[h] = from[1:]
+			continue
+		}
+		res.New = append(res.New, path)
+	}
+	for _, paths := range removedByHash {
+		res.Removed = append(res.Removed, paths...)
+	}
+	sort.Strings(res.Changed)
+	sort.Strings(res.Removed)
+
+	log.Printf("[ScanCache] Scanned %s: %d file(s), %d read, %d new, %d changed, %d removed, %d renamed",
+		root, len(seen), res.Read, len(res.New), len(res.Changed), len(res.Removed), len(res.Renamed))
+	return res, nil
+}
+
+// under reports whether path is inside root. A relative root only holds
+// relative paths, since the cache keys paths the way the scan found them.
+func under(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Hash returns the content hash of a scanned file, or "" if it isn't in the cache
+func (c *Cache) Hash(path string) string {
+	return c.Entries[filepath.Clean(path)].Hash
+}
+
+// ReadAnnotationInfo answers from the cache, reading the file only if it
+// isn't cached. It can be passed to Tags.GenerateMetadataWith.
+func (c *Cache) ReadAnnotationInfo(txtPath string) (mapName, workshopID, nadeType string, err error) {
+	e, ok := c.Entries[filepath.Clean(txtPath)]
+	if !ok {
+		return Tags.ReadAnnotationInfo(txtPath)
+	}
+	if e.Error != "" {
+		return "", "", "", fmt.Errorf("could not parse %s: %s", txtPath, e.Error)
+	}
+	return e.MapName, e.WorkshopID, e.NadeType, nil
+}
+
+// Move is a tagged nade whose annotation file moved
+type Move struct {
+	NadeName string
+	Rename
+}
+
+// TaggedChanges are the scan results that affect nades already in tags.json
+type TaggedChanges struct {
+	Changed []string // names of nades whose annotation content changed
+	Moved   []Move
+}
+
+// Tagged finds the nades in tags.json whose annotation changed or moved in the scan
+func Tagged(res Result, nades []Tags.AnnotationMetadata) TaggedChanges {
+	changed := make(map[string]bool)
+	for _, path := range res.Changed {
+		changed[path] = true
+	}
+	renamed := make(map[string]string)
+	for _, r := range res.Renamed {
+		renamed[r.From] = r.To
+	}
+
+	var tc TaggedChanges
+	for _, nade := range nades {
+		path := filepath.Clean(nade.FilePath)
+		if changed[path] {
+			tc.Changed = append(tc.Changed, nade.NadeName)
+		}
+		if to, ok := renamed[path]; ok {
+			tc.Moved = append(tc.Moved, Move{nade.NadeName, Rename{From: path, To: to}})
+		}
+	}
+	return tc
+}
+
+// ApplyMoves points the moved nades at their new file, and their images at
+// the files of the same name next to it. Labels and nade names are kept.
+func ApplyMoves(nades []Tags.AnnotationMetadata, moves []Move, files map[string]Tags.FileInfo) []Tags.AnnotationMetadata {
+	images := make(map[string][]string)
+	for _, f := range files {
+		if f.TxtPath != "" {
+			images[filepath.Clean(f.TxtPath)] = f.Images
+		}
+	}
+
+	updated := append([]Tags.AnnotationMetadata(nil), nades...)
+	for _, m := range moves {
+		for i := range updated {
+			if updated[i].NadeName != m.NadeName || filepath.Clean(updated[i].FilePath) != m.From {
+				continue
+			}
+			nade := &updated[i]
+			nade.FilePath = m.To
+			nade.FileName = filepath.Base(m.To)
+
+			moved := make(map[string]string)
+			for _, img := range images[m.To] {
+				moved[filepath.Base(img)] = img
+			}
+			if len(nade.Images) == 0 {
+				nade.Images = Tags.ImagesFor(images[m.To])
+			} else {
+				nade.Images = append([]Tags.Image(nil), nade.Images...)
+				for j, img := range nade.Images {
+					if p, ok := moved[filepath.Base(img.Path)]; ok {
+						nade.Images[j].Path = p
+					}
+				}
+			}
+			Tags.MigrateImages(nade)
+		}
+	}
+	return updated
+}
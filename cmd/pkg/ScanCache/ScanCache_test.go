@@ -0,0 +1,164 @@
+package ScanCache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// smoke is only as much of a file as GenerateMetadata needs to tag it
+const smoke = `{
+	MapName = "de_inferno"
+	MapAnnotationNode0 =
+	{
+		SubType = "main"
+		GrenadeType = "smoke"
+	}
+}`
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "local")
+	a := filepath.Join(root, "A", "A.txt")
+	b := filepath.Join(root, "B", "B.txt")
+	write(t, a, smoke)
+	write(t, b, "{ broken")
+
+	cachePath := filepath.Join(dir, "scancache.json")
+	c, err := Load(cachePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err := c.Scan(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Read != 2 || !reflect.DeepEqual(res.New, []string{a, b}) || len(res.Files) != 2 {
+		t.Errorf("unexpected first scan: %+v", res)
+	}
+	if m, _, n, err := c.ReadAnnotationInfo(a); err != nil || m != "de_inferno" || n != "smoke" {
+		t.Errorf("unexpected info: %q %q %v", m, n, err)
+	}
+	if _, _, _, err := c.ReadAnnotationInfo(b); err == nil {
+		t.Errorf("expected parse error for broken file")
+	}
+	if err := c.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A reloaded cache reads nothing when nothing changed
+	c, _ = Load(cachePath)
+	if res, _ = c.Scan(root); res.Read != 0 || len(res.New)+len(res.Changed)+len(res.Removed) != 0 {
+		t.Errorf("unexpected rescan: %+v", res)
+	}
+
+	// Touching a file reads it again but it isn't a change
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(a, later, later)
+	if res, _ = c.Scan(root); res.Read != 1 || len(res.Changed) != 0 {
+		t.Errorf("unexpected scan after touch: %+v", res)
+	}
+
+	write(t, b, smoke+"\n")
+	if res, _ = c.Scan(root); !reflect.DeepEqual(res.Changed, []string{b}) {
+		t.Errorf("expected %s to change: %+v", b, res)
+	}
+
+	// Moving a file is a rename, deleting one is a removal
+	moved := filepath.Join(root, "Renamed", "A.txt")
+	os.MkdirAll(filepath.Dir(moved), 0755)
+	os.Rename(a, moved)
+	os.Remove(b)
+	res, _ = c.Scan(root)
+	if !reflect.DeepEqual(res.Renamed, []Rename{{a, moved}}) || !reflect.DeepEqual(res.Removed, []string{b}) || len(res.New) != 0 {
+		t.Errorf("unexpected scan after move: %+v", res)
+	}
+	if c.Hash(moved) == "" || c.Hash(a) != "" {
+		t.Errorf("cache not updated after move")
+	}
+}
+
+// Scanning "." from inside the library still notices removed files
+func TestScanRelativeRoot(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	a := filepath.Join("local", "A", "A.txt")
+	b := filepath.Join("local", "B", "B.txt")
+	write(t, a, smoke)
+	write(t, b, smoke+"\n")
+
+	c, _ := Load(filepath.Join(dir, "scancache.json"))
+	if res, err := c.Scan("."); err != nil || !reflect.DeepEqual(res.New, []string{a, b}) {
+		t.Fatalf("unexpected first scan: %+v, %v", res, err)
+	}
+
+	// A scan of one folder leaves the others alone
+	if res, _ := c.Scan(filepath.Join("local", "A")); len(res.Removed) != 0 || c.Hash(b) == "" {
+		t.Errorf("files outside the scanned folder should be kept: %+v", res)
+	}
+
+	os.Remove(b)
+	if res, _ := c.Scan("."); !reflect.DeepEqual(res.Removed, []string{b}) {
+		t.Errorf("expected %s to be removed: %+v", b, res)
+	}
+	if c.Hash(b) != "" {
+		t.Errorf("removed file still cached")
+	}
+}
+
+func TestLoadBroken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scancache.json")
+	write(t, path, "not json")
+	c, err := Load(path)
+	if err != nil || c.Entries == nil || len(c.Entries) != 0 {
+		t.Errorf("a broken cache should start empty: %v %v", c.Entries, err)
+	}
+}
+
+func TestTagged(t *testing.T) {
+	res := Result{
+		Changed: []string{filepath.Join("local", "Edited", "Edited.txt")},
+		Renamed: []Rename{{From: filepath.Join("local", "Old", "Old.txt"), To: filepath.Join("local", "New", "Old.txt")}},
+		Files: map[string]Tags.FileInfo{"Old": {
+			TxtPath: filepath.Join("local", "New", "Old.txt"),
+			PngPath: filepath.Join("local", "New", "Old.png"),
+			Images:  []string{filepath.Join("local", "New", "Old.png")},
+		}},
+	}
+	nades := []Tags.AnnotationMetadata{
+		{NadeName: "Edited", FilePath: "local/Edited/Edited.txt"},
+		{NadeName: "Old", FileName: "Old.txt", FilePath: "local/Old/Old.txt", ImagePath: "local/Old/Old.png", Images: []Tags.Image{{Path: "local/Old/Old.png", Label: "Landing"}}},
+		{NadeName: "Untouched", FilePath: "local/Untouched/Untouched.txt"},
+	}
+
+	tc := Tagged(res, nades)
+	if !reflect.DeepEqual(tc.Changed, []string{"Edited"}) || len(tc.Moved) != 1 || tc.Moved[0].NadeName != "Old" {
+		t.Fatalf("unexpected tagged changes: %+v", tc)
+	}
+
+	updated := ApplyMoves(nades, tc.Moved, res.Files)
+	if updated[1].FilePath != res.Renamed[0].To || updated[1].ImagePath != filepath.Join("local", "New", "Old.png") ||
+		updated[1].Images[0] != (Tags.Image{Path: filepath.Join("local", "New", "Old.png"), Label: "Landing"}) || updated[1].NadeName != "Old" {
+		t.Errorf("move not applied: %+v", updated[1])
+	}
+	if nades[1].FilePath != "local/Old/Old.txt" || nades[1].Images[0].Path != "local/Old/Old.png" {
+		t.Errorf("input slice should not change")
+	}
+}
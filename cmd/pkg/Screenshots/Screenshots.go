@@ -0,0 +1,242 @@
+package Screenshots
+
+// Imports screenshots from Steam's screenshot folder into the folders of the
+// nades they show, as <NadeName>_<role>.png so GetFilePaths pairs them.
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+	_ "golang.org/x/image/webp"
+)
+
+// CS2's app id, Steam keeps its screenshots under userdata/<user>/760/remote/730/screenshots
+const appID = "730"
+
+// Screenshot is an image in a screenshot folder and when it was taken
+type Screenshot struct {
+	Path string
+	Time time.Time
+}
+
+// SteamRoot returns the Steam folder that holds path, such as the annotation
+// folder inside steamapps, or "" if path isn't inside one
+func SteamRoot(path string) string {
+	dir := filepath.Clean(path)
+	for {
+		if strings.EqualFold(filepath.Base(dir), "steamapps") {
+			return filepath.Dir(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Folders returns the CS2 screenshot folder of every Steam user under steamRoot
+func Folders(steamRoot string) []string {
+	if steamRoot == "" {
+		return nil
+	}
+	matches, _ := filepath.Glob(filepath.Join(steamRoot, "userdata", "*", "760", "remote", appID, "screenshots"))
+	return matches
+}
+
+// Steam names screenshots after when they were taken, like 20240131154502_1.jpg
+var stampName = regexp.MustCompile(`^(\d{14})_\d+$`)
+
+// takenAt reads the time from a Steam screenshot name, falling back to the modified time
+func takenAt(path string, info os.FileInfo) time.Time {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if m := stampName.FindStringSubmatch(name); m != nil {
+		if t, err := time.ParseInLocation("20060102150405", m[1], time.Local); err == nil {
+			return t
+		}
+	}
+	return info.ModTime()
+}
+
+// List returns the screenshots in dirs taken at or after since, newest first.
+// Folders that don't exist are skipped.
+func List(dirs []string, since time.Time) ([]Screenshot, error) {
+	var shots []Screenshot
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", dir, err)
+		}
+		for _, e := range entries {
+			if e.IsDir() || !Tags.IsImage(e.Name()) {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if t := takenAt(path, info); !t.Before(since) {
+				shots = append(shots, Screenshot{Path: path, Time: t})
+			}
+		}
+	}
+	sort.SliceStable(shots, func(i, j int) bool {
+		if !shots[i].Time.Equal(shots[j].Time) {
+			return shots[i].Time.After(shots[j].Time)
+		}
+		return shots[i].Path > shots[j].Path
+	})
+	return shots, nil
+}
+
+// Assignment attaches a screenshot to a nade, with an optional label from Tags.ImageLabels
+type Assignment struct {
+	Screenshot string
+	NadeName   string
+	Label      string
+}
+
+// Attached is a screenshot copied into a nade's folder
+type Attached struct {
+	Screenshot string
+	NadeName   string
+	Path       string
+}
+
+// labelRoles are the roles of Tags.ImageLabels, in the same order
+var labelRoles = []string{Tags.RoleStand, Tags.RoleAim, Tags.RoleLand}
+
+func roleOf(label string) string {
+	for i, l := range Tags.ImageLabels {
+		if strings.EqualFold(l, label) && i < len(labelRoles) {
+			return labelRoles[i]
+		}
+	}
+	return ""
+}
+
+// target picks the file a screenshot is saved as. Labeled screenshots are named
+// after their role and replace an earlier one. Others become <NadeName>.png
+// for a nade without images, or the next free <NadeName>_<n>.png.
+func target(nade Tags.AnnotationMetadata, label string) string {
+	dir := filepath.Dir(nade.FilePath)
+	base := strings.TrimSuffix(filepath.Base(nade.FilePath), filepath.Ext(nade.FilePath))
+	if role := roleOf(label); role != "" {
+		return filepath.Join(dir, base+"_"+role+".png")
+	}
+	if len(nade.Images) == 0 {
+		if p := filepath.Join(dir, base+".png"); !exists(p) {
+			return p
+		}
+	}
+	for n := 2; ; n++ {
+		if p := filepath.Join(dir, fmt.Sprintf("%s_%d.png", base, n)); !exists(p) {
+			return p
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Attach copies each screenshot into the folder of its nade as a PNG, converting
+// other formats, and adds it to the nade's images. It returns the updated nades
+// and what was copied. Nothing is written for an assignment that fails, but the
+// ones before it are kept.
+func Attach(nades []Tags.AnnotationMetadata, assignments []Assignment) ([]Tags.AnnotationMetadata, []Attached, error) {
+	nades = append([]Tags.AnnotationMetadata(nil), nades...)
+	byName := make(map[string]int, len(nades))
+	for i := range nades {
+		byName[nades[i].NadeName] = i
+	}
+
+	var attached []Attached
+	for _, a := range assignments {
+		i, ok := byName[a.NadeName]
+		if !ok {
+			return nades, attached, fmt.Errorf("no nade named %q in tags", a.NadeName)
+		}
+		if a.Label != "" && roleOf(a.Label) == "" {
+			return nades, attached, fmt.Errorf("unknown label %q, use one of: %s", a.Label, strings.Join(Tags.ImageLabels, ", "))
+		}
+		nade := nades[i]
+		Tags.MigrateImages(&nade)
+		dest := target(nade, a.Label)
+		if err := savePNG(a.Screenshot, dest); err != nil {
+			return nades, attached, err
+		}
+
+		label := a.Label
+		for _, l := range Tags.ImageLabels {
+			if strings.EqualFold(l, label) {
+				label = l
+			}
+		}
+		replaced := false
+		nade.Images = append([]Tags.Image(nil), nade.Images...)
+		for j, img := range nade.Images {
+			if filepath.Clean(img.Path) == filepath.Clean(dest) {
+				nade.Images[j].Label = label
+				replaced = true
+			}
+		}
+		if !replaced {
+			nade.Images = append(nade.Images, Tags.Image{Path: dest, Label: label})
+		}
+		Tags.MigrateImages(&nade)
+		nades[i] = nade
+		attached = append(attached, Attached{Screenshot: a.Screenshot, NadeName: a.NadeName, Path: dest})
+	}
+	return nades, attached, nil
+}
+
+// savePNG writes src to dest as a PNG, copying it as it is when it already is one
+func savePNG(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// Written to a temporary file first so a failed import leaves the old image alone
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "import-*.tmp")
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(src), ".png") {
+		_, err = io.Copy(tmp, in)
+	} else {
+		var img image.Image
+		if img, _, err = image.Decode(in); err == nil {
+			err = png.Encode(tmp, img)
+		}
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to import %s: %v", src, err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to import %s: %v", src, err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package Screenshots
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+func writeImage(t *testing.T, path string) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	img.Set(1, 1, color.RGBA{255, 0, 0, 255})
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", path, err)
+	}
+	defer f.Close()
+	if filepath.Ext(path) == ".png" {
+		err = png.Encode(f, img)
+	} else {
+		err = jpeg.Encode(f, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("failed to encode %s: %v", path, err)
+	}
+}
+
+func TestFolders(t *testing.T) {
+	steam := t.TempDir()
+	shots := filepath.Join(steam, "userdata", "1234", "760", "remote", "730", "screenshots")
+	os.MkdirAll(shots, 0755)
+	os.MkdirAll(filepath.Join(steam, "userdata", "1234", "760", "remote", "440", "screenshots"), 0755)
+	annotations := filepath.Join(steam, "steamapps", "common", "Counter-Strike Global Offensive", "game", "csgo", "annotations")
+
+	if root := SteamRoot(annotations); root != steam {
+		t.Errorf("expected Steam root %s, got %s", steam, root)
+	}
+	if root := SteamRoot(t.TempDir()); root != "" {
+		t.Errorf("expected no Steam root, got %s", root)
+	}
+	if got := Folders(SteamRoot(annotations)); len(got) != 1 || got[0] != shots {
+		t.Errorf("unexpected folders: %v", got)
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	writeImage(t, filepath.Join(dir, "20240131154502_1.jpg"))
+	writeImage(t, filepath.Join(dir, "20240201090000_1.jpg"))
+	writeImage(t, filepath.Join(dir, "20230101000000_1.jpg"))
+	writeImage(t, filepath.Join(dir, "renamed.png"))
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644)
+	os.Mkdir(filepath.Join(dir, "thumbnails"), 0755)
+	old := time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local)
+	os.Chtimes(filepath.Join(dir, "renamed.png"), old, old)
+
+	shots, err := List([]string{dir, filepath.Join(dir, "missing")}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(shots) != 2 || filepath.Base(shots[0].Path) != "20240201090000_1.jpg" || filepath.Base(shots[1].Path) != "20240131154502_1.jpg" {
+		t.Fatalf("unexpected screenshots: %v", shots)
+	}
+	if want := time.Date(2024, 1, 31, 15, 45, 2, 0, time.Local); !shots[1].Time.Equal(want) {
+		t.Errorf("expected time %v, got %v", want, shots[1].Time)
+	}
+
+	all, _ := List([]string{dir}, time.Time{})
+	if len(all) != 4 || filepath.Base(all[3].Path) != "renamed.png" {
+		t.Errorf("expected the renamed file last by modified time: %v", all)
+	}
+}
+
+func TestAttach(t *testing.T) {
+	shots := t.TempDir()
+	jpg := filepath.Join(shots, "20240131154502_1.jpg")
+	pngShot := filepath.Join(shots, "20240131154510_1.png")
+	writeImage(t, jpg)
+	writeImage(t, pngShot)
+
+	lib := t.TempDir()
+	os.MkdirAll(filepath.Join(lib, "Banana"), 0755)
+	txt := filepath.Join(lib, "Banana", "Banana Smoke.txt")
+	os.WriteFile(txt, []byte("x"), 0644)
+	other := filepath.Join(lib, "Mid.txt")
+	os.WriteFile(other, []byte("x"), 0644)
+	existing := filepath.Join(lib, "Mid.png")
+	writeImage(t, existing)
+
+	nades := []Tags.AnnotationMetadata{
+		{NadeName: "Banana Smoke", FilePath: txt},
+		{NadeName: "Mid", FilePath: other, ImagePath: existing},
+	}
+	updated, attached, err := Attach(nades, []Assignment{
+		{Screenshot: jpg, NadeName: "Banana Smoke"},
+		{Screenshot: pngShot, NadeName: "Banana Smoke", Label: "landing"},
+		{Screenshot: jpg, NadeName: "Mid"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nades[0].Images) != 0 {
+		t.Errorf("input nades were changed")
+	}
+
+	banana := updated[0]
+	wantMain := filepath.Join(lib, "Banana", "Banana Smoke.png")
+	wantLand := filepath.Join(lib, "Banana", "Banana Smoke_land.png")
+	if banana.ImagePath != wantMain || len(banana.Images) != 2 || banana.Images[1].Path != wantLand || banana.Images[1].Label != "Landing" {
+		t.Errorf("unexpected images: %+v", banana.Images)
+	}
+	f, err := os.Open(wantMain)
+	if err != nil {
+		t.Fatalf("imported image missing: %v", err)
+	}
+	_, format, err := image.DecodeConfig(f)
+	f.Close()
+	if err != nil || format != "png" {
+		t.Errorf("expected the JPG to be converted to PNG, got %q %v", format, err)
+	}
+
+	mid := updated[1]
+	if len(mid.Images) != 2 || mid.Images[0].Path != existing || mid.Images[1].Path != filepath.Join(lib, "Mid_2.png") {
+		t.Errorf("unexpected images: %+v", mid.Images)
+	}
+	if len(attached) != 3 || attached[1].Path != wantLand {
+		t.Errorf("unexpected attached: %+v", attached)
+	}
+
+	// Importing a landing again replaces the file rather than adding an image
+	again, _, err := Attach(updated, []Assignment{{Screenshot: jpg, NadeName: "Banana Smoke", Label: "Landing"}})
+	if err != nil || len(again[0].Images) != 2 {
+		t.Errorf("expected the landing to be replaced: %+v %v", again[0].Images, err)
+	}
+
+	if _, _, err := Attach(nades, []Assignment{{Screenshot: jpg, NadeName: "Nope"}}); err == nil {
+		t.Errorf("expected error for unknown nade")
+	}
+	if _, _, err := Attach(nades, []Assignment{{Screenshot: jpg, NadeName: "Mid", Label: "Sideways"}}); err == nil {
+		t.Errorf("expected error for unknown label")
+	}
+	os.WriteFile(filepath.Join(shots, "broken.jpg"), []byte("no"), 0644)
+	if _, _, err := Attach(nades, []Assignment{{Screenshot: filepath.Join(shots, "broken.jpg"), NadeName: "Mid"}}); err == nil {
+		t.Errorf("expected error for broken screenshot")
+	}
+	if matches, _ := filepath.Glob(filepath.Join(lib, "*.tmp")); len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+
+	// The imported files pair with the annotation the way tags are generated
+	files, _, err := Tags.ScanFiles(lib)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info := files[filepath.Join("Banana", "Banana Smoke")]; len(info.Images) != 2 || info.PngPath != wantMain {
+		t.Errorf("imported images not paired: %+v", info)
+	}
+}
@@ -0,0 +1,168 @@
+package Styles
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+)
+
+// TextStyle is the look of a Title or Desc block. Zero values are left as recorded.
+type TextStyle struct {
+	FontSize       int     `json:"font_size,omitempty"`
+	FadeInDist     float64 `json:"fade_in_dist,omitempty"`
+	FadeOutDist    float64 `json:"fade_out_dist,omitempty"`
+	ShowBackground *bool   `json:"show_background,omitempty"`
+}
+
+// NodeStyle is the look of one node SubType (main, aim_target or destination)
+type NodeStyle struct {
+	Title          TextStyle `json:"title"`
+	Desc           TextStyle `json:"desc"`
+	TextFacePlayer *bool     `json:"text_face_player,omitempty"`
+}
+
+// Profile is a named set of styles applied to every node of a pack
+type Profile struct {
+	Name  string               `json:"name"`
+	Nodes map[string]NodeStyle `json:"nodes,omitempty"`
+	// Colors of the main node by grenade type (smoke, flash, molotov, incendiary, he)
+	Colors map[string][3]int `json:"colors,omitempty"`
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+// Colors picked to stay apart for the common kinds of color blindness
+var colorblindColors = map[string][3]int{
+	"smoke":      {86, 180, 233},
+	"flash":      {240, 228, 66},
+	"molotov":    {213, 94, 0},
+	"incendiary": {213, 94, 0},
+	"he":         {0, 114, 178},
+}
+
+// Builtin are the profiles that are always available
+var Builtin = map[string]Profile{
+	"compact": {
+		Name: "compact",
+		Nodes: map[string]NodeStyle{
+			"main":        {Title: TextStyle{FontSize: 80, FadeInDist: 400}, Desc: TextStyle{FontSize: 50, FadeInDist: 200}},
+			"aim_target":  {Title: TextStyle{FontSize: 80}, Desc: TextStyle{FontSize: 50}},
+			"destination": {Title: TextStyle{FontSize: 50}, Desc: TextStyle{FontSize: 50}},
+		},
+	},
+	"high-visibility": {
+		Name: "high-visibility",
+		Nodes: map[string]NodeStyle{
+			"main": {
+				Title:          TextStyle{FontSize: 160, FadeInDist: 1000, ShowBackground: boolPtr(true)},
+				Desc:           TextStyle{FontSize: 100, FadeInDist: 600, ShowBackground: boolPtr(true)},
+				TextFacePlayer: boolPtr(true),
+			},
+			"aim_target": {
+				Title: TextStyle{FontSize: 160, FadeInDist: 100, ShowBackground: boolPtr(true)},
+				Desc:  TextStyle{FontSize: 100, FadeInDist: 100, ShowBackground: boolPtr(true)},
+			},
+			"destination": {
+				Title: TextStyle{FontSize: 100, ShowBackground: boolPtr(true)},
+				Desc:  TextStyle{FontSize: 100, ShowBackground: boolPtr(true)},
+			},
+		},
+		Colors: map[string][3]int{
+			"smoke":      {255, 255, 255},
+			"flash":      {255, 255, 0},
+			"molotov":    {255, 80, 0},
+			"incendiary": {255, 80, 0},
+			"he":         {255, 0, 0},
+		},
+	},
+	"colorblind": {
+		Name:   "colorblind",
+		Colors: colorblindColors,
+	},
+}
+
+// LoadProfiles returns the builtin profiles plus any defined in the JSON file
+// at path. A missing file just gives the builtin profiles.
+func LoadProfiles(path string) (map[string]Profile, error) {
+	profiles := make(map[string]Profile)
+	for name, p := range Builtin {
+		profiles[name] = p
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return profiles, fmt.Errorf("error reading styles file %s: %v", path, err)
+	}
+
+	var custom []Profile
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return profiles, fmt.Errorf("error parsing styles file %s: %v", path, err)
+	}
+	for _, p := range custom {
+		if p.Name == "" {
+			log.Printf("[Styles] Skipping profile without a name in %s", path)
+			continue
+		}
+		profiles[p.Name] = p
+	}
+	return profiles, nil
+}
+
+// Names returns the profile names in alphabetical order
+func Names(profiles map[string]Profile) []string {
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply restyles the nodes in place. The grenade type of each lineup's main
+// node picks the color.
+func Apply(nodes []Annotation.Node, p Profile) {
+	for _, n := range nodes {
+		style, ok := p.Nodes[n.SubType()]
+		if ok {
+			applyText(n.Value.Get("Title"), style.Title)
+			applyText(n.Value.Get("Desc"), style.Desc)
+			if style.TextFacePlayer != nil {
+				n.Value.Set("TextFacePlayer", Annotation.NewBool(*style.TextFacePlayer))
+			}
+		}
+
+		// Only main nodes carry a Color in the game's files
+		if n.SubType() == "main" {
+			if c, ok := p.Colors[n.GrenadeType()]; ok {
+				n.Value.Set("Color", &Annotation.Value{Kind: Annotation.Array, Items: []*Annotation.Value{
+					Annotation.NewInt(c[0]), Annotation.NewInt(c[1]), Annotation.NewInt(c[2]),
+				}})
+			}
+		}
+	}
+}
+
+func applyText(block *Annotation.Value, style TextStyle) {
+	if block == nil || block.Kind != Annotation.Object {
+		return
+	}
+	if style.FontSize > 0 {
+		block.Set("FontSize", Annotation.NewInt(style.FontSize))
+	}
+	if style.FadeInDist != 0 {
+		block.Set("FadeInDist", Annotation.NewNumber(style.FadeInDist))
+	}
+	if style.FadeOutDist != 0 {
+		block.Set("FadeOutDist", Annotation.NewNumber(style.FadeOutDist))
+	}
+	if style.ShowBackground != nil {
+		block.Set("ShowBackground", Annotation.NewBool(*style.ShowBackground))
+	}
+}
@@ -0,0 +1,99 @@
+package Styles
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+)
+
+const lineup = `{
+	MapName = "de_inferno"
+	MapAnnotationNode0 = 
+	{
+		SubType = "main"
+		Color = [ 255, 255, 255 ]
+		TextFacePlayer = false
+		Title = 
+		{
+			Text = "T2Camera"
+			FontSize = 125
+			FadeInDist = 600.0
+			FadeOutDist = 40.0
+			ShowBackground = false
+		}
+		Desc = 
+		{
+			Text = "stand in the corner"
+			FontSize = 75
+		}
+		GrenadeType = "smoke"
+	}
+	MapAnnotationNode1 = 
+	{
+		SubType = "aim_target"
+		Title = 
+		{
+			Text = "T2Camera"
+			FontSize = 125
+		}
+	}
+}`
+
+func TestApply(t *testing.T) {
+	f, err := Annotation.Parse([]byte(lineup))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nodes := f.Nodes()
+	Apply(nodes, Builtin["high-visibility"])
+
+	main := nodes[0].Value
+	if main.Get("Title").Get("FontSize").Text != "160" || main.Get("Title").Get("FadeInDist").Text != "1000.0" {
+		t.Errorf("title not restyled: %s", f.Bytes())
+	}
+	// Unset fields keep what was recorded
+	if main.Get("Title").Get("FadeOutDist").Text != "40.0" {
+		t.Errorf("FadeOutDist should not change")
+	}
+	if !main.Get("Title").Get("ShowBackground").Bool || !main.Get("TextFacePlayer").Bool {
+		t.Errorf("booleans not applied: %s", f.Bytes())
+	}
+	if !strings.Contains(string(f.Bytes()), "Color = [ 255, 255, 255 ]") {
+		t.Errorf("smoke color not applied: %s", f.Bytes())
+	}
+	if nodes[1].Value.Get("Title").Get("FontSize").Text != "160" || nodes[1].Value.Get("Color") != nil {
+		t.Errorf("unexpected aim_target: %s", f.Bytes())
+	}
+
+	Apply(nodes, Builtin["colorblind"])
+	if !strings.Contains(string(f.Bytes()), "Color = [ 86, 180, 233 ]") {
+		t.Errorf("colorblind smoke color not applied: %s", f.Bytes())
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	dir := t.TempDir()
+
+	profiles, err := LoadProfiles(filepath.Join(dir, "missing.json"))
+	if err != nil || len(profiles) != len(Builtin) {
+		t.Errorf("expected builtin profiles for missing file, got %v, %v", Names(profiles), err)
+	}
+
+	path := filepath.Join(dir, "styles.json")
+	os.WriteFile(path, []byte(`[{"name": "scrim", "nodes": {"main": {"title": {"font_size": 90}}}}, {"nodes": {}}]`), 0644)
+	profiles, err = LoadProfiles(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profiles["scrim"].Nodes["main"].Title.FontSize != 90 || len(profiles) != len(Builtin)+1 {
+		t.Errorf("unexpected profiles: %v", Names(profiles))
+	}
+
+	os.WriteFile(path, []byte(`{`), 0644)
+	if _, err := LoadProfiles(path); err == nil {
+		t.Errorf("expected error for corrupt styles file")
+	}
+}
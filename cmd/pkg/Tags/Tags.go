@@ -7,40 +7,182 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
+	"unicode"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Carousel"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
 )
 
 // Metadata struct
 type AnnotationMetadata struct {
-	FileName    string `json:"file_name"`
-	FilePath    string `json:"file_path"`
-	ImagePath   string `json:"image_path"`
-	NadeName    string `json:"nade_name"`
-	Description string `json:"description"`
-	MapName     string `json:"map_name"`
-	Side        string `json:"side,omitempty"`
-	NadeType    string `json:"nade_type"`
-	Site        string `json:"site,omitempty"`
+	FileName    string  `json:"file_name"`
+	FilePath    string  `json:"file_path"`
+	ImagePath   string  `json:"image_path"` // the first of Images, kept for older readers
+	Images      []Image `json:"images,omitempty"`
+	NadeName    string  `json:"nade_name"`
+	Description string  `json:"description"`
+	MapName     string  `json:"map_name"`
+	WorkshopID  string  `json:"workshop_id,omitempty"`
+	Side        string  `json:"side,omitempty"`
+	NadeType    string  `json:"nade_type"`
+	Site        string  `json:"site,omitempty"`
+	Callout     string  `json:"callout,omitempty"`
+	ThrowZone   string  `json:"throw_zone,omitempty"`
+}
+
+// Image is one labeled screenshot of a nade
+type Image struct {
+	Path  string `json:"path"`
+	Label string `json:"label,omitempty"`
 }
 
 // Struct to store text file and image file paths
 type FileInfo struct {
 	TxtPath    string
-	PngPath    string
+	PngPath    string   // main image, which can also be a .jpg or .webp
+	Images     []string // every image of the nade, main image first
 	ParentPath string
 }
 
-// GetFilePaths scans the directory for .txt files
+// ImageExts are the screenshot formats a nade can have, in order of preference
+var ImageExts = []string{".png", ".jpg", ".jpeg", ".webp"}
+
+// IsImage reports whether the path has one of the ImageExts
+func IsImage(path string) bool {
+	return extRank(path) < len(ImageExts)
+}
+
+func extRank(path string) int {
+	ext := strings.ToLower(filepath.Ext(path))
+	for i, e := range ImageExts {
+		if ext == e {
+			return i
+		}
+	}
+	return len(ImageExts)
+}
+
+// Roles of the screenshots of a lineup, recognised from words in the file name
+const (
+	RoleStand = "stand"
+	RoleAim   = "aim"
+	RoleLand  = "land"
+)
+
+var roleWords = []struct {
+	role  string
+	words []string
+}{
+	{RoleStand, []string{"stand", "standing", "pos", "position", "spot"}},
+	{RoleAim, []string{"aim", "crosshair", "xhair", "lineup"}},
+	{RoleLand, []string{"land", "landing", "result", "dest", "destination"}},
+}
+
+// ImageRole guesses what a screenshot shows from its name, for example
+// Banana_aim.png is RoleAim. It returns "" when the name doesn't say.
+func ImageRole(path string) string {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) })
+	for i, rw := range roleWords {
+		for _, w := range words {
+			for _, want := range rw.words {
+				if w == want {
+					return roleWords[i].role
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// ImageLabels are the labels offered for screenshots, one per role in stand, aim, land order
+var ImageLabels = []string{"Standing position", "Crosshair placement", "Landing"}
+
+// ImagesFor labels each image from its role
+func ImagesFor(paths []string) []Image {
+	var images []Image
+	for _, p := range paths {
+		img := Image{Path: p}
+		for i, rw := range roleWords {
+			if rw.role == ImageRole(p) {
+				img.Label = ImageLabels[i]
+			}
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+// MigrateImages fills Images from ImagePath for nades saved before they could
+// have several images, and keeps ImagePath set to the first image
+func MigrateImages(nade *AnnotationMetadata) {
+	if len(nade.Images) == 0 && nade.ImagePath != "" {
+		nade.Images = []Image{{Path: nade.ImagePath}}
+	}
+	if len(nade.Images) > 0 {
+		nade.ImagePath = nade.Images[0].Path
+	}
+}
+
+func roleRank(path string) int {
+	role := ImageRole(path)
+	for i, rw := range roleWords {
+		if rw.role == role {
+			return i
+		}
+	}
+	return len(roleWords)
+}
+
+// sortImages puts the image named like the nade first, then the rest in
+// stand, aim, land order
+func sortImages(baseName string, images []string) {
+	main := func(path string) bool {
+		return strings.EqualFold(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), baseName)
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		a, b := images[i], images[j]
+		if main(a) != main(b) {
+			return main(a)
+		}
+		if roleRank(a) != roleRank(b) {
+			return roleRank(a) < roleRank(b)
+		}
+		if extRank(a) != extRank(b) {
+			return extRank(a) < extRank(b)
+		}
+		return a < b
+	})
+}
+
+// folder is what one directory of the annotation folder holds
+type folder struct {
+	txts   []string
+	images []string
+}
+
+// GetFilePaths scans the directory for .txt files and their images. Problems
+// pairing them are logged; use ScanFiles to get them.
 func GetFilePaths(dirPath string) (map[string]FileInfo, error) {
-	//dirPath := "./" // Change this to your target directory
-	files := make(map[string]FileInfo)
+	files, warnings, err := ScanFiles(dirPath)
+	for _, w := range warnings {
+		log.Printf("WARNING: %s", w)
+	}
+	return files, err
+}
 
+// ScanFiles scans the directory for .txt files and pairs each with the images
+// in its own folder. The key is the path of the .txt relative to dirPath
+// without the extension, such as "Banana/Banana". Images that can't be matched
+// to one .txt are reported as warnings.
+func ScanFiles(dirPath string) (map[string]FileInfo, []string, error) {
+	folders := make(map[string]*folder)
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -48,87 +190,177 @@ func GetFilePaths(dirPath string) (map[string]FileInfo, error) {
 		if info.IsDir() {
 			return nil
 		}
+		dir := filepath.Dir(path)
+		if folders[dir] == nil {
+			folders[dir] = &folder{}
+		}
+		if strings.EqualFold(filepath.Ext(path), ".txt") {
+			folders[dir].txts = append(folders[dir].txts, path)
+		} else if IsImage(path) {
+			folders[dir].images = append(folders[dir].images, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error walking the path %s: %v", dirPath, err)
+	}
 
-		// Get the parent directory name (not full path)
-		dir := filepath.Dir(path)        // Get directory path
-		parentPath := filepath.Base(dir) // Extract only the last folder name
+	var dirs []string
+	for dir := range folders {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
 
-		baseName := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+	files := make(map[string]FileInfo)
+	var warnings []string
+	for _, dir := range dirs {
+		warnings = append(warnings, folders[dir].pair(dirPath, dir, files)...)
+	}
+	return files, warnings, nil
+}
 
-		//Regex to match .txt and .png
-		txtRegex := regexp.MustCompile(`\.txt$`)
-		pngRegex := regexp.MustCompile(`\.png$`)
+// key names a file by its path under root, without the extension
+func key(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	return filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+}
 
-		// If it is a txt file, store it in the map
-		if txtRegex.MatchString(info.Name()) {
-			if _, exists := files[baseName]; !exists {
-				files[baseName] = FileInfo{TxtPath: path, ParentPath: parentPath}
-			} else {
-				f := files[baseName]
-				f.TxtPath = path
-				f.ParentPath = parentPath
-				files[baseName] = f
-			}
+func baseName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// pair adds the nades of one folder to files and returns any warnings
+func (f *folder) pair(root, dir string, files map[string]FileInfo) []string {
+	parentPath := filepath.Base(dir)
+	sort.Strings(f.txts)
+	sort.Strings(f.images)
+
+	// Screenshots waiting for their annotation are kept, grouped by name
+	if len(f.txts) == 0 {
+		groups := make(map[string][]string)
+		for _, img := range f.images {
+			k := key(root, img)
+			groups[k] = append(groups[k], img)
+		}
+		for k, images := range groups {
+			sortImages(baseName(images[0]), images)
+			files[k] = FileInfo{PngPath: images[0], Images: images, ParentPath: parentPath}
 		}
-		// If it's a .png file, store it in the same map entry
-		if pngRegex.MatchString(info.Name()) {
-			if _, exists := files[baseName]; !exists {
-				files[baseName] = FileInfo{PngPath: path, ParentPath: parentPath}
-			} else {
-				f := files[baseName]
-				f.PngPath = path
-				f.ParentPath = parentPath
-				files[baseName] = f
+		return nil
+	}
+
+	var warnings []string
+	owned := make(map[string][]string)
+	if len(f.txts) == 1 {
+		owned[f.txts[0]] = f.images
+	} else {
+		warnings = append(warnings, fmt.Sprintf("%s has %d .txt files; nades are named after their folder, so they all get the name %s", dir, len(f.txts), parentPath))
+		for _, img := range f.images {
+			// The longest .txt name the image name starts with, so Smoke_B_aim.png goes to Smoke_B.txt rather than Smoke.txt
+			var owner string
+			for _, txt := range f.txts {
+				if matchesNade(baseName(img), baseName(txt)) && len(txt) > len(owner) {
+					owner = txt
+				}
+			}
+			if owner == "" {
+				warnings = append(warnings, fmt.Sprintf("%s: can't tell which of the %d .txt files in its folder it belongs to; name it after one of them", img, len(f.txts)))
+				continue
 			}
+			owned[owner] = append(owned[owner], img)
 		}
+	}
 
-		return nil
-	})
+	for _, txt := range f.txts {
+		images := append([]string(nil), owned[txt]...)
+		sortImages(baseName(txt), images)
+		info := FileInfo{TxtPath: txt, Images: images, ParentPath: parentPath}
+		if len(images) > 0 {
+			info.PngPath = images[0]
+		}
+		files[key(root, txt)] = info
+	}
+	return warnings
+}
+
+// matchesNade reports whether an image name is the nade name, or the nade name
+// followed by a separator such as Banana_aim for Banana
+func matchesNade(image, nade string) bool {
+	image, nade = strings.ToLower(image), strings.ToLower(nade)
+	if image == nade {
+		return true
+	}
+	return strings.HasPrefix(image, nade) && strings.ContainsRune("_- .", rune(image[len(nade)]))
+}
 
+// ReadAnnotationInfo reads the top level MapName and WorkshopSubmissionID and
+// the GrenadeType of the first main node
+func ReadAnnotationInfo(txtPath string) (mapName, workshopID, nadeType string, err error) {
+	f, err := Annotation.Load(txtPath)
 	if err != nil {
-		return nil, fmt.Errorf("error walking the path %s: %v", dirPath, err)
+		return "", "", "", fmt.Errorf("could not parse %s: %v", txtPath, err)
 	}
+	mapName, workshopID, nadeType = AnnotationInfo(f)
+	return mapName, workshopID, nadeType, nil
+}
 
-	return files, nil
+// AnnotationInfo is ReadAnnotationInfo for a file that is already parsed
+func AnnotationInfo(f *Annotation.File) (mapName, workshopID, nadeType string) {
+	for _, n := range f.Nodes() {
+		if n.SubType() == "main" && n.GrenadeType() != "" {
+			nadeType = n.GrenadeType()
+			break
+		}
+	}
+	return f.MapName(), f.WorkshopID(), nadeType
 }
 
+// InfoReader reads the map, workshop ID and nade type of an annotation file
+type InfoReader func(txtPath string) (mapName, workshopID, nadeType string, err error)
+
 // Main Function
 func GenerateMetadata(files map[string]FileInfo) ([]AnnotationMetadata, error) {
+	return GenerateMetadataWith(files, ReadAnnotationInfo)
+}
+
+// GenerateMetadataWith is GenerateMetadata with another way to read the files, such as a scan cache
+func GenerateMetadataWith(files map[string]FileInfo, readInfo InfoReader) ([]AnnotationMetadata, error) {
 	var metadataList []AnnotationMetadata
 
-	for baseName, fileInfo := range files {
-		fileText, err := os.ReadFile(fileInfo.TxtPath)
-		if err != nil {
+	for _, fileInfo := range files {
+		if _, err := os.Stat(fileInfo.TxtPath); err != nil {
 			log.Printf("Error reading file %s: %v", fileInfo.TxtPath, err)
 			continue
 		}
 
-		// Extract map name
-		mapName := ""
-		if m := regexp.MustCompile(`de_\w+`).FindString(string(fileText)); m != "" {
-			mapName = m
-		} else {
+		// Map and nade type come from the file's keys, so text in a description can't change them
+		mapName, workshopID, nadeType, err := readInfo(fileInfo.TxtPath)
+		if err != nil {
+			log.Printf("WARNING: %v", err)
+		}
+		if mapName == "" {
 			log.Printf("WARNING: MapName not found in %s", fileInfo.TxtPath)
 		}
-
-		// Extract nade type
-		nadeType := ""
-		if match := regexp.MustCompile(`GrenadeType = "([^"]+)"`).FindStringSubmatch(string(fileText)); len(match) > 1 {
-			nadeType = match[1]
-		} else {
+		if nadeType == "" {
 			log.Printf("WARNING: GrenadeType not found in %s", fileInfo.TxtPath)
 		}
 
 		metadata := AnnotationMetadata{
-			FileName:    baseName + ".txt",
+			FileName:    filepath.Base(fileInfo.TxtPath),
 			FilePath:    fileInfo.TxtPath,
 			ImagePath:   fileInfo.PngPath,
+			Images:      ImagesFor(fileInfo.Images),
 			NadeName:    fileInfo.ParentPath,
 			MapName:     mapName,
+			WorkshopID:  workshopID,
 			NadeType:    nadeType,
 			Description: "", // user will fill
 			Side:        "", // user will select
 			Site:        "", // user will select
+			Callout:     "", // user will select
 		}
 		log.Printf("[GenerateMetadata] Created metadata: %+v\n", metadata)
 		metadataList = append(metadataList, metadata)
@@ -154,9 +386,15 @@ func ValidateAnnotationMetadata(metadata AnnotationMetadata) error {
 		return errors.New("file_path is required and must point to the same .txt file")
 	}
 
-	// Validate ImagePath (optional, if exists, should end with .png)
-	if metadata.ImagePath != "" && !strings.HasSuffix(metadata.ImagePath, ".png") {
-		return errors.New("if image_path exists, it must end with .png")
+	// Validate ImagePath (optional, if exists, should be a .png, .jpg or .webp)
+	if metadata.ImagePath != "" && !IsImage(metadata.ImagePath) {
+		return errors.New("if image_path exists, it must end with .png, .jpg, .jpeg or .webp")
+	}
+
+	for _, img := range metadata.Images {
+		if !IsImage(img.Path) {
+			return fmt.Errorf("image %s must end with .png, .jpg, .jpeg or .webp", img.Path)
+		}
 	}
 
 	// Validate NadeName (required)
@@ -169,9 +407,9 @@ func ValidateAnnotationMetadata(metadata AnnotationMetadata) error {
 		return errors.New("description is required")
 	}
 
-	// Validate MapName (required, must start with 'de_')
-	if metadata.MapName == "" || !strings.HasPrefix(metadata.MapName, "de_") {
-		return errors.New("map_name is required and must start with 'de_'")
+	// Validate MapName (required, any official or workshop map name)
+	if !Maps.Valid(metadata.MapName) {
+		return errors.New("map_name is required and must be a map name such as 'de_inferno' or 'cs_office'")
 	}
 
 	// Validate Side (optional, can only be "T", "CT", or empty)
@@ -192,9 +430,14 @@ func ValidateAnnotationMetadata(metadata AnnotationMetadata) error {
 		return errors.New("nade_type is required and must be one of 'flash', 'smoke', 'molotov', 'he_grenade'")
 	}
 
-	// Validate Site (optional, can only be "A", "B", "Mid", or empty)
-	if metadata.Site != "" && metadata.Site != "A" && metadata.Site != "B" && metadata.Site != "Mid" {
-		return errors.New("site can only be 'A', 'B', 'Mid', or empty")
+	// Validate Site (optional, must be one of the map's sites)
+	if !Maps.ValidSite(metadata.MapName, metadata.Site) {
+		return fmt.Errorf("site can only be one of %s on %s, or empty", strings.Join(Maps.Sites(metadata.MapName), ", "), metadata.MapName)
+	}
+
+	// Validate Callout (optional, must be one of the map's callouts)
+	if !Maps.ValidCallout(metadata.MapName, metadata.Callout) {
+		return fmt.Errorf("callout %q is not a callout on %s", metadata.Callout, metadata.MapName)
 	}
 
 	return nil
@@ -228,7 +471,7 @@ func GetJSONFiles() ([]string, error) {
 	//#HERE#
 	files, err := os.ReadDir(".")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read directory: ", err)
+		return nil, fmt.Errorf("failed to read directory: %v", err)
 	}
 
 	// Filter for .json files
@@ -241,6 +484,48 @@ func GetJSONFiles() ([]string, error) {
 	return jsonFiles, nil
 }
 
+// TagsFile is the layout of tags.json
+type TagsFile struct {
+	Nades []AnnotationMetadata `json:"nades"`
+}
+
+// LoadTags reads every nade from tags.json. An empty file has no nades.
+func LoadTags(tagsPath string) ([]AnnotationMetadata, error) {
+	data, err := os.ReadFile(tagsPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+
+	var tags TagsFile
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %v", tagsPath, err)
+	}
+	for i := range tags.Nades {
+		MigrateImages(&tags.Nades[i])
+	}
+	return tags.Nades, nil
+}
+
+// SaveTags writes every nade back to tags.json
+func SaveTags(tagsPath string, nades []AnnotationMetadata) error {
+	nades = append([]AnnotationMetadata(nil), nades...)
+	for i := range nades {
+		MigrateImages(&nades[i])
+	}
+	data, err := json.MarshalIndent(TagsFile{Nades: nades}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %v", err)
+	}
+	if err := os.WriteFile(tagsPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write tags to file %s: %v", tagsPath, err)
+	}
+	log.Println("Tags saved to", tagsPath)
+	return nil
+}
+
 // MergeJSONFiles merges multiple JSON files into a single JSON file
 // suggested default value outputFilePath := "tags.json"
 func MergeJSONFiles(filePaths []string, outputFilePath string) error {
@@ -327,23 +612,34 @@ func PromptUserForAllNades(a fyne.App, metadataList []AnnotationMetadata) ([]Ann
 	nadeNameLabel.Wrapping = fyne.TextWrap(fyne.TextTruncateClip)
 	sideT := widget.NewCheck("T", nil)
 	sideCT := widget.NewCheck("CT", nil)
-	siteA := widget.NewCheck("A", nil)
-	siteB := widget.NewCheck("B", nil)
-	siteMid := widget.NewCheck("Mid", nil)
+	// Sites and callouts come from the map registry and change with each nade's map
+	siteRadio := widget.NewRadioGroup(nil, nil)
+	siteRadio.Horizontal = true
+	calloutSelect := widget.NewSelect(nil, nil)
+	calloutSelect.PlaceHolder = "(none)"
+	throwZoneLabel := widget.NewLabel("")
+	// Suggestions from placeholder outlines are often wrong or missing
+	placeholderLabel := widget.NewLabel("Side, site and callout were guessed from placeholder map outlines. Check them.")
+	placeholderLabel.Wrapping = fyne.TextWrapWord
 	counterLabel := widget.NewLabel("")
-	imageCanvas := canvas.NewImageFromResource(nil)
-	imageCanvas.FillMode = canvas.ImageFillContain
-	imageCanvas.SetMinSize(fyne.NewSize(400, 300))
-
-	// Wrap image in a container that grows with available space
-	imageContainer := container.NewCenter(imageCanvas)
+	images := Carousel.New(fyne.NewSize(400, 300))
+	// The label of the image shown can be picked or typed
+	imageLabel := widget.NewSelectEntry(ImageLabels)
+	imageLabel.SetPlaceHolder("What does this image show?")
+	imageLabel.OnChanged = images.SetLabel
+	images.OnChanged = func(i int) {
+		imageLabel.OnChanged = nil
+		imageLabel.SetText(images.Items()[i].Label)
+		imageLabel.OnChanged = images.SetLabel
+	}
 
 	// Top container: Nade Name + Image Preview
 	topContainer := container.NewVBox(
 		widget.NewLabel("Nade Name:"),
 		nadeNameLabel,
 		widget.NewLabel("Image Preview:"),
-		imageContainer,
+		images,
+		container.NewBorder(nil, nil, widget.NewLabel("Image label:"), nil, imageLabel),
 	)
 
 	// Single-selection logic
@@ -357,22 +653,13 @@ func PromptUserForAllNades(a fyne.App, metadataList []AnnotationMetadata) ([]Ann
 			sideT.SetChecked(false)
 		}
 	}
-	siteA.OnChanged = func(checked bool) {
-		if checked {
-			siteB.SetChecked(false)
-			siteMid.SetChecked(false)
-		}
-	}
-	siteB.OnChanged = func(checked bool) {
-		if checked {
-			siteA.SetChecked(false)
-			siteMid.SetChecked(false)
-		}
-	}
-	siteMid.OnChanged = func(checked bool) {
-		if checked {
-			siteA.SetChecked(false)
-			siteB.SetChecked(false)
+	// Picking a callout also picks the site it is in
+	calloutSelect.OnChanged = func(callout string) {
+		site := Maps.SiteOf(metadataList[currentIndex].MapName, callout)
+		for _, option := range siteRadio.Options {
+			if option == site {
+				siteRadio.SetSelected(site)
+			}
 		}
 	}
 
@@ -393,58 +680,51 @@ func PromptUserForAllNades(a fyne.App, metadataList []AnnotationMetadata) ([]Ann
 		} else {
 			nade.Side = ""
 		}
-		if siteA.Checked {
-			nade.Site = "A"
-		} else if siteB.Checked {
-			nade.Site = "B"
-		} else if siteMid.Checked {
-			nade.Site = "Mid"
-		} else {
-			nade.Site = ""
+		nade.Site = siteRadio.Selected
+		nade.Callout = calloutSelect.Selected
+		for i, item := range images.Items() {
+			nade.Images[i].Label = item.Label
 		}
 		log.Printf("[saveCurrentNade] Updated metadata: %+v\n", *nade)
 	}
 
-	// Helper for reading file as []byte
-	mustReadFile := func(path string) []byte {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			log.Printf("[mustReadFile] Error reading file: %v", err)
-			return nil
-		}
-		return data
-	}
-
 	loadNade := func(index int) {
 		nade := metadataList[index]
 		log.Printf("[loadNade] Loading index: %d, NadeName: %s\n", index, nade.NadeName)
-		log.Printf("[loadNade] Description: %s, Side: %s, Site: %s, ImagePath: %s\n", nade.Description, nade.Side, nade.Site, nade.ImagePath)
+		log.Printf("[loadNade] Description: %s, Side: %s, Site: %s, Callout: %s, ImagePath: %s\n", nade.Description, nade.Side, nade.Site, nade.Callout, nade.ImagePath)
 
 		nadeNameLabel.SetText(nade.NadeName)
 		descriptionEntry.SetText(nade.Description)
 		sideT.SetChecked(nade.Side == "T")
 		sideCT.SetChecked(nade.Side == "CT")
-		siteA.SetChecked(nade.Site == "A")
-		siteB.SetChecked(nade.Site == "B")
-		siteMid.SetChecked(nade.Site == "Mid")
+		siteRadio.Options = Maps.Sites(nade.MapName)
+		siteRadio.Selected = nade.Site
+		siteRadio.Refresh()
+		calloutSelect.Options = Maps.Callouts(nade.MapName)
+		calloutSelect.Selected = nade.Callout
+		calloutSelect.Refresh()
+		throwZoneLabel.SetText("Thrown from: " + nade.ThrowZone)
+		if m, ok := Maps.Lookup(nade.MapName); ok && m.Placeholder {
+			placeholderLabel.Show()
+		} else {
+			placeholderLabel.Hide()
+		}
 		counterLabel.SetText(fmt.Sprintf("%d / %d", index+1, total))
 
-		if _, err := os.Stat(nade.ImagePath); err == nil {
-			data := mustReadFile(nade.ImagePath)
-			if data != nil {
-				imageCanvas.Resource = fyne.NewStaticResource(filepath.Base(nade.ImagePath), data)
-			} else {
-				imageCanvas.Resource = nil
+		var items []Carousel.Item
+		for _, img := range nade.Images {
+			if _, err := os.Stat(img.Path); err != nil {
+				log.Printf("[loadNade] Image NOT found or invalid path: %s\n", img.Path)
 			}
-		} else {
-			log.Printf("[loadNade] Image NOT found or invalid path: %s\n", nade.ImagePath)
-			imageCanvas.Resource = nil
+			items = append(items, Carousel.Item{Path: img.Path, Label: img.Label})
+		}
+		if len(items) == 0 {
+			imageLabel.OnChanged = nil
+			imageLabel.SetText("")
+			imageLabel.OnChanged = images.SetLabel
 		}
-		imageCanvas.Refresh()
+		images.SetItems(items)
 
-		//log.Printf("[loadNade] ImageCanvas Resource: %v", imageCanvas.Resource)
-		log.Printf("[loadNade] ImageCanvas.Size(): %v", imageCanvas.Size())
-		log.Printf("[loadNade] ImageCanvas.MinSize(): %v", imageCanvas.MinSize())
 		log.Printf("[loadNade] topContainer.Size(): %v", topContainer.Size())
 		//log.Printf("[loadNade] content container.Size(): %v", content.Size())
 		//log.Printf("[loadNade] myWindow.Size(): %v", myWindow.Size())
@@ -517,14 +797,16 @@ func PromptUserForAllNades(a fyne.App, metadataList []AnnotationMetadata) ([]Ann
 	cancelBtn := widget.NewButton("Cancel", func() { resultErr = errors.New("canceled"); close(done) })
 
 	sideContainer := container.NewHBox(sideT, sideCT)
-	siteContainer := container.NewHBox(siteA, siteB, siteMid)
 	buttonContainer := container.NewHBox(prevBtn, nextBtn, submitBtn, submitAllBtn, cancelBtn)
 
 	// Bottom container: Description, Side, Site, Counter, Buttons
 	bottomContainer := container.NewVBox(
 		widget.NewLabel("Description:"), descriptionEntry,
 		widget.NewLabel("Side:"), sideContainer,
-		widget.NewLabel("Site:"), siteContainer,
+		widget.NewLabel("Site:"), siteRadio,
+		widget.NewLabel("Callout:"), calloutSelect,
+		throwZoneLabel,
+		placeholderLabel,
 		counterLabel,
 		buttonContainer,
 	)
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -36,7 +37,7 @@ func TestGetFilePaths(t *testing.T) {
 
 	// Expected results
 	expected := map[string]FileInfo{
-		"test1": {TxtPath: filepath.Join(tempDir, "test1.txt"), PngPath: filepath.Join(tempDir, "test1.png"), ParentPath: filepath.Base(tempDir)},
+		"test1": {TxtPath: filepath.Join(tempDir, "test1.txt"), PngPath: filepath.Join(tempDir, "test1.png"), Images: []string{filepath.Join(tempDir, "test1.png")}, ParentPath: filepath.Base(tempDir)},
 		"test2": {TxtPath: filepath.Join(tempDir, "test2.txt"), ParentPath: filepath.Base(tempDir)},
 	}
 
@@ -84,30 +85,121 @@ func TestGetFilePaths(t *testing.T) {
 	}
 }
 
+func TestScanFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		// Same file name in two folders
+		"Banana/notes.txt", "Banana/landing.webp", "Banana/notes.png", "Banana/stand.jpg", "Banana/Aim.PNG",
+		"Arch/notes.txt",
+		// A pack folder with two nades and an image that fits neither
+		"Pack/Smoke.txt", "Pack/Smoke_B.txt", "Pack/Smoke_aim.png", "Pack/Smoke_B_aim.png", "Pack/cover.png", "Pack/readme.md",
+		// Screenshots without an annotation yet
+		"Shots/Mid.png", "Shots/Mid.jpg",
+	} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, nil, 0644)
+	}
+	p := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+
+	files, warnings, err := ScanFiles(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]FileInfo{
+		"Arch/notes": {TxtPath: p("Arch/notes.txt"), ParentPath: "Arch"},
+		"Banana/notes": {TxtPath: p("Banana/notes.txt"), PngPath: p("Banana/notes.png"), ParentPath: "Banana",
+			Images: []string{p("Banana/notes.png"), p("Banana/stand.jpg"), p("Banana/Aim.PNG"), p("Banana/landing.webp")}},
+		"Pack/Smoke":   {TxtPath: p("Pack/Smoke.txt"), PngPath: p("Pack/Smoke_aim.png"), Images: []string{p("Pack/Smoke_aim.png")}, ParentPath: "Pack"},
+		"Pack/Smoke_B": {TxtPath: p("Pack/Smoke_B.txt"), PngPath: p("Pack/Smoke_B_aim.png"), Images: []string{p("Pack/Smoke_B_aim.png")}, ParentPath: "Pack"},
+		"Shots/Mid":    {PngPath: p("Shots/Mid.png"), Images: []string{p("Shots/Mid.png"), p("Shots/Mid.jpg")}, ParentPath: "Shots"},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("unexpected files:\ngot  %v\nwant %v", files, want)
+	}
+	// The shared folder name and the unmatched cover image are reported
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "2 .txt files") || !strings.Contains(warnings[1], "cover.png") {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+}
+
+func TestImageRole(t *testing.T) {
+	for name, want := range map[string]string{
+		"Banana_stand.png":   RoleStand,
+		"crosshair.jpg":      RoleAim,
+		"smoke-landing.webp": RoleLand,
+		"BananaSmoke.png":    "",
+		"Standoff_mid.png":   "",
+	} {
+		if got := ImageRole(name); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+const mirageSmoke = `{
+	MapName = "de_mirage"
+	MapAnnotationNode0 =
+	{
+		SubType = "main"
+		Desc =
+		{
+			Text = "better than the de_dust2 one"
+		}
+		GrenadeType = "smoke"
+	}
+}`
+
+const workshopFlash = `{
+	MapName = "cs_cool_map"
+	WorkshopSubmissionID = "3070284539"
+	MapAnnotationNode0 =
+	{
+		SubType = "aim_target"
+	}
+	MapAnnotationNode1 =
+	{
+		SubType = "main"
+		GrenadeType = "flash"
+	}
+}`
+
 func TestGenerateMetadata(t *testing.T) {
 	tempDir := t.TempDir()
 	txtPath := filepath.Join(tempDir, "test.txt")
 	pngPath := filepath.Join(tempDir, "test.png")
-	os.WriteFile(txtPath, []byte("GrenadeType = \"smoke\"\nde_mirage"), 0644)
+	os.WriteFile(txtPath, []byte(mirageSmoke), 0644)
 	os.WriteFile(pngPath, []byte(""), 0644)
 
 	files := map[string]FileInfo{
 		"test": {TxtPath: txtPath, PngPath: pngPath, ParentPath: "nade_folder"},
 	}
 
-	metadata := GenerateMetadata(files, "T", "A", "Test description")
+	metadataList, err := GenerateMetadata(files)
+	if err != nil || len(metadataList) != 1 {
+		t.Fatalf("unexpected result: %v, %v", metadataList, err)
+	}
+	metadata := metadataList[0]
 
-	if metadata.NadeType != "smoke" || metadata.MapName != "de_mirage" || metadata.FileName != "test.txt" {
+	if metadata.NadeType != "smoke" || metadata.MapName != "de_mirage" || metadata.FileName != "test.txt" || metadata.NadeName != "nade_folder" {
 		t.Errorf("unexpected metadata output: %+v", metadata)
 	}
 
+	// Hostage and workshop maps
+	workshopPath := filepath.Join(tempDir, "workshop.txt")
+	os.WriteFile(workshopPath, []byte(workshopFlash), 0644)
+	metadataList, _ = GenerateMetadata(map[string]FileInfo{"workshop": {TxtPath: workshopPath, ParentPath: "cool_flash"}})
+	if m := metadataList[0]; m.MapName != "cs_cool_map" || m.WorkshopID != "3070284539" || m.NadeType != "flash" {
+		t.Errorf("unexpected workshop metadata: %+v", m)
+	}
+
 	// Test missing .png file
 	files = map[string]FileInfo{
 		"test": {TxtPath: txtPath, ParentPath: "nade_folder"},
 	}
-	metadata = GenerateMetadata(files, "T", "A", "Test description")
-	if metadata.ImagePath != "" {
-		t.Errorf("expected empty ImagePath, got %v", metadata.ImagePath)
+	metadataList, _ = GenerateMetadata(files)
+	if metadataList[0].ImagePath != "" {
+		t.Errorf("expected empty ImagePath, got %v", metadataList[0].ImagePath)
 	}
 
 	// Test malformed .txt content
@@ -116,14 +208,110 @@ func TestGenerateMetadata(t *testing.T) {
 	files = map[string]FileInfo{
 		"bad": {TxtPath: badTxtPath, ParentPath: "nade_folder"},
 	}
-	metadata = GenerateMetadata(files, "T", "A", "Test description")
-	if metadata.MapName != "" || metadata.NadeType != "" {
-		t.Errorf("expected empty MapName and NadeType for malformed txt file, got %+v", metadata)
+	metadataList, _ = GenerateMetadata(files)
+	if metadataList[0].MapName != "" || metadataList[0].NadeType != "" {
+		t.Errorf("expected empty MapName and NadeType for malformed txt file, got %+v", metadataList[0])
 	}
 
 	// Test empty input
-	metadata = GenerateMetadata(map[string]FileInfo{}, "T", "A", "Test description")
-	if (metadata != AnnotationMetadata{}) {
-		t.Errorf("expected empty metadata for empty input, got %+v", metadata)
+	if _, err := GenerateMetadata(map[string]FileInfo{}); err == nil {
+		t.Errorf("expected error for empty input")
+	}
+}
+
+func TestValidateMapName(t *testing.T) {
+	nade := AnnotationMetadata{FileName: "a.txt", FilePath: "x/a.txt", NadeName: "a", Description: "d", NadeType: "smoke"}
+	for _, name := range []string{"de_inferno", "cs_office", "ar_shoots", "de_cool_map"} {
+		nade.MapName = name
+		if err := ValidateAnnotationMetadata(nade); err != nil {
+			t.Errorf("unexpected error for %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "de inferno"} {
+		nade.MapName = name
+		if err := ValidateAnnotationMetadata(nade); err == nil {
+			t.Errorf("expected error for map name %q", name)
+		}
+	}
+}
+
+func TestValidateSiteAndCallout(t *testing.T) {
+	nade := AnnotationMetadata{FileName: "a.txt", FilePath: "x/a.txt", NadeName: "a", Description: "d", NadeType: "smoke", MapName: "de_inferno"}
+	valid := []struct{ mapName, site, callout string }{
+		{"de_inferno", "B", "Banana"},
+		{"de_inferno", "", ""},
+		{"de_nuke", "Outside", "Silo"},
+		{"de_cool_map", "Mid", "Anywhere"},
+	}
+	for _, v := range valid {
+		nade.MapName, nade.Site, nade.Callout = v.mapName, v.site, v.callout
+		if err := ValidateAnnotationMetadata(nade); err != nil {
+			t.Errorf("unexpected error for %+v: %v", v, err)
+		}
+	}
+	invalid := []struct{ mapName, site, callout string }{
+		{"de_inferno", "C", ""},
+		{"de_nuke", "Mid", ""},
+		{"de_inferno", "B", "Apps"},
+	}
+	for _, v := range invalid {
+		nade.MapName, nade.Site, nade.Callout = v.mapName, v.site, v.callout
+		if err := ValidateAnnotationMetadata(nade); err == nil {
+			t.Errorf("expected error for %+v", v)
+		}
+	}
+}
+
+func TestLoadSaveTags(t *testing.T) {
+	tagsPath := filepath.Join(t.TempDir(), "tags.json")
+
+	// checkFile creates an empty tags.json on first run
+	os.WriteFile(tagsPath, []byte(""), 0644)
+	nades, err := LoadTags(tagsPath)
+	if err != nil || len(nades) != 0 {
+		t.Errorf("expected no nades for empty file, got %v, %v", nades, err)
+	}
+
+	want := []AnnotationMetadata{
+		{FileName: "T2Camera.txt", NadeName: "T2Camera", MapName: "de_train", Side: "T", NadeType: "smoke", Site: "A"},
+	}
+	if err := SaveTags(tagsPath, want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nades, err = LoadTags(tagsPath)
+	if err != nil || !reflect.DeepEqual(nades, want) {
+		t.Errorf("unexpected nades: got %v, %v; want %v", nades, err, want)
+	}
+
+	os.WriteFile(tagsPath, []byte("{"), 0644)
+	if _, err := LoadTags(tagsPath); err == nil {
+		t.Errorf("expected error for corrupt tags file")
+	}
+}
+
+func TestImages(t *testing.T) {
+	images := ImagesFor([]string{"Banana.png", "Banana_stand.jpg", "Banana_aim.png", "Banana_landing.webp"})
+	want := []Image{{"Banana.png", ""}, {"Banana_stand.jpg", "Standing position"}, {"Banana_aim.png", "Crosshair placement"}, {"Banana_landing.webp", "Landing"}}
+	if !reflect.DeepEqual(images, want) {
+		t.Errorf("unexpected images: %v", images)
+	}
+
+	// Records from before images were a list are migrated when loaded
+	tagsPath := filepath.Join(t.TempDir(), "tags.json")
+	os.WriteFile(tagsPath, []byte(`{"nades": [{"nade_name": "Old", "image_path": "Old/Old.png"}, {"nade_name": "NoImage"}]}`), 0644)
+	nades, err := LoadTags(tagsPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(nades[0].Images, []Image{{Path: "Old/Old.png"}}) || nades[1].Images != nil {
+		t.Errorf("unexpected migration: %+v", nades)
+	}
+
+	// image_path follows the first image
+	nades[0].Images = []Image{{"Old/aim.png", "Crosshair placement"}, {"Old/Old.png", ""}}
+	SaveTags(tagsPath, nades)
+	nades, _ = LoadTags(tagsPath)
+	if nades[0].ImagePath != "Old/aim.png" || len(nades[0].Images) != 2 {
+		t.Errorf("unexpected saved nade: %+v", nades[0])
 	}
 }
@@ -0,0 +1,189 @@
+package TextSync
+
+// Keeps the descriptions in tags.json and the text players see in game in step.
+// Changes are planned first so they can be previewed, then applied.
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// Field names used in a Change
+const (
+	FieldTitle       = "Title"
+	FieldDesc        = "Desc"
+	FieldDescription = "description"
+)
+
+// Options for writing tags.json into the annotation files
+type Options struct {
+	// Labels adds the side, site and nade type to the main node title
+	Labels bool
+}
+
+// Change is one text that will be replaced
+type Change struct {
+	NadeName string
+	FilePath string
+	Field    string // Title or Desc of the main node, or description in tags.json
+	Old      string
+	New      string
+}
+
+// Placeholder descriptions PromptUserForAllNades fills in; never written into a file
+var placeholders = map[string]bool{
+	"":                        true,
+	"No description provided": true,
+	"Default description":     true,
+}
+
+// String describes the change on one line for the preview
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s: %q -> %q", c.NadeName, c.Field, c.Old, c.New)
+}
+
+// mainNode returns the single main node of a nade file
+func mainNode(path string) (*Annotation.File, Annotation.Node, error) {
+	f, err := Annotation.Load(path)
+	if err != nil {
+		return nil, Annotation.Node{}, err
+	}
+	var mains []Annotation.Node
+	for _, n := range f.Nodes() {
+		if n.SubType() == "main" {
+			mains = append(mains, n)
+		}
+	}
+	if len(mains) != 1 {
+		return nil, Annotation.Node{}, fmt.Errorf("%s has %d main nodes, expected 1", path, len(mains))
+	}
+	return f, mains[0], nil
+}
+
+// Title builds the main node title, adding labels when asked
+func Title(nade Tags.AnnotationMetadata, opts Options) string {
+	if !opts.Labels {
+		return nade.NadeName
+	}
+	var labels []string
+	for _, l := range []string{nade.Side, nade.Site, nade.NadeType} {
+		if l != "" {
+			labels = append(labels, l)
+		}
+	}
+	if len(labels) == 0 {
+		return nade.NadeName
+	}
+	return nade.NadeName + " [" + strings.Join(labels, " ") + "]"
+}
+
+// PlanToFiles lists the changes needed to write each nade's description into
+// the Desc of its main node, and the labels into the Title if asked
+func PlanToFiles(nades []Tags.AnnotationMetadata, opts Options) ([]Change, []string) {
+	var changes []Change
+	var warnings []string
+	for _, nade := range nades {
+		_, main, err := mainNode(nade.FilePath)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipped %s: %v", nade.NadeName, err))
+			continue
+		}
+
+		if !placeholders[nade.Description] && main.DescText() != nade.Description {
+			changes = append(changes, Change{nade.NadeName, nade.FilePath, FieldDesc, main.DescText(), nade.Description})
+		}
+		// A nade with nothing to label keeps its recorded title
+		if title := Title(nade, opts); opts.Labels && title != nade.NadeName {
+			if main.TitleText() != title {
+				changes = append(changes, Change{nade.NadeName, nade.FilePath, FieldTitle, main.TitleText(), title})
+			}
+		}
+	}
+	return changes, warnings
+}
+
+// PlanFromFiles lists the nades with no description and the in-file text that
+// would fill it: the main node Desc, or the Title if there is no Desc
+func PlanFromFiles(nades []Tags.AnnotationMetadata) ([]Change, []string) {
+	var changes []Change
+	var warnings []string
+	for _, nade := range nades {
+		if !placeholders[nade.Description] {
+			continue
+		}
+		_, main, err := mainNode(nade.FilePath)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipped %s: %v", nade.NadeName, err))
+			continue
+		}
+
+		text := main.DescText()
+		if text == "" {
+			text = main.TitleText()
+		}
+		if text == "" || text == nade.Description {
+			continue
+		}
+		changes = append(changes, Change{nade.NadeName, nade.FilePath, FieldDescription, nade.Description, text})
+	}
+	return changes, warnings
+}
+
+// ApplyToFiles writes the Title and Desc changes into the annotation files
+func ApplyToFiles(changes []Change) error {
+	byFile := make(map[string][]Change)
+	var order []string
+	for _, c := range changes {
+		if c.Field != FieldTitle && c.Field != FieldDesc {
+			continue
+		}
+		if _, ok := byFile[c.FilePath]; !ok {
+			order = append(order, c.FilePath)
+		}
+		byFile[c.FilePath] = append(byFile[c.FilePath], c)
+	}
+
+	for _, path := range order {
+		f, main, err := mainNode(path)
+		if err != nil {
+			return err
+		}
+		for _, c := range byFile[path] {
+			if c.Field == FieldTitle {
+				err = main.SetTitleText(c.New)
+			} else {
+				err = main.SetDescText(c.New)
+			}
+			if err != nil {
+				return fmt.Errorf("error updating %s: %v", path, err)
+			}
+		}
+		if err := f.Save(path); err != nil {
+			return fmt.Errorf("error writing %s: %v", path, err)
+		}
+		log.Printf("[TextSync] Updated %s", path)
+	}
+	return nil
+}
+
+// ApplyToMetadata returns the nades with the description changes applied
+func ApplyToMetadata(nades []Tags.AnnotationMetadata, changes []Change) []Tags.AnnotationMetadata {
+	descriptions := make(map[string]string)
+	for _, c := range changes {
+		if c.Field == FieldDescription {
+			descriptions[c.FilePath] = c.New
+		}
+	}
+
+	updated := append([]Tags.AnnotationMetadata(nil), nades...)
+	for i := range updated {
+		if d, ok := descriptions[updated[i].FilePath]; ok {
+			updated[i].Description = d
+		}
+	}
+	return updated
+}
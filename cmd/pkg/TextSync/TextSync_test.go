@@ -0,0 +1,120 @@
+package TextSync
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// t2Camera has the stand and aim text that syncing rewrites
+const t2Camera = `{
+	MapName = "de_train"
+	MapAnnotationNode0 =
+	{
+		SubType = "main"
+		Title =
+		{
+			Text = "T2Camera"
+		}
+		Desc =
+		{
+			Text = "stand in the corner"
+		}
+	}
+	MapAnnotationNode1 =
+	{
+		SubType = "aim_target"
+		Title =
+		{
+			Text = "T2Camera"
+		}
+		Desc =
+		{
+			Text = "aim at the pole"
+		}
+	}
+}`
+
+func writeNade(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name+".txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestSyncToFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := writeNade(t, dir, "T2Camera", t2Camera)
+	nades := []Tags.AnnotationMetadata{
+		{NadeName: "T2Camera", FilePath: path, Description: "Smokes Camera from T Spawn", Side: "T", Site: "A", NadeType: "smoke"},
+		{NadeName: "Placeholder", FilePath: path, Description: "No description provided"},
+		{NadeName: "Missing", FilePath: filepath.Join(dir, "missing.txt"), Description: "x"},
+	}
+
+	changes, warnings := PlanToFiles(nades, Options{Labels: true})
+	if len(warnings) != 1 {
+		t.Errorf("expected a warning for the missing file, got %v", warnings)
+	}
+	want := []Change{
+		{"T2Camera", path, FieldDesc, "stand in the corner", "Smokes Camera from T Spawn"},
+		{"T2Camera", path, FieldTitle, "T2Camera", "T2Camera [T A smoke]"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("unexpected changes:\ngot  %v\nwant %v", changes, want)
+	}
+
+	// Planning doesn't touch the file
+	data, _ := os.ReadFile(path)
+	if string(data) != t2Camera {
+		t.Errorf("file changed before apply")
+	}
+
+	if err := ApplyToFiles(changes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, err := Annotation.Load(path)
+	if err != nil {
+		t.Fatalf("failed to parse updated file: %v", err)
+	}
+	main, aim := f.Nodes()[0], f.Nodes()[1]
+	if main.TitleText() != "T2Camera [T A smoke]" || main.DescText() != "Smokes Camera from T Spawn" {
+		t.Errorf("main node not updated: %q %q", main.TitleText(), main.DescText())
+	}
+	if aim.DescText() != "aim at the pole" {
+		t.Errorf("aim_target should not change: %q", aim.DescText())
+	}
+
+	// Nothing left to do afterwards
+	if changes, _ := PlanToFiles(nades[:1], Options{Labels: true}); len(changes) != 0 {
+		t.Errorf("expected no changes after apply, got %v", changes)
+	}
+}
+
+func TestSyncFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := writeNade(t, dir, "T2Camera", t2Camera)
+	other := writeNade(t, dir, "Other", t2Camera)
+	nades := []Tags.AnnotationMetadata{
+		{NadeName: "T2Camera", FilePath: path, Description: ""},
+		{NadeName: "Other", FilePath: other, Description: "Already described"},
+	}
+
+	changes, warnings := PlanFromFiles(nades)
+	if len(warnings) != 0 || len(changes) != 1 || changes[0].New != "stand in the corner" {
+		t.Fatalf("unexpected plan: %v %v", changes, warnings)
+	}
+
+	updated := ApplyToMetadata(nades, changes)
+	if updated[0].Description != "stand in the corner" || updated[1].Description != "Already described" {
+		t.Errorf("unexpected metadata: %+v", updated)
+	}
+	if nades[0].Description != "" {
+		t.Errorf("input slice should not change")
+	}
+}
@@ -0,0 +1,190 @@
+package Thumbnails
+
+// Small copies of the screenshots for the image previews, so stepping through
+// nades doesn't decode a 1080p image on every click. Thumbnails are stored on
+// disk under the content hash of the original and made in the background.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// DefaultSize is the longest side of a thumbnail in pixels
+const DefaultSize = 640
+
+// Cache makes thumbnails and keeps them in a folder
+type Cache struct {
+	dir  string
+	size int
+	sem  chan struct{} // limits how many images are decoded at once
+
+	mu     sync.Mutex
+	hashes map[string]stamp
+}
+
+// stamp is the hash of a file, and the size and time it had when hashed
+type stamp struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// New creates a cache that keeps thumbnails no bigger than size in dir
+func New(dir string, size int) *Cache {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Cache{
+		dir:    dir,
+		size:   size,
+		sem:    make(chan struct{}, runtime.NumCPU()),
+		hashes: make(map[string]stamp),
+	}
+}
+
+// hash returns the content hash of a file, reading it only when it changed
+// since it was last hashed
+func (c *Cache) hash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	s, ok := c.hashes[path]
+	c.mu.Unlock()
+	if ok && s.size == info.Size() && s.modTime.Equal(info.ModTime()) {
+		return s.hash, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	c.mu.Lock()
+	c.hashes[path] = stamp{info.Size(), info.ModTime(), sum}
+	c.mu.Unlock()
+	return sum, nil
+}
+
+func decodeFile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %v", path, err)
+	}
+	return img, nil
+}
+
+// Path returns the thumbnail file for an image, making it if needed
+func (c *Cache) Path(src string) (string, error) {
+	sum, err := c.hash(src)
+	if err != nil {
+		return "", err
+	}
+	thumb := filepath.Join(c.dir, fmt.Sprintf("%s-%d.jpg", sum, c.size))
+	if _, err := os.Stat(thumb); err == nil {
+		return thumb, nil
+	}
+
+	img, err := decodeFile(src)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return "", err
+	}
+	// Written to a temporary file first so a half written thumbnail is never used
+	tmp, err := os.CreateTemp(c.dir, "thumb-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	err = jpeg.Encode(tmp, Scale(img, c.size), &jpeg.Options{Quality: 85})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("error writing thumbnail for %s: %v", src, err)
+	}
+	if err := os.Rename(tmp.Name(), thumb); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return thumb, nil
+}
+
+// Image returns the decoded thumbnail of an image
+func (c *Cache) Image(src string) (image.Image, error) {
+	thumb, err := c.Path(src)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFile(thumb)
+}
+
+// Load gets the thumbnail in the background and calls done with it
+func (c *Cache) Load(src string, done func(image.Image, error)) {
+	go func() {
+		c.sem <- struct{}{}
+		img, err := c.Image(src)
+		<-c.sem
+		done(img, err)
+	}()
+}
+
+// Warm makes the thumbnails of the images in the background, so the first
+// look at a nade is as quick as the rest
+func (c *Cache) Warm(paths []string) {
+	for _, p := range paths {
+		p := p
+		go func() {
+			c.sem <- struct{}{}
+			defer func() { <-c.sem }()
+			if _, err := c.Path(p); err != nil {
+				log.Printf("[Thumbnails] Error making thumbnail: %v", err)
+			}
+		}()
+	}
+}
+
+// Scale shrinks img so its longest side is at most size. Smaller images are returned as they are.
+func Scale(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	if w >= h {
+		w, h = size, max(1, h*size/w)
+	} else {
+		w, h = max(1, w*size/h), size
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.BiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
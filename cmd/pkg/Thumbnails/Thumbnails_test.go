@@ -0,0 +1,109 @@
+package Thumbnails
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePNG(t *testing.T, path string, w, h int, c color.Color) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", path, err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("failed to encode %s: %v", path, err)
+	}
+}
+
+func TestPath(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shot.png")
+	writePNG(t, src, 1920, 1080, color.RGBA{200, 0, 0, 255})
+
+	c := New(filepath.Join(dir, "thumbs"), 0)
+	thumb, err := c.Path(src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	img, err := c.Image(src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != DefaultSize || b.Dy() != 360 {
+		t.Errorf("unexpected thumbnail size %v", b)
+	}
+
+	// The thumbnail is reused while the image doesn't change
+	info, _ := os.Stat(thumb)
+	if again, _ := c.Path(src); again != thumb {
+		t.Errorf("expected the same thumbnail, got %s", again)
+	}
+	if after, _ := os.Stat(thumb); !after.ModTime().Equal(info.ModTime()) {
+		t.Errorf("thumbnail was rewritten")
+	}
+
+	// A new cache finds the thumbnail on disk by content
+	copyPath := filepath.Join(dir, "copy.png")
+	data, _ := os.ReadFile(src)
+	os.WriteFile(copyPath, data, 0644)
+	if other, _ := New(filepath.Join(dir, "thumbs"), 0).Path(copyPath); other != thumb {
+		t.Errorf("same content should share a thumbnail: %s %s", other, thumb)
+	}
+
+	// Changing the image makes a new thumbnail
+	writePNG(t, src, 100, 50, color.RGBA{0, 0, 200, 255})
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(src, later, later)
+	changed, err := c.Path(src)
+	if err != nil || changed == thumb {
+		t.Errorf("expected a new thumbnail, got %s %v", changed, err)
+	}
+	if img, _ := c.Image(src); img.Bounds().Dx() != 100 {
+		t.Errorf("small images should keep their size, got %v", img.Bounds())
+	}
+
+	if _, err := c.Path(filepath.Join(dir, "missing.png")); err == nil {
+		t.Errorf("expected error for missing image")
+	}
+	os.WriteFile(filepath.Join(dir, "broken.png"), []byte("not an image"), 0644)
+	if _, err := c.Path(filepath.Join(dir, "broken.png")); err == nil {
+		t.Errorf("expected error for broken image")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "tall.png")
+	writePNG(t, src, 300, 900, color.White)
+
+	c := New(filepath.Join(dir, "thumbs"), 300)
+	c.Warm([]string{src})
+	done := make(chan image.Image)
+	c.Load(src, func(img image.Image, err error) {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		done <- img
+	})
+	select {
+	case img := <-done:
+		if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 300 {
+			t.Errorf("unexpected thumbnail size %v", b)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("thumbnail never loaded")
+	}
+}
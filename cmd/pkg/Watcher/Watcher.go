@@ -0,0 +1,169 @@
+package Watcher
+
+// Watches the annotation folder and tags.json, so edits made in game with
+// annotations_save or to tags.json by hand show up without a refresh.
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// DefaultDelay is how long the files have to be quiet before a change is reported.
+// Saving in game or from an editor fires several events per file.
+const DefaultDelay = 500 * time.Millisecond
+
+// Change says what changed since the last report
+type Change struct {
+	Tags        bool // tags.json was written, replaced or removed
+	Annotations bool // a file or folder under the annotation folder changed
+}
+
+// Config says what to watch
+type Config struct {
+	TagsPath       string
+	AnnotationPath string
+	Delay          time.Duration // DefaultDelay when 0
+}
+
+// Watcher reports batches of changes to OnChange until closed
+type Watcher struct {
+	cfg         Config
+	tags        string
+	annotations string
+	fs          *fsnotify.Watcher
+	onChange    func(Change)
+}
+
+// New starts watching. onChange is called from the watcher's goroutine.
+func New(cfg Config, onChange func(Change)) (*Watcher, error) {
+	if cfg.Delay == 0 {
+		cfg.Delay = DefaultDelay
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{cfg: cfg, fs: fsw, onChange: onChange}
+
+	// Editors often save by replacing the file, so watch the folder tags.json is in
+	if cfg.TagsPath != "" {
+		w.tags, _ = filepath.Abs(cfg.TagsPath)
+		if err := fsw.Add(filepath.Dir(w.tags)); err != nil {
+			fsw.Close()
+			return nil, err
+		}
+	}
+	// fsnotify only watches one level, so every folder is added
+	if cfg.AnnotationPath != "" {
+		w.annotations, _ = filepath.Abs(cfg.AnnotationPath)
+		if err := w.addTree(w.annotations); err != nil {
+			fsw.Close()
+			return nil, err
+		}
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	return w.fs.Close()
+}
+
+func (w *Watcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return w.fs.Add(path)
+		}
+		return nil
+	})
+}
+
+// classify says what an event is for, and watches folders created under the annotation folder
+func (w *Watcher) classify(ev fsnotify.Event) Change {
+	path, _ := filepath.Abs(ev.Name)
+	if path == w.tags {
+		return Change{Tags: true}
+	}
+	if w.annotations == "" || (path != w.annotations && !strings.HasPrefix(path, w.annotations+string(filepath.Separator))) {
+		return Change{}
+	}
+	if ev.Has(fsnotify.Create) {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			if err := w.addTree(path); err != nil {
+				log.Printf("[Watcher] Error watching %s: %v", path, err)
+			}
+		}
+	}
+	return Change{Annotations: true}
+}
+
+// run collects events until the files have been quiet for the delay, then reports them as one change
+func (w *Watcher) run() {
+	var pending Change
+	var quiet <-chan time.Time
+	for {
+		select {
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			c := w.classify(ev)
+			if !c.Tags && !c.Annotations {
+				continue
+			}
+			pending.Tags = pending.Tags || c.Tags
+			pending.Annotations = pending.Annotations || c.Annotations
+			quiet = time.After(w.cfg.Delay)
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("[Watcher] Error: %v", err)
+		case <-quiet:
+			quiet = nil
+			log.Printf("[Watcher] Change: %+v", pending)
+			w.onChange(pending)
+			pending = Change{}
+		}
+	}
+}
+
+// Untagged returns the annotation folders with a .txt file that no nade in
+// tags.json has, sorted. The folder name is the nade name, as in tag generation.
+func Untagged(annotationPath string, nades []Tags.AnnotationMetadata) ([]string, error) {
+	files, err := Tags.GetFilePaths(annotationPath)
+	if err != nil {
+		return nil, err
+	}
+	tagged := make(map[string]bool)
+	for _, n := range nades {
+		tagged[n.NadeName] = true
+	}
+
+	seen := make(map[string]bool)
+	var untagged []string
+	for _, f := range files {
+		if f.TxtPath == "" || tagged[f.ParentPath] || seen[f.ParentPath] {
+			continue
+		}
+		seen[f.ParentPath] = true
+		untagged = append(untagged, f.ParentPath)
+	}
+	sort.Strings(untagged)
+	return untagged, nil
+}
@@ -0,0 +1,97 @@
+package Watcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// start watches a fresh folder and returns the tags path, annotation folder and the reported changes
+func start(t *testing.T) (string, string, chan Change) {
+	t.Helper()
+	dir := t.TempDir()
+	tagsPath := filepath.Join(dir, "tags.json")
+	annotations := filepath.Join(dir, "local")
+	os.MkdirAll(annotations, 0755)
+
+	changes := make(chan Change, 10)
+	w, err := New(Config{TagsPath: tagsPath, AnnotationPath: annotations, Delay: 50 * time.Millisecond},
+		func(c Change) { changes <- c })
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return tagsPath, annotations, changes
+}
+
+func next(t *testing.T, changes chan Change) Change {
+	t.Helper()
+	select {
+	case c := <-changes:
+		return c
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no change reported")
+	}
+	return Change{}
+}
+
+func TestWatcher(t *testing.T) {
+	tagsPath, annotations, changes := start(t)
+
+	// Several writes in a row are one change
+	for i := 0; i < 3; i++ {
+		os.WriteFile(tagsPath, []byte(`{"nades": []}`), 0644)
+	}
+	if c := next(t, changes); c != (Change{Tags: true}) {
+		t.Errorf("unexpected change: %+v", c)
+	}
+	select {
+	case c := <-changes:
+		t.Errorf("expected one change, also got %+v", c)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Other files next to tags.json are ignored
+	os.WriteFile(filepath.Join(filepath.Dir(tagsPath), "settings.json"), []byte("{}"), 0644)
+	select {
+	case c := <-changes:
+		t.Errorf("unexpected change for settings.json: %+v", c)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// New folders are watched too
+	folder := filepath.Join(annotations, "NewSmoke")
+	os.Mkdir(folder, 0755)
+	if c := next(t, changes); c != (Change{Annotations: true}) {
+		t.Errorf("unexpected change: %+v", c)
+	}
+	os.WriteFile(filepath.Join(folder, "NewSmoke.txt"), []byte("{}"), 0644)
+	if c := next(t, changes); c != (Change{Annotations: true}) {
+		t.Errorf("unexpected change: %+v", c)
+	}
+}
+
+func TestUntagged(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"Tagged/Tagged.txt", "NewB/NewB.txt", "NewA/NewA.txt", "NewA/NewA.png", "ImageOnly/ImageOnly.png"} {
+		path = filepath.Join(dir, path)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("{}"), 0644)
+	}
+
+	got, err := Untagged(dir, []Tags.AnnotationMetadata{{NadeName: "Tagged"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"NewA", "NewB"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := Untagged(filepath.Join(dir, "missing"), nil); err == nil {
+		t.Errorf("expected error for missing folder")
+	}
+}
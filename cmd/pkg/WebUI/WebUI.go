@@ -0,0 +1,303 @@
+package WebUI
+
+// Read-only web view of tags.json for teammates without the app. tags.json is
+// read on every request, so edits show up without a restart.
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/FileGenerator"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/MetadataExplorer"
+)
+
+// Config says where the library is
+type Config struct {
+	TagsPath string
+}
+
+// Server serves the web view
+type Server struct {
+	cfg Config
+	mux *http.ServeMux
+}
+
+// New builds the web view. Mount it with http.ListenAndServe(addr, server).
+func New(cfg Config) *Server {
+	s := &Server{cfg: cfg, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /{$}", s.handleIndex)
+	s.mux.HandleFunc("GET /nade/{name}", s.handleNade)
+	s.mux.HandleFunc("GET /image/{name}", s.handleImage)
+	s.mux.HandleFunc("GET /download/{name}", s.handleDownload)
+	s.mux.HandleFunc("GET /pack", s.handlePack)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) load(w http.ResponseWriter) ([]MetadataExplorer.Metadata, bool) {
+	metadata, err := MetadataExplorer.LoadMetadata(s.cfg.TagsPath)
+	if err != nil {
+		log.Printf("[WebUI] Error loading %s: %v", s.cfg.TagsPath, err)
+		http.Error(w, "could not read tags.json", http.StatusInternalServerError)
+		return nil, false
+	}
+	return metadata, true
+}
+
+// find returns the nade with the given name. Only files listed in tags.json are ever served.
+func find(metadata []MetadataExplorer.Metadata, name string) (MetadataExplorer.Metadata, bool) {
+	for _, m := range metadata {
+		if m.NadeName == name {
+			return m, true
+		}
+	}
+	return MetadataExplorer.Metadata{}, false
+}
+
+type mapLink struct {
+	Name, Workshop, Display string
+	Count                   int
+}
+
+type option struct {
+	Value, Label string
+	Checked      bool
+}
+
+type indexPage struct {
+	Maps     []mapLink
+	Map      string
+	Workshop string
+	Display  string
+	Sides    []option
+	Types    []option
+	Sites    []option
+	Callouts []option
+	Nades    []MetadataExplorer.Metadata
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	metadata, ok := s.load(w)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	filters := MetadataExplorer.FiltersFromQuery(q)
+
+	page := indexPage{Map: filters.MapPick, Workshop: filters.WorkshopID}
+	counts := make(map[Maps.Key]int)
+	for _, m := range metadata {
+		counts[Maps.Key{Name: m.MapName, WorkshopID: m.WorkshopID}]++
+	}
+	for key, n := range counts {
+		page.Maps = append(page.Maps, mapLink{key.Name, key.WorkshopID, key.DisplayName(), n})
+	}
+	sort.Slice(page.Maps, func(i, j int) bool { return page.Maps[i].Display < page.Maps[j].Display })
+
+	if page.Map != "" {
+		page.Display = Maps.DisplayName(page.Map, page.Workshop)
+		checked := func(key, value string) bool {
+			for _, v := range q[key] {
+				if v == value {
+					return true
+				}
+			}
+			return false
+		}
+		for _, side := range []string{"T", "CT"} {
+			page.Sides = append(page.Sides, option{side, side, checked("side", side)})
+		}
+		for _, t := range []string{"smoke", "flash", "molotov", "he"} {
+			page.Types = append(page.Types, option{t, t, checked("type", t)})
+		}
+		for _, site := range Maps.Sites(page.Map) {
+			page.Sites = append(page.Sites, option{site, site, checked("site", site)})
+		}
+		for _, c := range Maps.Callouts(page.Map) {
+			page.Callouts = append(page.Callouts, option{c, c, c == filters.Callout})
+		}
+		page.Nades = MetadataExplorer.FilterMetadata(metadata, filters)
+	}
+	render(w, indexTemplate, page)
+}
+
+func (s *Server) handleNade(w http.ResponseWriter, r *http.Request) {
+	metadata, ok := s.load(w)
+	if !ok {
+		return
+	}
+	nade, ok := find(metadata, r.PathValue("name"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	render(w, nadeTemplate, struct {
+		MetadataExplorer.Metadata
+		Display string
+	}{nade, Maps.DisplayName(nade.MapName, nade.WorkshopID)})
+}
+
+func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
+	metadata, ok := s.load(w)
+	if !ok {
+		return
+	}
+	nade, ok := find(metadata, r.PathValue("name"))
+	if !ok || nade.ImagePath == "" {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, nade.ImagePath)
+}
+
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	metadata, ok := s.load(w)
+	if !ok {
+		return
+	}
+	nade, ok := find(metadata, r.PathValue("name"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(nade.FilePath)))
+	http.ServeFile(w, r, nade.FilePath)
+}
+
+// handlePack generates a pack from the nades named in the query and sends it as a download
+func (s *Server) handlePack(w http.ResponseWriter, r *http.Request) {
+	metadata, ok := s.load(w)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	name := q.Get("file")
+	if name == "" {
+		name = "pack"
+	}
+	fileName, err := FileGenerator.NormalizeOutputName(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var files []string
+	names := make(map[string]string) // file path -> nade name
+	for _, n := range q["nade"] {
+		nade, ok := find(metadata, n)
+		if !ok {
+			http.Error(w, fmt.Sprintf("no nade named %q", n), http.StatusNotFound)
+			return
+		}
+		files = append(files, nade.FilePath)
+		names[nade.FilePath] = n
+	}
+	if len(files) == 0 {
+		http.Error(w, "pick at least one nade", http.StatusBadRequest)
+		return
+	}
+
+	data, result, err := FileGenerator.Pack(files, FileGenerator.Options{})
+	if err != nil {
+		log.Printf("[WebUI] Error generating pack: %v", err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	// A pack missing some of the picked nades would look fine until it's used in game
+	if len(result.Skipped) > 0 {
+		var skipped []string
+		for _, f := range result.Skipped {
+			skipped = append(skipped, names[f])
+		}
+		log.Printf("[WebUI] Could not read %v for a pack", result.Skipped)
+		http.Error(w, "could not read "+strings.Join(skipped, ", "), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.Write(data)
+}
+
+func render(w http.ResponseWriter, t *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, data); err != nil {
+		log.Printf("[WebUI] Error rendering %s: %v", t.Name(), err)
+	}
+}
+
+const style = `<style>
+body { font-family: sans-serif; max-width: 70em; margin: auto; padding: 1em; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border-bottom: 1px solid #ddd; padding: 0.3em; text-align: left; }
+fieldset { display: inline-block; border: none; }
+img { max-width: 100%; }
+</style>`
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>CS-StratBook{{with .Display}}: {{.}}{{end}}</title>` + style + `</head>
+<body>
+<h1>CS-StratBook</h1>
+<p>{{range .Maps}}<a href="/?map={{.Name}}{{with .Workshop}}&amp;workshop={{.}}{{end}}">{{.Display}}</a> ({{.Count}}) {{end}}</p>
+{{- if .Map}}
+<h2>{{.Display}}</h2>
+<form method="get" action="/">
+<input type="hidden" name="map" value="{{.Map}}">
+{{- with .Workshop}}
+<input type="hidden" name="workshop" value="{{.}}">
+{{- end}}
+<fieldset>Side: {{range .Sides}}<label><input type="checkbox" name="side" value="{{.Value}}"{{if .Checked}} checked{{end}}> {{.Label}}</label> {{end}}</fieldset>
+<fieldset>Type: {{range .Types}}<label><input type="checkbox" name="type" value="{{.Value}}"{{if .Checked}} checked{{end}}> {{.Label}}</label> {{end}}</fieldset>
+<fieldset>Site: {{range .Sites}}<label><input type="checkbox" name="site" value="{{.Value}}"{{if .Checked}} checked{{end}}> {{.Label}}</label> {{end}}</fieldset>
+{{- if .Callouts}}
+<fieldset>Callout: <select name="callout"><option value="">Any</option>{{range .Callouts}}<option{{if .Checked}} selected{{end}}>{{.Value}}</option>{{end}}</select></fieldset>
+{{- end}}
+<button type="submit">Apply Filters</button>
+</form>
+<form method="get" action="/pack">
+<table>
+<tr><th></th><th>Name</th><th>Side</th><th>Type</th><th>Site</th><th>Callout</th><th>Description</th></tr>
+{{- range .Nades}}
+<tr><td><input type="checkbox" name="nade" value="{{.NadeName}}"></td><td><a href="/nade/{{.NadeName}}">{{.NadeName}}</a></td><td>{{.Side}}</td><td>{{.NadeType}}</td><td>{{.SiteLocation}}</td><td>{{.Callout}}</td><td>{{.Description}}</td></tr>
+{{- else}}
+<tr><td colspan="7">No nades match the filters.</td></tr>
+{{- end}}
+</table>
+<p><input name="file" placeholder="pack name" pattern="[A-Za-z0-9_-]+"> <button type="submit">Download Pack</button></p>
+</form>
+{{- end}}
+</body>
+</html>
+`))
+
+var nadeTemplate = template.Must(template.New("nade").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>{{.NadeName}}</title>` + style + `</head>
+<body>
+<p><a href="/?map={{.MapName}}{{with .WorkshopID}}&amp;workshop={{.}}{{end}}">{{.Display}}</a></p>
+<h1>{{.NadeName}}</h1>
+<table>
+<tr><th>Description</th><td>{{.Description}}</td></tr>
+<tr><th>Map</th><td>{{.Display}} ({{.MapName}})</td></tr>
+<tr><th>Side</th><td>{{.Side}}</td></tr>
+<tr><th>Type</th><td>{{.NadeType}}</td></tr>
+<tr><th>Site</th><td>{{.SiteLocation}}</td></tr>
+<tr><th>Callout</th><td>{{.Callout}}</td></tr>
+<tr><th>Thrown from</th><td>{{.ThrowZone}}</td></tr>
+</table>
+<p><a href="/download/{{.NadeName}}">Download {{.FileName}}</a></p>
+{{- if .ImagePath}}
+<img src="/image/{{.NadeName}}" alt="{{.NadeName}}">
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,151 @@
+package WebUI
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/MetadataExplorer"
+)
+
+// titled is the least a served file needs: its name, so downloads and packs
+// can be told apart
+func titled(name string) string {
+	return fmt.Sprintf(`{
+	MapName = "de_inferno"
+	MapAnnotationNode0 =
+	{
+		SubType = "main"
+		Title =
+		{
+			Text = "%s"
+		}
+	}
+}`, name)
+}
+
+func setup(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	dir := t.TempDir()
+	var nades []MetadataExplorer.Metadata
+	for _, n := range []struct{ name, side, site, workshop string }{
+		{"BananaSmoke", "T", "B", ""},
+		{"ArchSmoke", "CT", "A", ""},
+		{"WorkshopSmoke", "T", "B", "3070284539"},
+	} {
+		txt := filepath.Join(dir, n.name+".txt")
+		img := filepath.Join(dir, n.name+".png")
+		os.WriteFile(txt, []byte(titled(n.name)), 0644)
+		os.WriteFile(img, []byte("png"), 0644)
+		nades = append(nades, MetadataExplorer.Metadata{
+			FileName: n.name + ".txt", FilePath: txt, ImagePath: img, NadeName: n.name,
+			MapName: "de_inferno", WorkshopID: n.workshop, Side: n.side, NadeType: "smoke", SiteLocation: n.site,
+		})
+	}
+	data, _ := json.Marshal(MetadataExplorer.MetadataWrapper{Nades: nades})
+	tagsPath := filepath.Join(dir, "tags.json")
+	if err := os.WriteFile(tagsPath, data, 0644); err != nil {
+		t.Fatalf("failed to write tags: %v", err)
+	}
+
+	srv := httptest.NewServer(New(Config{TagsPath: tagsPath}))
+	t.Cleanup(srv.Close)
+	return srv, dir
+}
+
+func get(t *testing.T, url string) (int, http.Header, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header, string(body)
+}
+
+func TestIndex(t *testing.T) {
+	srv, _ := setup(t)
+
+	code, _, body := get(t, srv.URL+"/")
+	if code != http.StatusOK || !strings.Contains(body, "Inferno</a> (2)") {
+		t.Errorf("unexpected map list %d:\n%s", code, body)
+	}
+
+	_, _, body = get(t, srv.URL+"/?map=de_inferno&side=T")
+	if !strings.Contains(body, "/nade/BananaSmoke") || strings.Contains(body, "/nade/ArchSmoke") || strings.Contains(body, "/nade/WorkshopSmoke") {
+		t.Errorf("side filter not applied:\n%s", body)
+	}
+
+	// The workshop copy of Inferno is a map of its own
+	if !strings.Contains(body, `/?map=de_inferno&amp;workshop=3070284539">Inferno (Workshop 3070284539)</a> (1)`) {
+		t.Errorf("workshop map not listed separately:\n%s", body)
+	}
+	_, _, body = get(t, srv.URL+"/?map=de_inferno&workshop=3070284539")
+	if !strings.Contains(body, "/nade/WorkshopSmoke") || strings.Contains(body, "/nade/BananaSmoke") || !strings.Contains(body, `name="workshop" value="3070284539"`) {
+		t.Errorf("workshop map not filtered:\n%s", body)
+	}
+
+	_, _, body = get(t, srv.URL+"/?map=de_inferno&site=A")
+	if strings.Contains(body, "/nade/BananaSmoke") || !strings.Contains(body, "/nade/ArchSmoke") {
+		t.Errorf("site filter not applied:\n%s", body)
+	}
+}
+
+func TestNadeFiles(t *testing.T) {
+	srv, _ := setup(t)
+
+	code, _, body := get(t, srv.URL+"/nade/ArchSmoke")
+	if code != http.StatusOK || !strings.Contains(body, "/download/ArchSmoke") || !strings.Contains(body, "/image/ArchSmoke") {
+		t.Errorf("unexpected detail page %d:\n%s", code, body)
+	}
+
+	code, header, body := get(t, srv.URL+"/download/ArchSmoke")
+	if code != http.StatusOK || !strings.Contains(header.Get("Content-Disposition"), "ArchSmoke.txt") || !strings.Contains(body, "ArchSmoke") {
+		t.Errorf("unexpected download %d %v", code, header)
+	}
+
+	if code, _, body = get(t, srv.URL+"/image/ArchSmoke"); code != http.StatusOK || body != "png" {
+		t.Errorf("unexpected image %d %q", code, body)
+	}
+
+	// Only nades listed in tags.json are served
+	for _, path := range []string{"/nade/Nope", "/download/..%2Ftags.json", "/image/Nope"} {
+		if code, _, _ := get(t, srv.URL+path); code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, code)
+		}
+	}
+}
+
+func TestPack(t *testing.T) {
+	srv, dir := setup(t)
+
+	code, header, body := get(t, srv.URL+"/pack?file=Execute&nade=BananaSmoke&nade=ArchSmoke")
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", code, body)
+	}
+	if !strings.Contains(header.Get("Content-Disposition"), "Execute.txt") ||
+		!strings.Contains(body, "BananaSmoke") || !strings.Contains(body, "ArchSmoke") {
+		t.Errorf("unexpected pack %v:\n%s", header, body)
+	}
+
+	if code, _, _ := get(t, srv.URL+"/pack?file=Execute"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 with no nades, got %d", code)
+	}
+	if code, _, _ := get(t, srv.URL+"/pack?nade=Nope"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown nade, got %d", code)
+	}
+
+	// A nade in tags.json whose file is gone isn't quietly left out
+	os.Remove(filepath.Join(dir, "ArchSmoke.txt"))
+	code, _, body = get(t, srv.URL+"/pack?nade=BananaSmoke&nade=ArchSmoke")
+	if code != http.StatusUnprocessableEntity || !strings.Contains(body, "ArchSmoke") || strings.Contains(body, dir) {
+		t.Errorf("expected 422 naming the missing nade, got %d: %s", code, body)
+	}
+}
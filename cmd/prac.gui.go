@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Annotation"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/FileGenerator"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/PracConfig"
+)
+
+// showPracConfig opens a window that writes a practice config for the nades in
+// files, loading the pack named outputName
+func (g *gui) showPracConfig(files []string, outputName string) {
+	w := g.App.NewWindow("Practice Config")
+	opts := PracConfig.DefaultOptions()
+
+	pack := ""
+	if name, err := FileGenerator.NormalizeOutputName(outputName); err == nil {
+		pack = strings.TrimSuffix(name, ".txt")
+	}
+	mapName := ""
+	if len(files) > 0 {
+		if f, err := Annotation.Load(files[0]); err == nil {
+			mapName = f.MapName()
+		}
+	}
+
+	folderEntry := widget.NewEntry()
+	folderEntry.SetText(cfgFolder(g.Cfg_path, g.Annotation_path))
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText("prac")
+	if pack != "" {
+		nameEntry.SetText("prac_" + pack)
+	}
+	mapEntry := widget.NewEntry()
+	mapEntry.SetText(mapName)
+	packEntry := widget.NewEntry()
+	packEntry.SetText(pack)
+	packEntry.SetPlaceHolder("Pack to load with annotations_load")
+	trajectoryEntry := widget.NewEntry()
+	trajectoryEntry.SetText(strconv.Itoa(opts.TrajectoryTime))
+	ammoCheck := widget.NewCheck("Infinite ammo", nil)
+	ammoCheck.SetChecked(opts.InfiniteAmmo)
+	botsCheck := widget.NewCheck("Kick bots", nil)
+	botsCheck.SetChecked(opts.KickBots)
+	bindsCheck := widget.NewCheck(fmt.Sprintf("Bind %s to %s to the lineups", opts.Keys[0], opts.Keys[len(opts.Keys)-1]), nil)
+	bindsCheck.SetChecked(len(files) > 0)
+	if len(files) == 0 {
+		bindsCheck.Disable()
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Folder", folderEntry),
+		widget.NewFormItem("File name", nameEntry),
+		widget.NewFormItem("Map", mapEntry),
+		widget.NewFormItem("Pack", packEntry),
+		widget.NewFormItem("Trajectory seconds", trajectoryEntry),
+		widget.NewFormItem("", container.NewHBox(ammoCheck, botsCheck)),
+		widget.NewFormItem("", bindsCheck),
+	)
+	form.SubmitText = "Write Config"
+	form.OnSubmit = func() {
+		seconds, err := strconv.Atoi(strings.TrimSpace(trajectoryEntry.Text))
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("trajectory seconds must be a whole number"), w)
+			return
+		}
+		folder := strings.TrimSpace(folderEntry.Text)
+		if folder == "" {
+			dialog.ShowError(fmt.Errorf("CS2 cfg folder not found, enter it above"), w)
+			return
+		}
+		opts.TrajectoryTime = seconds
+		opts.InfiniteAmmo = ammoCheck.Checked
+		opts.KickBots = botsCheck.Checked
+		opts.Map = strings.TrimSpace(mapEntry.Text)
+		opts.Pack = strings.TrimSpace(packEntry.Text)
+		opts.Spots = nil
+		if bindsCheck.Checked {
+			if opts.Spots, err = PracConfig.Spots(files); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+		}
+
+		path, warnings, err := PracConfig.Write(folder, strings.TrimSpace(nameEntry.Text), opts)
+		if err != nil {
+			log.Printf("Error writing practice config: %v", err)
+			dialog.ShowError(err, w)
+			return
+		}
+		// Remember a folder picked by hand
+		if folder != PracConfig.CfgDir(g.Annotation_path) && folder != g.Cfg_path {
+			g.Cfg_path = folder
+			g.saveSettings()
+		}
+		name := strings.TrimSuffix(strings.TrimSpace(nameEntry.Text), ".cfg")
+		text := fmt.Sprintf("Written to %s\n\nRun it in game with:\nexec %s", path, name)
+		if len(warnings) > 0 {
+			text += "\n\n" + strings.Join(warnings, "\n")
+		}
+		dialog.ShowInformation("Practice Config Written", text, w)
+	}
+
+	w.SetContent(form)
+	w.Resize(fyne.NewSize(550, 350))
+	w.Show()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Screenshots"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+)
+
+// screenshotPeriods are the choices for how far back to list screenshots
+var screenshotPeriods = []struct {
+	name string
+	age  time.Duration
+}{
+	{"Last hour", time.Hour},
+	{"Today", 24 * time.Hour},
+	{"This week", 7 * 24 * time.Hour},
+	{"Everything", 0},
+}
+
+// showScreenshotImport opens a window listing recent screenshots, where each can
+// be given a nade and a label and imported in one go
+func (g *gui) showScreenshotImport() {
+	w := g.App.NewWindow("Import Screenshots")
+
+	// Left empty the folders are found from the Steam folder holding the annotations
+	folderEntry := widget.NewEntry()
+	folderEntry.SetPlaceHolder("Found from the Steam folder")
+	if found := screenshotFolders("", g.Annotation_path); len(found) > 0 {
+		folderEntry.SetPlaceHolder(strings.Join(found, ", "))
+	}
+	folderEntry.SetText(g.Screenshots_path)
+
+	var nadeNames []string
+	labels := append([]string{""}, Tags.ImageLabels...)
+	rows := container.NewVBox()
+	status := widget.NewLabel("")
+
+	var shots []Screenshots.Screenshot
+	var nadeFor, labelFor []string
+
+	period := widget.NewSelect(nil, nil)
+	list := func() {
+		nades, err := Tags.LoadTags(g.Tags_path)
+		if err != nil {
+			log.Printf("Error loading tags: %v", err)
+		}
+		nadeNames = nadeNames[:0]
+		for _, n := range nades {
+			nadeNames = append(nadeNames, n.NadeName)
+		}
+		sort.Strings(nadeNames)
+
+		var since time.Time
+		for _, p := range screenshotPeriods {
+			if p.name == period.Selected && p.age > 0 {
+				since = time.Now().Add(-p.age)
+			}
+		}
+		dirs := screenshotFolders(g.Screenshots_path, g.Annotation_path)
+		shots, err = Screenshots.List(dirs, since)
+		if err != nil {
+			log.Printf("Error listing screenshots: %v", err)
+			status.SetText("Error: " + err.Error())
+			return
+		}
+		nadeFor = make([]string, len(shots))
+		labelFor = make([]string, len(shots))
+
+		rows.RemoveAll()
+		for i, s := range shots {
+			i := i
+			thumb := canvas.NewImageFromImage(nil)
+			thumb.FillMode = canvas.ImageFillContain
+			thumb.SetMinSize(fyne.NewSize(160, 90))
+			g.thumbs.Load(s.Path, func(img image.Image, err error) {
+				if err != nil {
+					log.Printf("Error loading thumbnail: %v", err)
+					return
+				}
+				thumb.Image = img
+				thumb.Refresh()
+			})
+
+			nade := widget.NewSelectEntry(nadeNames)
+			nade.SetPlaceHolder("Nade")
+			nade.OnChanged = func(s string) { nadeFor[i] = strings.TrimSpace(s) }
+			label := widget.NewSelect(labels, func(s string) { labelFor[i] = s })
+			label.PlaceHolder = "Label"
+
+			rows.Add(container.NewBorder(nil, nil, thumb, nil,
+				container.NewVBox(widget.NewLabel(s.Time.Format("2006-01-02 15:04:05")), nade, label)))
+		}
+		if len(dirs) == 0 {
+			status.SetText("No screenshot folder found, set one above")
+		} else {
+			status.SetText(fmt.Sprintf("%d screenshot(s)", len(shots)))
+		}
+	}
+
+	var names []string
+	for _, p := range screenshotPeriods {
+		names = append(names, p.name)
+	}
+	period.Options = names
+	period.OnChanged = func(string) { list() }
+
+	saveFolder := widget.NewButton("Save Folder", func() {
+		g.Screenshots_path = strings.TrimSpace(folderEntry.Text)
+		g.saveSettings()
+		list()
+	})
+
+	importBtn := widget.NewButton("Import", func() {
+		var assignments []Screenshots.Assignment
+		for i, s := range shots {
+			if nadeFor[i] != "" {
+				assignments = append(assignments, Screenshots.Assignment{Screenshot: s.Path, NadeName: nadeFor[i], Label: labelFor[i]})
+			}
+		}
+		if len(assignments) == 0 {
+			dialog.ShowInformation("Import Screenshots", "Pick a nade for the screenshots to import.", w)
+			return
+		}
+		nades, err := Tags.LoadTags(g.Tags_path)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		updated, attached, err := Screenshots.Attach(nades, assignments)
+		if len(attached) > 0 {
+			if saveErr := Tags.SaveTags(g.Tags_path, updated); saveErr != nil {
+				dialog.ShowError(saveErr, w)
+				return
+			}
+		}
+		if err != nil {
+			log.Printf("Error importing screenshots: %v", err)
+			dialog.ShowError(fmt.Errorf("imported %d of %d: %v", len(attached), len(assignments), err), w)
+			return
+		}
+		var lines []string
+		for _, a := range attached {
+			lines = append(lines, a.NadeName+": "+a.Path)
+		}
+		dialog.ShowInformation("Screenshots Imported", strings.Join(lines, "\n"), w)
+		list()
+	})
+
+	period.SetSelected(screenshotPeriods[1].name)
+
+	top := container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("Folder:"), saveFolder, folderEntry),
+		container.NewBorder(nil, nil, widget.NewLabel("Taken:"), nil, period),
+		status,
+	)
+	w.SetContent(container.NewBorder(top, importBtn, nil, nil, container.NewVScroll(rows)))
+	w.Resize(fyne.NewSize(500, 600))
+	w.Show()
+}
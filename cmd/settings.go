@@ -5,18 +5,35 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Maps"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/PracConfig"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Screenshots"
 )
 
 // Settings holds user-configurable values
 type Settings struct {
 	TagsPath       string `json:"tags_path"`
 	AnnotationPath string `json:"annotation_path"`
+	DraftsPath     string `json:"drafts_path"`
+	OutputPath     string `json:"output_path"`
+	StylesPath     string `json:"styles_path"`
+	RadarPath      string `json:"radar_path"`
+	ScanCachePath  string `json:"scan_cache_path"`
+	ThumbnailsPath string `json:"thumbnails_path"`
+	DrillPath      string `json:"drill_path"`
+	MapsPath       string `json:"maps_path"` // map files that add to or replace the built in ones
+	// ScreenshotsPath is found from the Steam folder when empty
+	ScreenshotsPath string `json:"screenshots_path,omitempty"`
+	// CfgPath is CS2's cfg folder, found from the annotation folder when empty
+	CfgPath string `json:"cfg_path,omitempty"`
 }
 
 // where the settings file will be stored
 const settingsFile = "settings.json"
 
-// LoadSettings reads settings.json if it exists, otherwise returns defaults
+// LoadSettings sets up the log file and reads settings.json, creating it and
+// the tags file when they don't exist yet
 func LoadSettings() Settings {
 	// --- SETUP LOGGING HERE ---
 	logFile, err := os.OpenFile("CS_Stratbook.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -28,8 +45,22 @@ func LoadSettings() Settings {
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
 	// --- END LOGGING SETUP ---
 
-	var s Settings
+	s, found := ReadSettings()
+	if !found {
+		SaveSettings(s)
+		return s
+	}
 
+	//Ensure settings.json exists
+	checkFile(settingsFile)
+	// Ensure the tags file exists
+	checkFile(s.TagsPath)
+	return s
+}
+
+// ReadSettings reads settings.json if it exists, otherwise returns defaults.
+// It writes nothing, so commands can run without touching the working directory.
+func ReadSettings() (s Settings, found bool) {
 	// Try reading the file
 	data, err := os.ReadFile(settingsFile)
 	if err != nil {
@@ -39,9 +70,8 @@ func LoadSettings() Settings {
 			TagsPath:       "tags.json",
 			AnnotationPath: filepath.Join("C:\\", "Program Files (x86)", "Steam", "steamapps", "common", "Counter-Strike Global Offensive", "game", "csgo", "annotations"),
 		}
-
-		SaveSettings(s)
-		return s
+		applyDefaults(&s)
+		return s, false
 	}
 
 	// Parse JSON
@@ -53,11 +83,45 @@ func LoadSettings() Settings {
 		}
 	}
 
-	//Ensure settings.json exists
-	checkFile(settingsFile)
-	// Ensure the tags file exists
-	checkFile(s.TagsPath)
-	return s
+	// Fill in settings added after the file was first written
+	applyDefaults(&s)
+	return s, true
+}
+
+// loadMaps adds the map files in the maps folder to the map registry
+func loadMaps(s Settings) {
+	if err := Maps.LoadDir(s.MapsPath); err != nil {
+		log.Printf("Error loading maps from %s: %v", s.MapsPath, err)
+	}
+}
+
+// applyDefaults fills in any optional settings that are missing
+func applyDefaults(s *Settings) {
+	if s.DraftsPath == "" {
+		s.DraftsPath = "drafts.json"
+	}
+	if s.StylesPath == "" {
+		s.StylesPath = "styles.json"
+	}
+	if s.RadarPath == "" {
+		s.RadarPath = "radars"
+	}
+	if s.ScanCachePath == "" {
+		s.ScanCachePath = "scancache.json"
+	}
+	if s.ThumbnailsPath == "" {
+		s.ThumbnailsPath = "thumbnails"
+	}
+	if s.DrillPath == "" {
+		s.DrillPath = "drill.json"
+	}
+	if s.MapsPath == "" {
+		s.MapsPath = "maps"
+	}
+	// Generated packs go next to the annotations unless told otherwise
+	if s.OutputPath == "" {
+		s.OutputPath = s.AnnotationPath
+	}
 }
 
 // SaveSettings writes the current settings back to settings.json
@@ -89,6 +153,23 @@ func checkFile(filename string) {
 		defer file.Close()
 	} else {
 		// Some other error, like permission issues
-		log.Println("Error checking %s file:", filename, err)
+		log.Printf("Error checking %s file: %v", filename, err)
+	}
+}
+
+// screenshotFolders returns the folders to import screenshots from, finding
+// Steam's from the annotation folder unless one is set
+func screenshotFolders(screenshotsPath, annotationPath string) []string {
+	if screenshotsPath != "" {
+		return []string{screenshotsPath}
+	}
+	return Screenshots.Folders(Screenshots.SteamRoot(annotationPath))
+}
+
+// cfgFolder returns the folder practice configs are written to
+func cfgFolder(cfgPath, annotationPath string) string {
+	if cfgPath != "" {
+		return cfgPath
 	}
+	return PracConfig.CfgDir(annotationPath)
 }
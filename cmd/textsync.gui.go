@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/TextSync"
+)
+
+const (
+	syncToFiles   = "tags.json descriptions -> annotation files"
+	syncFromFiles = "annotation text -> empty tags.json descriptions"
+)
+
+// showTextSync previews and applies copying text between tags.json and the annotation files
+func (g *gui) showTextSync() {
+	w := g.App.NewWindow("Sync Annotation Text")
+
+	direction := widget.NewRadioGroup([]string{syncToFiles, syncFromFiles}, nil)
+	direction.SetSelected(syncToFiles)
+	labelsCheck := widget.NewCheck("Add side/site/type to titles", nil)
+	summary := widget.NewLabel("")
+
+	var changes []TextSync.Change
+	var lines []string
+	preview := widget.NewList(
+		func() int { return len(lines) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) { o.(*widget.Label).SetText(lines[i]) },
+	)
+
+	var applyBtn *widget.Button
+	plan := func() {
+		nades, err := Tags.LoadTags(g.Tags_path)
+		if err != nil {
+			summary.SetText("Error loading tags: " + err.Error())
+			return
+		}
+
+		var warnings []string
+		if direction.Selected == syncFromFiles {
+			changes, warnings = TextSync.PlanFromFiles(nades)
+		} else {
+			changes, warnings = TextSync.PlanToFiles(nades, TextSync.Options{Labels: labelsCheck.Checked})
+		}
+
+		lines = lines[:0]
+		for _, c := range changes {
+			lines = append(lines, c.String())
+		}
+		lines = append(lines, warnings...)
+		summary.SetText(fmt.Sprintf("%d change(s), %d warning(s)", len(changes), len(warnings)))
+		preview.Refresh()
+		if len(changes) == 0 {
+			applyBtn.Disable()
+		} else {
+			applyBtn.Enable()
+		}
+	}
+	direction.OnChanged = func(string) { plan() }
+	labelsCheck.OnChanged = func(bool) { plan() }
+
+	applyBtn = widget.NewButton("Apply", func() {
+		var err error
+		if direction.Selected == syncFromFiles {
+			var nades []Tags.AnnotationMetadata
+			nades, err = Tags.LoadTags(g.Tags_path)
+			if err == nil {
+				err = Tags.SaveTags(g.Tags_path, TextSync.ApplyToMetadata(nades, changes))
+			}
+		} else {
+			err = TextSync.ApplyToFiles(changes)
+		}
+		if err != nil {
+			log.Printf("Error applying text sync: %v", err)
+			dialog.ShowError(err, w)
+			return
+		}
+		dialog.ShowInformation("Sync Annotation Text", fmt.Sprintf("Applied %d change(s).", len(changes)), w)
+		plan()
+	})
+
+	top := container.NewVBox(direction, labelsCheck, summary)
+	w.SetContent(container.NewBorder(top, applyBtn, nil, nil, preview))
+	w.Resize(fyne.NewSize(900, 500))
+	plan()
+	w.Show()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2/dialog"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Tags"
+	"github.com/yahzoos/CS-StratBook/cmd/pkg/Watcher"
+)
+
+// watch (re)starts watching tags.json and the annotation folder. onTagsChanged
+// runs when tags.json changes, and new untagged folders are offered for tagging.
+func (g *gui) watch(onTagsChanged func()) {
+	if g.watcher != nil {
+		g.watcher.Close()
+		g.watcher = nil
+	}
+	w, err := Watcher.New(Watcher.Config{TagsPath: g.Tags_path, AnnotationPath: g.Annotation_path}, func(c Watcher.Change) {
+		if c.Tags {
+			onTagsChanged()
+		}
+		if c.Annotations {
+			g.offerUntagged()
+		}
+	})
+	if err != nil {
+		log.Printf("Error watching %s and %s: %v", g.Tags_path, g.Annotation_path, err)
+		return
+	}
+	g.watcher = w
+}
+
+// offerUntagged asks once per folder whether to tag annotation folders that aren't in tags.json
+func (g *gui) offerUntagged() {
+	nades, err := Tags.LoadTags(g.Tags_path)
+	if err != nil {
+		log.Printf("Error loading tags: %v", err)
+		return
+	}
+	untagged, err := Watcher.Untagged(g.Annotation_path, nades)
+	if err != nil {
+		log.Printf("Error finding untagged folders: %v", err)
+		return
+	}
+
+	// Changes come from the watcher's goroutine, and a restarted watcher can overlap the old one
+	g.offeredMu.Lock()
+	if g.offered == nil {
+		g.offered = make(map[string]bool)
+	}
+	var folders []string
+	for _, f := range untagged {
+		if !g.offered[f] {
+			g.offered[f] = true
+			folders = append(folders, f)
+		}
+	}
+	g.offeredMu.Unlock()
+	if len(folders) == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("%d new annotation folder(s) aren't tagged yet:\n%s\n\nTag them now?", len(folders), strings.Join(folders, "\n"))
+	dialog.ShowConfirm("New Annotation Folders", msg, func(ok bool) {
+		if ok {
+			g.generateTagsFor(folders)
+		}
+	}, g.win)
+}